@@ -0,0 +1,357 @@
+// Package check evaluates declarative assertions against HTTP responses:
+// status sets, header and body patterns, JSONPath values, latency and body
+// size limits.  Targets carry a Set built from their config.Check entries;
+// the default job runs it on every response and records each check's
+// outcome by name, separately from transport errors.
+//
+// # Thread safety
+//
+// Checks and Sets are immutable after construction and safe for concurrent
+// use.
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/config"
+)
+
+// MaxBody is the number of body bytes kept for body_match and json_path
+// checks.  Larger bodies are still read in full and counted in
+// Response.Size.  A json_path check fails on them without parsing, and a
+// body_match check searches only their first MaxBody bytes.
+const MaxBody = 1 << 20
+
+// Response is what checks inspect.
+type Response struct {
+	Status int
+	Header http.Header
+
+	// Body holds up to MaxBody bytes of the (decompressed) response body.
+	// It is nil unless the Set needs it; see Set.NeedsBody.
+	Body []byte
+
+	// Size is the full body size in bytes.
+	Size int64
+
+	// Latency is the request's total duration, including the body read.
+	Latency time.Duration
+}
+
+// truncated reports whether r.Body holds only part of the body.
+func (r *Response) truncated() bool { return r.Size > int64(len(r.Body)) }
+
+// Check is one named assertion.
+type Check struct {
+	// Name identifies the check in metrics and reports.
+	Name string
+
+	body bool // inspects Response.Body
+	fn   func(r *Response) error
+}
+
+// Run evaluates c against r and returns nil if it passes, or an error
+// describing the mismatch.
+func (c *Check) Run(r *Response) error { return c.fn(r) }
+
+// Status passes when the status code is one of codes.
+func Status(name string, codes ...int) *Check {
+	codes = slices.Clone(codes)
+	return &Check{Name: name, fn: func(r *Response) error {
+		if slices.Contains(codes, r.Status) {
+			return nil
+		}
+		return fmt.Errorf("status %d not in %v", r.Status, codes)
+	}}
+}
+
+// Header passes when header key is present and, if re is non-nil, one of
+// its values matches re.
+func Header(name, key string, re *regexp.Regexp) *Check {
+	key = http.CanonicalHeaderKey(key)
+	return &Check{Name: name, fn: func(r *Response) error {
+		values := r.Header.Values(key)
+		if len(values) == 0 {
+			return fmt.Errorf("header %s missing", key)
+		}
+		if re == nil {
+			return nil
+		}
+		for _, v := range values {
+			if re.MatchString(v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("header %s %q does not match %q", key, values[0], re)
+	}}
+}
+
+// BodyMatch passes when the body matches re.
+func BodyMatch(name string, re *regexp.Regexp) *Check {
+	return &Check{Name: name, body: true, fn: func(r *Response) error {
+		if re.Match(r.Body) {
+			return nil
+		}
+		if r.truncated() {
+			return fmt.Errorf("first %d bytes of the body do not match %q; the remaining %d were not inspected", len(r.Body), re, r.Size-int64(len(r.Body)))
+		}
+		return fmt.Errorf("body does not match %q", re)
+	}}
+}
+
+// JSONPath passes when the body is JSON and path selects a value.  path uses
+// a subset of JSONPath: $ followed by .name, ['name'] and [index] steps,
+// where a negative index counts from the end, e.g. $.items[0].id or
+// $['user-name'].
+func JSONPath(name, path string) (*Check, error) {
+	return jsonPath(name, path, nil, false)
+}
+
+// JSONPathEquals is JSONPath that also requires the value to equal want,
+// given as decoded JSON (numbers are float64, a nil want is JSON null).
+func JSONPathEquals(name, path string, want any) (*Check, error) {
+	return jsonPath(name, path, want, true)
+}
+
+func jsonPath(name, path string, want any, compare bool) (*Check, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Check{Name: name, body: true, fn: func(r *Response) error {
+		if r.truncated() {
+			return fmt.Errorf("body of %d bytes exceeds %d bytes, not inspected", r.Size, len(r.Body))
+		}
+		var doc any
+		if err := json.Unmarshal(r.Body, &doc); err != nil {
+			return fmt.Errorf("body is not JSON: %w", err)
+		}
+		got, err := steps.eval(doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if compare && !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("%s = %s, want %s", path, jsonText(got), jsonText(want))
+		}
+		return nil
+	}}, nil
+}
+
+func jsonText(v any) string {
+	b, _ := json.Marshal(v)
+	if len(b) > 100 {
+		return string(b[:100]) + "…"
+	}
+	return string(b)
+}
+
+// MaxLatency passes when the request took at most d.
+func MaxLatency(name string, d time.Duration) *Check {
+	return &Check{Name: name, fn: func(r *Response) error {
+		if r.Latency <= d {
+			return nil
+		}
+		return fmt.Errorf("latency %s exceeds %s", r.Latency.Round(time.Millisecond), d)
+	}}
+}
+
+// MaxBodySize passes when the body is at most n bytes.
+func MaxBodySize(name string, n int64) *Check {
+	return &Check{Name: name, fn: func(r *Response) error {
+		if r.Size <= n {
+			return nil
+		}
+		return fmt.Errorf("body size %d exceeds %d bytes", r.Size, n)
+	}}
+}
+
+// New builds the check described by c.  The configuration is expected to
+// have been validated; New still rejects bad patterns and paths.
+func New(c config.Check) (*Check, error) {
+	switch {
+	case len(c.Status) > 0:
+		return Status(c.Name, c.Status...), nil
+	case c.Header != "":
+		var re *regexp.Regexp
+		if c.HeaderMatch != "" {
+			var err error
+			if re, err = regexp.Compile(c.HeaderMatch); err != nil {
+				return nil, fmt.Errorf("check %q: header_match: %w", c.Name, err)
+			}
+		}
+		return Header(c.Name, c.Header, re), nil
+	case c.BodyMatch != "":
+		re, err := regexp.Compile(c.BodyMatch)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: body_match: %w", c.Name, err)
+		}
+		return BodyMatch(c.Name, re), nil
+	case c.JSONPath != "":
+		ch, err := JSONPath(c.Name, c.JSONPath)
+		if len(c.Equals) > 0 {
+			var want any
+			if err := json.Unmarshal(c.Equals, &want); err != nil {
+				return nil, fmt.Errorf("check %q: equals: %w", c.Name, err)
+			}
+			ch, err = JSONPathEquals(c.Name, c.JSONPath, want)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check %q: %w", c.Name, err)
+		}
+		return ch, nil
+	case c.MaxLatency > 0:
+		return MaxLatency(c.Name, c.MaxLatency.Std()), nil
+	case c.MaxBodySize > 0:
+		return MaxBodySize(c.Name, c.MaxBodySize), nil
+	}
+	return nil, fmt.Errorf("check %q: no assertion set", c.Name)
+}
+
+// Set is the checks of one target.
+type Set []*Check
+
+// NewSet builds the checks described by cs, in order.
+func NewSet(cs []config.Check) (Set, error) {
+	var s Set
+	for _, c := range cs {
+		ch, err := New(c)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, ch)
+	}
+	return s, nil
+}
+
+// NeedsBody reports whether any check inspects the response body.
+func (s Set) NeedsBody() bool {
+	return slices.ContainsFunc(s, func(c *Check) bool { return c.body })
+}
+
+// Drain reads body to EOF, as the default job does to reuse connections,
+// and returns its size.  When s needs the body, the first MaxBody bytes are
+// also returned for Response.Body.
+func (s Set) Drain(body io.Reader) ([]byte, int64, error) {
+	if !s.NeedsBody() {
+		n, err := io.Copy(io.Discard, body)
+		return nil, n, err
+	}
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(body, MaxBody))
+	if err != nil {
+		return buf.Bytes(), n, err
+	}
+	rest, err := io.Copy(io.Discard, body)
+	return buf.Bytes(), n + rest, err
+}
+
+// Result is the outcome of one check.  Err is nil when the check passed.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Run evaluates every check against r.  It returns one Result per check,
+// in order, and the joined errors of the failed checks (nil if all passed),
+// each prefixed with the check's name.
+func (s Set) Run(r *Response) ([]Result, error) {
+	results := make([]Result, len(s))
+	var errs []error
+	for i, c := range s {
+		err := c.Run(r)
+		results[i] = Result{Name: c.Name, Err: err}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("check %q: %w", c.Name, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// path is a parsed JSONPath: a list of steps, each a string key or an int
+// index.
+type path []any
+
+// parsePath parses the JSONPath subset accepted by JSONPath.
+func parsePath(p string) (path, error) {
+	rest, ok := strings.CutPrefix(p, "$")
+	if !ok {
+		return nil, fmt.Errorf("json_path %q must start with $", p)
+	}
+	var steps path
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("json_path %q: empty name", p)
+			}
+			steps = append(steps, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json_path %q: unclosed [", p)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, inner[1:len(inner)-1])
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("json_path %q: bad index [%s]", p, inner)
+			}
+			steps = append(steps, i)
+		default:
+			return nil, fmt.Errorf("json_path %q: unexpected %q", p, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// eval returns the value p selects in doc.
+func (p path) eval(doc any) (any, error) {
+	v := doc
+	for _, step := range p {
+		switch s := step.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%q: not an object", s)
+			}
+			if v, ok = obj[s]; !ok {
+				return nil, fmt.Errorf("%q: no such key", s)
+			}
+		case int:
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("[%d]: not an array", s)
+			}
+			i := s
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("[%d]: index out of range (length %d)", s, len(arr))
+			}
+			v = arr[i]
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,125 @@
+package check_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/check"
+	"github.com/firasghr/GoSessionEngine/config"
+)
+
+var response = &check.Response{
+	Status:  201,
+	Header:  http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+	Body:    []byte(`{"user":{"id":42,"name":"alice","tags":["a","b"]},"user-count":1,"next":null}`),
+	Size:    77,
+	Latency: 120 * time.Millisecond,
+}
+
+func mustJSONPath(t *testing.T, path string, want ...any) *check.Check {
+	t.Helper()
+	var c *check.Check
+	var err error
+	if len(want) == 0 {
+		c, err = check.JSONPath("p", path)
+	} else {
+		c, err = check.JSONPathEquals("p", path, want[0])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *check.Check
+		pass bool
+	}{
+		{"status hit", check.Status("s", 200, 201), true},
+		{"status miss", check.Status("s", 200), false},
+		{"header present", check.Header("h", "content-type", nil), true},
+		{"header match", check.Header("h", "Content-Type", regexp.MustCompile(`^application/json`)), true},
+		{"header mismatch", check.Header("h", "Content-Type", regexp.MustCompile(`xml`)), false},
+		{"header missing", check.Header("h", "X-Request-Id", nil), false},
+		{"body match", check.BodyMatch("b", regexp.MustCompile(`"name":"a\w+"`)), true},
+		{"body mismatch", check.BodyMatch("b", regexp.MustCompile(`error`)), false},
+		{"json exists", mustJSONPath(t, "$.user.name"), true},
+		{"json missing", mustJSONPath(t, "$.user.email"), false},
+		{"json number", mustJSONPath(t, "$.user.id", float64(42)), true},
+		{"json wrong value", mustJSONPath(t, "$.user.id", float64(7)), false},
+		{"json index", mustJSONPath(t, "$.user.tags[-1]", "b"), true},
+		{"json index out of range", mustJSONPath(t, "$.user.tags[2]"), false},
+		{"json quoted key", mustJSONPath(t, "$['user-count']", float64(1)), true},
+		{"json null", mustJSONPath(t, "$.next", nil), true},
+		{"json not object", mustJSONPath(t, "$.user.name.first"), false},
+		{"latency ok", check.MaxLatency("l", 200*time.Millisecond), true},
+		{"latency over", check.MaxLatency("l", 100*time.Millisecond), false},
+		{"size ok", check.MaxBodySize("z", 77), true},
+		{"size over", check.MaxBodySize("z", 76), false},
+	}
+	for _, tt := range tests {
+		if err := tt.c.Run(response); (err == nil) != tt.pass {
+			t.Errorf("%s: got %v, want pass=%t", tt.name, err, tt.pass)
+		}
+	}
+
+	if err := mustJSONPath(t, "$.a").Run(&check.Response{Body: []byte("<html>")}); err == nil {
+		t.Error("json_path on a non-JSON body should fail")
+	}
+	partial := &check.Response{Body: []byte(`{"a":1,"b":[`), Size: check.MaxBody + 1}
+	if err := mustJSONPath(t, "$.a").Run(partial); err == nil || !strings.Contains(err.Error(), "not inspected") {
+		t.Errorf("json_path on a body larger than MaxBody: got %v, want a not-inspected error", err)
+	}
+	if err := check.BodyMatch("b", regexp.MustCompile(`"a":1`)).Run(partial); err != nil {
+		t.Errorf("body_match within the kept bytes: got %v", err)
+	}
+	if err := check.BodyMatch("b", regexp.MustCompile(`"z"`)).Run(partial); err == nil || !strings.Contains(err.Error(), "not inspected") {
+		t.Errorf("body_match beyond the kept bytes: got %v, want a not-inspected error", err)
+	}
+	for _, bad := range []string{"user.id", "$.", "$.a[", "$.a[x]", "$a"} {
+		if _, err := check.JSONPath("p", bad); err == nil {
+			t.Errorf("JSONPath(%q): expected a parse error", bad)
+		}
+	}
+}
+
+func TestSet_RunAndDrain(t *testing.T) {
+	set, err := check.NewSet([]config.Check{
+		{Name: "created", Status: []int{201}},
+		{Name: "user-id", JSONPath: "$.user.id", Equals: json.RawMessage(`42`)},
+		{Name: "tiny", MaxBodySize: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.NeedsBody() {
+		t.Error("a json_path check needs the body")
+	}
+	results, err := set.Run(response)
+	if len(results) != 3 || results[0].Err != nil || results[1].Err != nil || results[2].Err == nil {
+		t.Fatalf("results: got %+v", results)
+	}
+	if err == nil || !strings.Contains(err.Error(), `check "tiny"`) || strings.Contains(err.Error(), `"created"`) {
+		t.Errorf("joined error: got %v", err)
+	}
+
+	big := strings.Repeat("x", check.MaxBody+100)
+	body, n, err := set.Drain(strings.NewReader(big))
+	if err != nil || n != int64(len(big)) || len(body) != check.MaxBody {
+		t.Errorf("Drain: got %d kept, %d read, %v", len(body), n, err)
+	}
+	status, _ := check.NewSet([]config.Check{{Name: "ok", Status: []int{200}}})
+	if body, n, _ := status.Drain(strings.NewReader("hello")); body != nil || n != 5 {
+		t.Errorf("Drain without body checks: got %q, %d", body, n)
+	}
+
+	if _, err := check.NewSet([]config.Check{{Name: "bad", BodyMatch: "("}}); err == nil {
+		t.Error("expected an error for a bad regular expression")
+	}
+}
@@ -0,0 +1,360 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Body is a replayable request body.  Every reader it hands out starts from
+// the beginning, so requests built with NewRequest carry a GetBody and can
+// be resent on redirects, retries and HTTP/3 fallback.  File contents are
+// streamed from disk on each send rather than held in memory.
+//
+// A Body is immutable and safe for concurrent use; one Body may back many
+// requests.
+type Body struct {
+	// ContentType is set as the Content-Type header by NewRequest.  Empty
+	// leaves the header alone.
+	ContentType string
+
+	// Length is the body size in bytes, or -1 when unknown (the body is
+	// then sent chunked).
+	Length int64
+
+	open func() (io.ReadCloser, error)
+}
+
+// Open returns a fresh reader over the body.  Files are opened on the first
+// Read, so a reader that is closed unread costs nothing.
+func (b *Body) Open() (io.ReadCloser, error) {
+	return &lazyReader{open: b.open}, nil
+}
+
+// NewRequest builds a request sending b, with GetBody, ContentLength and
+// the Content-Type header set.  A nil b builds a request without a body.
+func (b *Body) NewRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	if b == nil || b.Length == 0 {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err == nil && b != nil && b.ContentType != "" {
+			req.Header.Set("Content-Type", b.ContentType)
+		}
+		return req, err
+	}
+	rc, _ := b.Open()
+	req, err := http.NewRequestWithContext(ctx, method, url, rc)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = b.Length
+	req.GetBody = b.Open
+	if b.ContentType != "" {
+		req.Header.Set("Content-Type", b.ContentType)
+	}
+	return req, nil
+}
+
+// lazyReader defers opening a body until it is first read.
+type lazyReader struct {
+	open func() (io.ReadCloser, error)
+	rc   io.ReadCloser
+	err  error
+}
+
+func (r *lazyReader) Read(p []byte) (int, error) {
+	if r.rc == nil && r.err == nil {
+		r.rc, r.err = r.open()
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.rc.Read(p)
+}
+
+func (r *lazyReader) Close() error {
+	if r.rc == nil {
+		r.err = io.ErrClosedPipe
+		return nil
+	}
+	return r.rc.Close()
+}
+
+// BytesBody returns a body sending data with the given content type.
+func BytesBody(data []byte, contentType string) *Body {
+	return &Body{
+		ContentType: contentType,
+		Length:      int64(len(data)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+// FormBody returns an application/x-www-form-urlencoded body for values.
+func FormBody(values url.Values) *Body {
+	return BytesBody([]byte(values.Encode()), "application/x-www-form-urlencoded")
+}
+
+// JSONBody returns an application/json body holding v encoded as JSON.
+func JSONBody(v any) (*Body, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("client: encode JSON body: %w", err)
+	}
+	return BytesBody(data, "application/json"), nil
+}
+
+// JSONTemplateBody renders tmpl with data and returns the result as an
+// application/json body.  It fails if the output is not valid JSON, which
+// usually means a value was inserted without the json function.  Parse
+// tmpl once with ParseTemplate and render it per request.
+func JSONTemplateBody(tmpl *template.Template, data any) (*Body, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("client: render template %q: %w", tmpl.Name(), err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("client: template %q rendered invalid JSON: %.200q", tmpl.Name(), buf.Bytes())
+	}
+	return BytesBody(buf.Bytes(), "application/json"), nil
+}
+
+// FileBody returns a body streaming the file at path.  An empty contentType
+// is derived from the extension, falling back to application/octet-stream.
+// The size is taken now; the file must not change size while the body is
+// in use.
+func FileBody(path, contentType string) (*Body, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("client: body file: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("client: body file %q is not a regular file", path)
+	}
+	if contentType == "" {
+		contentType = contentTypeOf(path)
+	}
+	return &Body{
+		ContentType: contentType,
+		Length:      fi.Size(),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path) // #nosec G304 – caller-supplied path
+		},
+	}, nil
+}
+
+// contentTypeOf guesses the MIME type of a file from its extension.
+func contentTypeOf(path string) string {
+	if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// Multipart builds a multipart/form-data body from fields and files.  Files
+// are streamed from disk each time the body is sent, so uploads of any size
+// use constant memory.
+type Multipart struct {
+	parts []multipartPart
+	err   error
+}
+
+type multipartPart struct {
+	field, value string
+	path, name   string // file parts only
+	contentType  string
+	size         int64
+}
+
+// NewMultipart returns an empty multipart body builder.
+func NewMultipart() *Multipart { return &Multipart{} }
+
+// Field adds a form field.
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{field: name, value: value})
+	return m
+}
+
+// File adds the file at path as field, with its base name as the file name
+// and a content type derived from the extension.
+func (m *Multipart) File(field, path string) *Multipart {
+	return m.FileAs(field, path, filepath.Base(path), "")
+}
+
+// FileAs adds the file at path as field under the given file name and
+// content type.  An empty contentType is derived from the extension.
+func (m *Multipart) FileAs(field, path, filename, contentType string) *Multipart {
+	fi, err := os.Stat(path)
+	switch {
+	case err != nil:
+		m.setErr(fmt.Errorf("client: multipart file: %w", err))
+	case !fi.Mode().IsRegular():
+		m.setErr(fmt.Errorf("client: multipart file %q is not a regular file", path))
+	}
+	if contentType == "" {
+		contentType = contentTypeOf(path)
+	}
+	m.parts = append(m.parts, multipartPart{field: field, path: path, name: filename, contentType: contentType, size: sizeOf(fi)})
+	return m
+}
+
+func (m *Multipart) setErr(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+func sizeOf(fi os.FileInfo) int64 {
+	if fi == nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// Body returns the finished body, or the first error met while adding
+// files.  Its Length is exact, so uploads are not sent chunked.  Later
+// calls to the builder do not affect the returned body.
+func (m *Multipart) Body() (*Body, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	parts := append([]multipartPart(nil), m.parts...)
+	boundary := multipart.NewWriter(nil).Boundary()
+
+	// Size the body by writing it with every file replaced by its length.
+	var cw countWriter
+	if err := writeMultipart(&cw, boundary, parts, false); err != nil {
+		return nil, err
+	}
+	return &Body{
+		ContentType: "multipart/form-data; boundary=" + boundary,
+		Length:      int64(cw),
+		open: func() (io.ReadCloser, error) {
+			pr, pw := io.Pipe()
+			go func() {
+				pw.CloseWithError(writeMultipart(pw, boundary, parts, true))
+			}()
+			return pr, nil
+		},
+	}, nil
+}
+
+// writeMultipart writes parts to w.  Without withFiles, file contents are
+// skipped and w (a countWriter) is advanced by their size instead.
+func writeMultipart(w io.Writer, boundary string, parts []multipartPart, withFiles bool) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if p.path == "" {
+			if err := mw.WriteField(p.field, p.value); err != nil {
+				return err
+			}
+			continue
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(p.field), quoteEscaper.Replace(p.name)))
+		h.Set("Content-Type", p.contentType)
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if !withFiles {
+			*w.(*countWriter) += countWriter(p.size)
+			continue
+		}
+		if err := copyFile(pw, p.path, p.size); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// copyFile streams the file at path to w, failing if its size no longer
+// matches the size the body was built with.
+func copyFile(w io.Writer, path string, size int64) error {
+	f, err := os.Open(path) // #nosec G304 – caller-supplied path
+	if err != nil {
+		return fmt.Errorf("client: multipart file: %w", err)
+	}
+	defer f.Close()
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("client: multipart file %q changed size (%d bytes, want %d)", path, n, size)
+	}
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// countWriter counts the bytes written to it.
+type countWriter int64
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c += countWriter(len(p))
+	return len(p), nil
+}
+
+// TemplateFuncs returns the functions available in request templates:
+//
+//	uuid          a random version 4 UUID
+//	randInt lo hi a random integer in [lo, hi]
+//	now           the current UTC time in RFC 3339 format
+//	unixMilli     the current Unix time in milliseconds
+//	json v        v encoded as JSON, for values inside JSON documents
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"uuid": func() string {
+			var b [16]byte
+			_, _ = rand.Read(b[:])
+			b[6] = b[6]&0x0f | 0x40
+			b[8] = b[8]&0x3f | 0x80
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+		},
+		"randInt": func(lo, hi int64) (int64, error) {
+			if hi < lo {
+				return 0, fmt.Errorf("randInt: %d < %d", hi, lo)
+			}
+			n, err := rand.Int(rand.Reader, big.NewInt(hi-lo+1))
+			if err != nil {
+				return 0, err
+			}
+			return lo + n.Int64(), nil
+		},
+		"now":       func() string { return time.Now().UTC().Format(time.RFC3339Nano) },
+		"unixMilli": func() int64 { return time.Now().UnixMilli() },
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}
+}
+
+// ParseTemplate parses a request template with TemplateFuncs.  Referring to
+// a missing map key is an error rather than "<no value>".
+func ParseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(TemplateFuncs()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("client: parse template %q: %w", name, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,237 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// bodyEchoServer redirects /redirect to /echo with 307, which makes the
+// client resend the body, and answers /echo with the request's
+// Content-Type and Content-Length (-1 when chunked) in headers and the
+// body echoed back.
+func bodyEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			io.Copy(io.Discard, r.Body)
+			http.Redirect(w, r, "/echo", http.StatusTemporaryRedirect)
+			return
+		}
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Content-Length", strconv.FormatInt(r.ContentLength, 10))
+		io.Copy(w, r.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// send posts b to the server's /redirect and returns the echo response and
+// body.
+func send(t *testing.T, srv *httptest.Server, b *client.Body) (*http.Response, []byte) {
+	t.Helper()
+	req, err := b.NewRequest(context.Background(), http.MethodPost, srv.URL+"/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Request.URL.Path != "/echo" {
+		t.Fatalf("request ended at %s, want /echo", resp.Request.URL.Path)
+	}
+	return resp, data
+}
+
+func TestFormAndJSONBody_ReplayedOnRedirect(t *testing.T) {
+	srv := bodyEchoServer(t)
+
+	resp, data := send(t, srv, client.FormBody(url.Values{"user": {"a b"}, "pin": {"1&2"}}))
+	if string(data) != "pin=1%262&user=a+b" {
+		t.Errorf("form body: got %q", data)
+	}
+	if ct := resp.Header.Get("X-Content-Type"); ct != "application/x-www-form-urlencoded" {
+		t.Errorf("form Content-Type: got %q", ct)
+	}
+
+	b, err := client.JSONBody(map[string]int{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, data = send(t, srv, b)
+	if string(data) != `{"n":1}` || resp.Header.Get("X-Content-Type") != "application/json" {
+		t.Errorf("JSON body: got %q (%s)", data, resp.Header.Get("X-Content-Type"))
+	}
+}
+
+func TestJSONTemplateBody(t *testing.T) {
+	tmpl, err := client.ParseTemplate("login", `{"user":{{json .User}},"id":"{{uuid}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := client.JSONTemplateBody(tmpl, map[string]string{"User": `quote"d`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, _ := b.Open()
+	data, _ := io.ReadAll(rc)
+	if !bytes.HasPrefix(data, []byte(`{"user":"quote\"d","id":"`)) || int64(len(data)) != b.Length {
+		t.Errorf("rendered %q (Length %d)", data, b.Length)
+	}
+
+	raw, _ := client.ParseTemplate("raw", `{"user":"{{.User}}"}`)
+	if _, err := client.JSONTemplateBody(raw, map[string]string{"User": `quote"d`}); err == nil {
+		t.Error("expected an error for a template rendering invalid JSON")
+	}
+	if _, err := client.JSONTemplateBody(raw, map[string]string{}); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+}
+
+func TestFileBody_Streams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, []byte(`{"big":true}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := client.FileBody(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, data := send(t, bodyEchoServer(t), b)
+	if string(data) != `{"big":true}` {
+		t.Errorf("body: got %q", data)
+	}
+	if ct := resp.Header.Get("X-Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type: got %q", ct)
+	}
+	if cl := resp.Header.Get("X-Content-Length"); cl != "12" {
+		t.Errorf("Content-Length: got %s, want 12", cl)
+	}
+	if _, err := client.FileBody(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestMultipart_UploadReplayedOnRedirect(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	logData := bytes.Repeat([]byte("line of log output\n"), 50_000) // ~1 MB
+	if err := os.WriteFile(logPath, logData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	imgPath := filepath.Join(dir, "avatar.png")
+	if err := os.WriteFile(imgPath, []byte("\x89PNG"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := client.NewMultipart().
+		Field("title", "weekly report").
+		File("log", logPath).
+		FileAs("avatar", imgPath, `me "2".png`, "").
+		Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		title, avatarName, avatarType string
+		log, avatar                   []byte
+		length                        int64
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			io.Copy(io.Discard, r.Body)
+			http.Redirect(w, r, "/upload", http.StatusPermanentRedirect)
+			return
+		}
+		got.length = r.ContentLength
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got.title = r.FormValue("title")
+		read := func(field string) []byte {
+			f, fh, err := r.FormFile(field)
+			if err != nil {
+				return nil
+			}
+			defer f.Close()
+			data, _ := io.ReadAll(f)
+			if field == "avatar" {
+				got.avatarName, got.avatarType = fh.Filename, fh.Header.Get("Content-Type")
+			}
+			return data
+		}
+		got.log, got.avatar = read("log"), read("avatar")
+	}))
+	defer srv.Close()
+
+	req, err := b.NewRequest(context.Background(), http.MethodPost, srv.URL+"/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: got %d", resp.StatusCode)
+	}
+	if got.length != b.Length {
+		t.Errorf("Content-Length: got %d, want %d", got.length, b.Length)
+	}
+	if got.title != "weekly report" {
+		t.Errorf("title: got %q", got.title)
+	}
+	if !bytes.Equal(got.log, logData) {
+		t.Errorf("log file: got %d bytes, want %d", len(got.log), len(logData))
+	}
+	if string(got.avatar) != "\x89PNG" || got.avatarName != `me "2".png` || got.avatarType != "image/png" {
+		t.Errorf("avatar: got %q named %q (%s)", got.avatar, got.avatarName, got.avatarType)
+	}
+
+	if _, err := client.NewMultipart().File("x", filepath.Join(dir, "missing")).Body(); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestMultipart_CloseMidStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, make([]byte, 1<<20), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := client.NewMultipart().File("f", path).Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, _ := b.Open()
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(rc, buf); err != nil {
+		t.Fatal(err)
+	}
+	// Closing mid-stream must stop the writer goroutine; reads fail after.
+	rc.Close()
+	if _, err := rc.Read(buf); err == nil {
+		t.Error("read after Close should fail")
+	}
+	if !strings.HasPrefix(b.ContentType, "multipart/form-data; boundary=") {
+		t.Errorf("ContentType: got %q", b.ContentType)
+	}
+}
@@ -0,0 +1,456 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is wrapped by the errors of requests a circuit breaker
+// refused to send.
+var ErrCircuitOpen = errors.New("client: circuit open")
+
+// CircuitOpenError is returned for a request short-circuited by an open
+// (or probing half-open) breaker.  It unwraps to ErrCircuitOpen.
+type CircuitOpenError struct {
+	// Key is the host or target name whose breaker refused the request.
+	Key string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("client: circuit open for %q", e.Key)
+}
+
+func (e *CircuitOpenError) Unwrap() error { return ErrCircuitOpen }
+
+// BreakerState is the state of one circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through and tracks their failure rate.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen refuses every request until the cool-down has passed.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a few probe requests through.  If they all
+	// succeed the breaker closes; any failure opens it again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// MarshalText encodes s by name, for JSON.
+func (s BreakerState) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// BreakerKey selects what a breaker is keyed by.
+type BreakerKey string
+
+const (
+	// BreakerByHost keeps one breaker per request host (host:port).
+	BreakerByHost BreakerKey = "host"
+
+	// BreakerByTarget keeps one breaker per target name, as set with
+	// WithBreakerKey.  Requests without one fall back to their host.
+	BreakerByTarget BreakerKey = "target"
+)
+
+// BreakerObserver receives the activity of circuit breakers, e.g. to feed
+// metrics.  Calls are made after the breaker's lock is released.
+type BreakerObserver interface {
+	// BreakerStateChanged reports a transition of key's breaker.
+	BreakerStateChanged(key string, from, to BreakerState)
+
+	// BreakerRejected reports a request short-circuited by key's breaker.
+	BreakerRejected(key string)
+}
+
+// BreakerOptions tunes NewBreakers.  Start from DefaultBreakerOptions.
+type BreakerOptions struct {
+	// Key selects per-host or per-target breakers.  Empty means
+	// BreakerByHost.
+	Key BreakerKey
+
+	// Window is the length of the closed state's counting window.  The
+	// failure rate is computed over the requests of the current window.
+	Window time.Duration
+
+	// MinRequests is the number of requests a window needs before its
+	// failure rate can open the breaker.
+	MinRequests int
+
+	// FailureRate in (0, 1] opens the breaker once reached.
+	FailureRate float64
+
+	// CoolDown is how long an open breaker refuses requests before
+	// letting probes through.
+	CoolDown time.Duration
+
+	// HalfOpenRequests is the number of probes that must succeed to close
+	// the breaker again.
+	HalfOpenRequests int
+
+	// Observer, if non-nil, receives state changes and rejections.
+	Observer BreakerObserver
+
+	// OnStateChange, if non-nil, is called on every state change, e.g.
+	// to log it.
+	OnStateChange func(key string, from, to BreakerState)
+}
+
+// DefaultBreakerOptions opens a host's breaker when at least half of at
+// least 20 requests within 10 seconds fail, and probes it again with one
+// request after 30 seconds.
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		Key:              BreakerByHost,
+		Window:           10 * time.Second,
+		MinRequests:      20,
+		FailureRate:      0.5,
+		CoolDown:         30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// Breakers is a set of circuit breakers, one per host or target, created on
+// first use.  It is safe for concurrent use and is usually shared by every
+// session, so a failing host is shed by all of them at once.  Install it
+// with TransportOptions.Breakers or NewBreakerTransport.
+//
+// A request fails, for the breaker, when it returns an error or a 5xx
+// response.  Requests canceled by their caller are not counted.
+type Breakers struct {
+	opts     BreakerOptions
+	breakers sync.Map // key -> *breaker
+}
+
+// NewBreakers returns an empty set of breakers.  Zero fields of opts take
+// their DefaultBreakerOptions values.
+func NewBreakers(opts BreakerOptions) *Breakers {
+	def := DefaultBreakerOptions()
+	if opts.Key == "" {
+		opts.Key = def.Key
+	}
+	if opts.Window <= 0 {
+		opts.Window = def.Window
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = def.MinRequests
+	}
+	if opts.FailureRate <= 0 || opts.FailureRate > 1 {
+		opts.FailureRate = def.FailureRate
+	}
+	if opts.CoolDown <= 0 {
+		opts.CoolDown = def.CoolDown
+	}
+	if opts.HalfOpenRequests <= 0 {
+		opts.HalfOpenRequests = def.HalfOpenRequests
+	}
+	return &Breakers{opts: opts}
+}
+
+// breaker is the state of one key.  gen increments on every transition so
+// the outcome of a request admitted in an earlier state is ignored.
+type breaker struct {
+	mu        sync.Mutex
+	state     BreakerState
+	gen       uint64
+	start     time.Time // of the window (closed) or of the open state
+	requests  int       // closed: in the window; half-open: probes admitted
+	failures  int       // closed: in the window
+	successes int       // half-open: probes succeeded
+	trips     uint64
+	rejected  uint64
+}
+
+func (b *Breakers) get(key string) *breaker {
+	v, ok := b.breakers.Load(key)
+	if !ok {
+		v, _ = b.breakers.LoadOrStore(key, &breaker{start: time.Now()})
+	}
+	return v.(*breaker)
+}
+
+// transition is a state change to report once the lock is released.
+type transition struct {
+	from, to BreakerState
+}
+
+// setState moves br to state; br.mu must be held.
+func (br *breaker) setState(to BreakerState, now time.Time) transition {
+	t := transition{from: br.state, to: to}
+	br.state, br.gen, br.start = to, br.gen+1, now
+	br.requests, br.failures, br.successes = 0, 0, 0
+	if to == BreakerOpen {
+		br.trips++
+	}
+	return t
+}
+
+// Allow reports whether a request for key may be sent.  When it may, done
+// must be called with the request's outcome.
+func (b *Breakers) Allow(key string) (done func(failed bool), ok bool) {
+	br, gen, ok := b.allow(key)
+	if !ok {
+		return nil, false
+	}
+	return func(failed bool) { b.done(key, br, gen, failed) }, true
+}
+
+// allow admits or rejects a request for key and returns the generation it
+// was admitted in.
+func (b *Breakers) allow(key string) (br *breaker, gen uint64, ok bool) {
+	br = b.get(key)
+	now := time.Now()
+	var ts []transition
+
+	br.mu.Lock()
+	if br.state == BreakerOpen && now.Sub(br.start) >= b.opts.CoolDown {
+		ts = append(ts, br.setState(BreakerHalfOpen, now))
+	}
+	switch br.state {
+	case BreakerClosed:
+		if now.Sub(br.start) >= b.opts.Window {
+			br.start, br.requests, br.failures = now, 0, 0
+		}
+		ok = true
+	case BreakerHalfOpen:
+		if br.requests < b.opts.HalfOpenRequests {
+			br.requests++
+			ok = true
+		}
+	}
+	if !ok {
+		br.rejected++
+	}
+	gen = br.gen
+	br.mu.Unlock()
+
+	b.report(key, ts)
+	if !ok && b.opts.Observer != nil {
+		b.opts.Observer.BreakerRejected(key)
+	}
+	return br, gen, ok
+}
+
+// Check reports whether a request for key would be short-circuited now,
+// without admitting it.  A refused request is counted as rejected and
+// reported as a *CircuitOpenError.  Callers use Check to skip the work of
+// building a request, such as drawing test data, for a host that is
+// known to be down; the transport still decides on the request itself.
+func (b *Breakers) Check(key string) error {
+	br := b.get(key)
+	now := time.Now()
+
+	br.mu.Lock()
+	var refuse bool
+	switch br.state {
+	case BreakerOpen:
+		refuse = now.Sub(br.start) < b.opts.CoolDown
+	case BreakerHalfOpen:
+		refuse = br.requests >= b.opts.HalfOpenRequests
+	}
+	if refuse {
+		br.rejected++
+	}
+	br.mu.Unlock()
+
+	if !refuse {
+		return nil
+	}
+	if b.opts.Observer != nil {
+		b.opts.Observer.BreakerRejected(key)
+	}
+	return &CircuitOpenError{Key: key}
+}
+
+// KeyFor returns the key of the breaker that requests of the named target
+// to rawURL are counted against, or "" when it cannot be known before the
+// request is built because the host is templated.
+func (b *Breakers) KeyFor(target, rawURL string) string {
+	if b.opts.Key == BreakerByTarget && target != "" {
+		return target
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || strings.Contains(u.Host, "{") {
+		return ""
+	}
+	return u.Host
+}
+
+// release forgets a request admitted in generation gen without counting
+// it, freeing its probe slot if the breaker is still half-open.
+func (b *Breakers) release(br *breaker, gen uint64) {
+	br.mu.Lock()
+	if gen == br.gen && br.state == BreakerHalfOpen {
+		br.requests--
+	}
+	br.mu.Unlock()
+}
+
+// done records the outcome of a request admitted in generation gen.
+func (b *Breakers) done(key string, br *breaker, gen uint64, failed bool) {
+	now := time.Now()
+	var ts []transition
+
+	br.mu.Lock()
+	if gen == br.gen {
+		switch br.state {
+		case BreakerClosed:
+			br.requests++
+			if failed {
+				br.failures++
+			}
+			if br.requests >= b.opts.MinRequests &&
+				float64(br.failures) >= b.opts.FailureRate*float64(br.requests) {
+				ts = append(ts, br.setState(BreakerOpen, now))
+			}
+		case BreakerHalfOpen:
+			if failed {
+				ts = append(ts, br.setState(BreakerOpen, now))
+			} else if br.successes++; br.successes >= b.opts.HalfOpenRequests {
+				ts = append(ts, br.setState(BreakerClosed, now))
+			}
+		}
+	}
+	br.mu.Unlock()
+
+	b.report(key, ts)
+}
+
+func (b *Breakers) report(key string, ts []transition) {
+	for _, t := range ts {
+		if b.opts.Observer != nil {
+			b.opts.Observer.BreakerStateChanged(key, t.from, t.to)
+		}
+		if b.opts.OnStateChange != nil {
+			b.opts.OnStateChange(key, t.from, t.to)
+		}
+	}
+}
+
+// BreakerStatus reports the state of one breaker.
+type BreakerStatus struct {
+	Key   string       `json:"key"`
+	State BreakerState `json:"state"`
+
+	// Requests and Failures count the current window of a closed breaker.
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+
+	// Since is when the breaker entered its state (or, when closed, began
+	// its window).
+	Since time.Time `json:"since"`
+
+	// Trips counts transitions to open; Rejected counts short-circuited
+	// requests.
+	Trips    uint64 `json:"trips"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// Status returns the status of every breaker, sorted by key.
+func (b *Breakers) Status() []BreakerStatus {
+	var out []BreakerStatus
+	b.breakers.Range(func(k, v any) bool {
+		br := v.(*breaker)
+		br.mu.Lock()
+		out = append(out, BreakerStatus{
+			Key:      k.(string),
+			State:    br.state,
+			Requests: br.requests,
+			Failures: br.failures,
+			Since:    br.start,
+			Trips:    br.trips,
+			Rejected: br.rejected,
+		})
+		br.mu.Unlock()
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// key returns the breaker key of req.
+func (b *Breakers) key(req *http.Request) string {
+	if b.opts.Key == BreakerByTarget {
+		if k := BreakerKeyFrom(req.Context()); k != "" {
+			return k
+		}
+	}
+	return req.URL.Host
+}
+
+type breakerKeyCtx struct{}
+
+// WithBreakerKey returns a context whose requests are counted against the
+// breaker named key when breakers are keyed by target.
+func WithBreakerKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, breakerKeyCtx{}, key)
+}
+
+// BreakerKeyFrom returns the key set by WithBreakerKey, or "".
+func BreakerKeyFrom(ctx context.Context) string {
+	k, _ := ctx.Value(breakerKeyCtx{}).(string)
+	return k
+}
+
+// NewBreakerTransport returns a RoundTripper that sends requests through
+// base unless their breaker in b is open, in which case it fails them with
+// a *CircuitOpenError without contacting the server.
+func NewBreakerTransport(base http.RoundTripper, b *Breakers) http.RoundTripper {
+	return &breakerTransport{base: base, breakers: b}
+}
+
+type breakerTransport struct {
+	base     http.RoundTripper
+	breakers *Breakers
+}
+
+// CloseIdleConnections forwards to the wrapped transport.
+func (t *breakerTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// Unwrap returns the wrapped transport.
+func (t *breakerTransport) Unwrap() http.RoundTripper { return t.base }
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.breakers
+	key := b.key(req)
+	br, gen, ok := b.allow(key)
+	if !ok {
+		closeBody(req)
+		return nil, &CircuitOpenError{Key: key}
+	}
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		// Canceled by the caller: says nothing about the server.
+		b.release(br, gen)
+	case err != nil:
+		// Transport errors and timeouts, including an expired deadline
+		// such as http.Client.Timeout, count against the server.
+		b.done(key, br, gen, true)
+	default:
+		b.done(key, br, gen, resp.StatusCode >= 500)
+	}
+	return resp, err
+}
@@ -0,0 +1,268 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// breakerClient returns a client whose requests to a test server pass
+// through b, the server URL, a switch that makes the server answer 503, and
+// a counter of the requests that reached it.
+func breakerClient(t *testing.T, b *client.Breakers) (*http.Client, string, *atomic.Bool, *atomic.Int32) {
+	t.Helper()
+	var failing atomic.Bool
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	opts := client.DefaultTransportOptions()
+	opts.Breakers = b
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv.URL, &failing, &hits
+}
+
+// transitions records the state changes reported by a Breakers.
+type transitions struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (tr *transitions) add(_ string, from, to client.BreakerState) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.got = append(tr.got, from.String()+">"+to.String())
+}
+
+func (tr *transitions) list() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return slices.Clone(tr.got)
+}
+
+func TestBreaker_TripsAndShortCircuits(t *testing.T) {
+	var tr transitions
+	b := client.NewBreakers(client.BreakerOptions{
+		MinRequests:   4,
+		FailureRate:   0.5,
+		CoolDown:      time.Hour,
+		OnStateChange: tr.add,
+	})
+	c, url, failing, hits := breakerClient(t, b)
+
+	for range 2 {
+		if _, _, err := get(c, url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failing.Store(true)
+	for range 2 {
+		if status, _, err := get(c, url); err != nil || status != http.StatusServiceUnavailable {
+			t.Fatalf("got %d, %v; want a 503 from the server", status, err)
+		}
+	}
+
+	_, _, err := get(c, url)
+	var open *client.CircuitOpenError
+	if !errors.Is(err, client.ErrCircuitOpen) || !errors.As(err, &open) {
+		t.Fatalf("got %v, want a short-circuited request", err)
+	}
+	if hits.Load() != 4 {
+		t.Errorf("server saw %d requests, want 4", hits.Load())
+	}
+	if got := tr.list(); !slices.Equal(got, []string{"closed>open"}) {
+		t.Errorf("transitions: got %v", got)
+	}
+	st := b.Status()
+	if len(st) != 1 || st[0].Key != open.Key || st[0].State != client.BreakerOpen || st[0].Trips != 1 || st[0].Rejected != 1 {
+		t.Errorf("status: got %+v", st)
+	}
+}
+
+func TestBreaker_HalfOpenRecoversOrReopens(t *testing.T) {
+	var tr transitions
+	b := client.NewBreakers(client.BreakerOptions{
+		MinRequests:      1,
+		FailureRate:      1,
+		CoolDown:         20 * time.Millisecond,
+		HalfOpenRequests: 2,
+		OnStateChange:    tr.add,
+	})
+	c, url, failing, _ := breakerClient(t, b)
+
+	failing.Store(true)
+	get(c, url) // trips
+	time.Sleep(30 * time.Millisecond)
+	get(c, url) // failed probe reopens
+	time.Sleep(30 * time.Millisecond)
+
+	failing.Store(false)
+	for i := range 2 {
+		if _, _, err := get(c, url); err != nil {
+			t.Fatalf("probe %d: %v", i, err)
+		}
+	}
+	want := []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}
+	if got := tr.list(); !slices.Equal(got, want) {
+		t.Errorf("transitions: got %v, want %v", got, want)
+	}
+}
+
+func TestBreaker_HalfOpenLimitsProbes(t *testing.T) {
+	b := client.NewBreakers(client.BreakerOptions{MinRequests: 1, FailureRate: 1, CoolDown: time.Millisecond})
+	done, ok := b.Allow("h")
+	if !ok {
+		t.Fatal("closed breaker refused a request")
+	}
+	done(true)
+	time.Sleep(5 * time.Millisecond)
+
+	probe, ok := b.Allow("h")
+	if !ok {
+		t.Fatal("half-open breaker refused its probe")
+	}
+	if _, ok := b.Allow("h"); ok {
+		t.Error("half-open breaker admitted a second concurrent probe")
+	}
+	probe(false)
+	if _, ok := b.Allow("h"); !ok {
+		t.Error("breaker did not close after a successful probe")
+	}
+}
+
+func TestBreaker_KeyedByTarget(t *testing.T) {
+	b := client.NewBreakers(client.BreakerOptions{
+		Key:         client.BreakerByTarget,
+		MinRequests: 1,
+		FailureRate: 1,
+		CoolDown:    time.Hour,
+	})
+	c, url, failing, _ := breakerClient(t, b)
+
+	send := func(target string) error {
+		req, _ := http.NewRequestWithContext(client.WithBreakerKey(context.Background(), target), http.MethodGet, url, nil)
+		resp, err := c.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	failing.Store(true)
+	send("login")
+	failing.Store(false)
+	if err := send("login"); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Errorf("login: got %v, want a short-circuited request", err)
+	}
+	if err := send("search"); err != nil {
+		t.Errorf("search shares the host but not the breaker: got %v", err)
+	}
+}
+
+func TestBreaker_CheckDoesNotAdmit(t *testing.T) {
+	b := client.NewBreakers(client.BreakerOptions{MinRequests: 1, FailureRate: 1, CoolDown: time.Millisecond})
+	if err := b.Check("h"); err != nil {
+		t.Fatalf("closed breaker: got %v", err)
+	}
+	done, _ := b.Allow("h")
+	done(true)
+	if err := b.Check("h"); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Errorf("open breaker: got %v, want ErrCircuitOpen", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// Checks during the half-open state must not use up the probe slot.
+	for range 3 {
+		if err := b.Check("h"); err != nil {
+			t.Fatalf("half-open breaker with a free probe: got %v", err)
+		}
+	}
+	if _, ok := b.Allow("h"); !ok {
+		t.Error("probe refused after Check")
+	}
+	if err := b.Check("h"); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Errorf("half-open breaker with its probe in flight: got %v", err)
+	}
+	if st := b.Status(); st[0].Rejected != 2 {
+		t.Errorf("rejected: got %d, want 2", st[0].Rejected)
+	}
+}
+
+func TestBreaker_KeyFor(t *testing.T) {
+	host := client.NewBreakers(client.BreakerOptions{})
+	byTarget := client.NewBreakers(client.BreakerOptions{Key: client.BreakerByTarget})
+	for _, tc := range []struct {
+		b           *client.Breakers
+		target, url string
+		want        string
+	}{
+		{host, "login", "https://api.example.com:8443/login", "api.example.com:8443"},
+		{host, "user", "https://{{.Row.host}}/user", ""},
+		{byTarget, "login", "https://api.example.com/login", "login"},
+		{byTarget, "", "https://api.example.com/login", "api.example.com"},
+	} {
+		if got := tc.b.KeyFor(tc.target, tc.url); got != tc.want {
+			t.Errorf("KeyFor(%q, %q): got %q, want %q", tc.target, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestBreaker_ClientTimeoutCountsAsFailure(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	b := client.NewBreakers(client.BreakerOptions{MinRequests: 3, FailureRate: 1, CoolDown: time.Hour})
+	opts := client.DefaultTransportOptions()
+	opts.Breakers = b
+	c, err := client.NewHTTPClientWithOptions("", 20*time.Millisecond, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, _, err := get(c, srv.URL); err == nil || errors.Is(err, client.ErrCircuitOpen) {
+			t.Fatalf("request %d: got %v, want a client timeout", i, err)
+		}
+	}
+	if _, _, err := get(c, srv.URL); !errors.Is(err, client.ErrCircuitOpen) {
+		t.Errorf("got %v, want the breaker tripped by timeouts", err)
+	}
+	if st := b.Status(); len(st) != 1 || st[0].State != client.BreakerOpen || st[0].Trips != 1 {
+		t.Errorf("status: got %+v", st)
+	}
+}
+
+func TestBreaker_CallerCancelIsNotCounted(t *testing.T) {
+	b := client.NewBreakers(client.BreakerOptions{MinRequests: 1, FailureRate: 1, CoolDown: time.Hour})
+	c, url, _, _ := breakerClient(t, b)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if _, err := c.Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if _, _, err := get(c, url); err != nil {
+		t.Errorf("a canceled request tripped the breaker: %v", err)
+	}
+}
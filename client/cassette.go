@@ -0,0 +1,506 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNoRecordedResponse is returned by a Replayer for a request that does
+// not match any recorded interaction.
+var ErrNoRecordedResponse = errors.New("client: no recorded response")
+
+// CassetteFormat is the file format of a cassette.
+type CassetteFormat string
+
+const (
+	// CassetteJSONL writes one JSON Interaction per line as it happens,
+	// so a crashed run keeps everything recorded so far.
+	CassetteJSONL CassetteFormat = "jsonl"
+
+	// CassetteHAR writes an HTTP Archive, one entry as each interaction
+	// completes; Close finishes the document.  The file opens in browser
+	// developer tools and HAR viewers.  A run that ends without Close
+	// leaves the document unterminated, so prefer JSONL for long runs.
+	CassetteHAR CassetteFormat = "har"
+)
+
+// CassetteFormatFor returns the format implied by path: HAR for a ".har"
+// extension, JSONL otherwise.
+func CassetteFormatFor(path string) CassetteFormat {
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		return CassetteHAR
+	}
+	return CassetteJSONL
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration_ns"`
+
+	// Tag identifies the recording client, e.g. the session ID.
+	Tag string `json:"tag,omitempty"`
+
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+
+	// Error is the transport error, if the request failed; Response is
+	// then empty.
+	Error string `json:"error,omitempty"`
+}
+
+// RecordedRequest is the request half of an Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Body is the request body; BodyEncoding is "base64" when it is not
+	// valid UTF-8 and has been base64-encoded.
+	Body         string `json:"body,omitempty"`
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+// RecordedResponse is the response half of an Interaction.  The body is
+// recorded after decompression, so Content-Encoding is never set.
+type RecordedResponse struct {
+	Status       int         `json:"status"`
+	Proto        string      `json:"proto,omitempty"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+
+	// BodySize is the full size of a body that was cut at the Recorder's
+	// MaxBodyBytes, so Body holds only its start.  It is zero when Body is
+	// complete.
+	BodySize int64 `json:"body_size,omitempty"`
+}
+
+// encodeBody returns b as text plus "base64" if it had to be encoded.
+func encodeBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+// decodeBody reverses encodeBody.
+func decodeBody(text, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
+
+func (r RecordedRequest) size() int {
+	b, _ := decodeBody(r.Body, r.BodyEncoding)
+	return len(b)
+}
+
+// size returns the full body size, including any part cut off when it was
+// recorded.
+func (r RecordedResponse) size() int {
+	if r.BodySize > 0 {
+		return int(r.BodySize)
+	}
+	b, _ := decodeBody(r.Body, r.BodyEncoding)
+	return len(b)
+}
+
+// RecorderOptions configures NewRecorderWithOptions.
+type RecorderOptions struct {
+	// Format is the cassette format.  Empty picks it from the path's
+	// extension; see CassetteFormatFor.
+	Format CassetteFormat
+
+	// MaxBodyBytes caps the response body bytes recorded per interaction.
+	// A longer body is still passed on in full, but only its first
+	// MaxBodyBytes are recorded; see RecordedResponse.BodySize.  Zero
+	// records whole bodies.
+	MaxBodyBytes int
+}
+
+// Recorder writes interactions to a cassette file as they complete.  It is
+// safe for concurrent use, so one Recorder can serve many sessions.
+type Recorder struct {
+	mu      sync.Mutex
+	f       *os.File
+	format  CassetteFormat
+	maxBody int
+	n       int
+	closed  bool
+}
+
+// NewRecorder creates (or truncates) the cassette at path and records
+// whole bodies.  An empty format is chosen from the extension; see
+// CassetteFormatFor.
+func NewRecorder(path string, format CassetteFormat) (*Recorder, error) {
+	return NewRecorderWithOptions(path, RecorderOptions{Format: format})
+}
+
+// NewRecorderWithOptions creates (or truncates) the cassette at path as
+// opts describes.
+func NewRecorderWithOptions(path string, opts RecorderOptions) (*Recorder, error) {
+	format := opts.Format
+	if format == "" {
+		format = CassetteFormatFor(path)
+	}
+	if format != CassetteJSONL && format != CassetteHAR {
+		return nil, fmt.Errorf("client: unknown cassette format %q", format)
+	}
+	f, err := os.Create(path) // #nosec G304 – operator-supplied config path
+	if err != nil {
+		return nil, fmt.Errorf("client: create cassette: %w", err)
+	}
+	r := &Recorder{f: f, format: format, maxBody: max(opts.MaxBodyBytes, 0)}
+	if format == CassetteHAR {
+		if _, err := f.Write(harHead()); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("client: write cassette: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// harHead returns the start of a HAR document up to the opening bracket of
+// its entries array.  HARLog.Entries is the last field, so the encoding of
+// an empty document ends with "[]}}".
+func harHead() []byte {
+	doc, _ := json.Marshal(NewHAR(nil))
+	return append(doc[:len(doc)-len("]}}")], '\n')
+}
+
+// harTail closes the document opened by harHead.
+const harTail = "\n]}}\n"
+
+// Record appends in to the cassette.
+func (r *Recorder) Record(in Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("client: record: cassette %s is closed", r.f.Name())
+	}
+	var line []byte
+	var err error
+	if r.format == CassetteHAR {
+		// Entries are separated by commas; the first follows harHead.
+		if line, err = json.Marshal(in.HAREntry()); err == nil && r.n > 0 {
+			line = append([]byte(",\n"), line...)
+		}
+	} else if line, err = json.Marshal(in); err == nil {
+		line = append(line, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("client: record: %w", err)
+	}
+	if _, err := r.f.Write(line); err != nil {
+		return fmt.Errorf("client: record: %w", err)
+	}
+	r.n++
+	return nil
+}
+
+// Len returns the number of interactions recorded so far.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// Close finishes the cassette: a HAR document is terminated, then the file
+// is closed.  Later Record calls fail.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if r.format == CassetteHAR {
+		if _, err := io.WriteString(r.f, harTail); err != nil {
+			r.f.Close()
+			return fmt.Errorf("client: write cassette: %w", err)
+		}
+	}
+	if err := r.f.Close(); err != nil {
+		return fmt.Errorf("client: close cassette: %w", err)
+	}
+	return nil
+}
+
+// LoadCassette reads every interaction from a cassette written by a
+// Recorder.  The format is detected from the content, so HAR files exported
+// by a browser load as well.
+func LoadCassette(path string) ([]Interaction, error) {
+	f, err := os.Open(path) // #nosec G304 – operator-supplied config path
+	if err != nil {
+		return nil, fmt.Errorf("client: open cassette: %w", err)
+	}
+	defer f.Close()
+
+	var out []Interaction
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for first := true; ; first = false {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return nil, fmt.Errorf("client: cassette %s: %w", path, err)
+		}
+		if first {
+			var probe struct {
+				Log *HARLog `json:"log"`
+			}
+			if err := json.Unmarshal(raw, &probe); err == nil && probe.Log != nil {
+				for _, e := range probe.Log.Entries {
+					out = append(out, e.Interaction())
+				}
+				return out, nil
+			}
+		}
+		var in Interaction
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return nil, fmt.Errorf("client: cassette %s: interaction %d: %w", path, len(out)+1, err)
+		}
+		out = append(out, in)
+	}
+}
+
+// recordingTransport copies every exchange through base into a Recorder.
+type recordingTransport struct {
+	base http.RoundTripper
+	rec  *Recorder
+	tag  string
+}
+
+// NewRecordingTransport returns a RoundTripper that sends requests through
+// base and records each request and response to rec, labelled with tag.
+// The response body is copied as the caller reads it and the interaction is
+// written when the body is exhausted or closed, so streaming responses are
+// not held up.  Protocol upgrades (101) are recorded without a body.
+func NewRecordingTransport(base http.RoundTripper, rec *Recorder, tag string) http.RoundTripper {
+	return &recordingTransport{base: base, rec: rec, tag: tag}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := snapshotBody(req)
+	if err != nil {
+		return nil, err
+	}
+	in := Interaction{
+		Time: time.Now(),
+		Tag:  t.tag,
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+		},
+	}
+	in.Request.Body, in.Request.BodyEncoding = encodeBody(reqBody)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		in.Duration = time.Since(in.Time)
+		in.Error = err.Error()
+		_ = t.rec.Record(in)
+		return nil, err
+	}
+	in.Response = RecordedResponse{Status: resp.StatusCode, Proto: resp.Proto, Header: resp.Header.Clone()}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		in.Duration = time.Since(in.Time)
+		_ = t.rec.Record(in)
+		return resp, nil
+	}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, rec: t.rec, in: in}
+	return resp, nil
+}
+
+// recordingBody copies a response body, up to the Recorder's MaxBodyBytes,
+// while it is read and records the interaction once, at EOF, on a read
+// error or on Close.
+type recordingBody struct {
+	io.ReadCloser
+	rec  *Recorder
+	in   Interaction
+	buf  bytes.Buffer
+	n    int64 // body bytes read, kept or not
+	once sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	keep := n
+	if limit := b.rec.maxBody; limit > 0 {
+		keep = min(n, max(limit-b.buf.Len(), 0))
+	}
+	b.buf.Write(p[:keep])
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish(nil)
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) finish(err error) {
+	b.once.Do(func() {
+		b.in.Duration = time.Since(b.in.Time)
+		if err != nil && !errors.Is(err, io.EOF) {
+			b.in.Error = err.Error()
+		}
+		b.in.Response.Body, b.in.Response.BodyEncoding = encodeBody(b.buf.Bytes())
+		if b.n > int64(b.buf.Len()) {
+			b.in.Response.BodySize = b.n
+		}
+		_ = b.rec.Record(b.in)
+	})
+}
+
+// CloseIdleConnections forwards to the wrapped transport.
+func (t *recordingTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// Unwrap returns the wrapped transport.
+func (t *recordingTransport) Unwrap() http.RoundTripper { return t.base }
+
+// snapshotBody returns a copy of req's body, leaving req able to send it.
+func snapshotBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("client: copy request body: %w", err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("client: read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+// Replayer is a RoundTripper that answers requests from recorded
+// interactions without touching the network.  A request matches an
+// interaction with the same method, URL and body.  Several matching
+// interactions are served in recorded order; once they are used up the
+// last one keeps being served, so a replay can run longer than the
+// recording.
+//
+// A Replayer is safe for concurrent use and may be shared by many clients.
+type Replayer struct {
+	mu    sync.Mutex
+	byKey map[string][]Interaction
+	next  map[string]int
+}
+
+// NewReplayer returns a Replayer serving interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	r := &Replayer{byKey: make(map[string][]Interaction), next: make(map[string]int)}
+	for _, in := range interactions {
+		body, _ := decodeBody(in.Request.Body, in.Request.BodyEncoding)
+		k := replayKey(in.Request.Method, in.Request.URL, body)
+		r.byKey[k] = append(r.byKey[k], in)
+	}
+	return r
+}
+
+// LoadReplayer reads the cassette at path and returns a Replayer for it.
+func LoadReplayer(path string) (*Replayer, error) {
+	ins, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(ins), nil
+}
+
+func replayKey(method, url string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.ToUpper(method) + " " + url + " " + hex.EncodeToString(sum[:])
+}
+
+// RoundTrip implements http.RoundTripper.  An unmatched request fails with
+// an error wrapping ErrNoRecordedResponse; a recorded transport error is
+// returned as an error again.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := snapshotBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	k := replayKey(req.Method, req.URL.String(), body)
+
+	r.mu.Lock()
+	ins := r.byKey[k]
+	if len(ins) == 0 {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s %s", ErrNoRecordedResponse, req.Method, req.URL)
+	}
+	i := r.next[k]
+	if i < len(ins)-1 {
+		r.next[k] = i + 1
+	}
+	in := ins[i]
+	r.mu.Unlock()
+
+	if in.Error != "" {
+		return nil, fmt.Errorf("client: replayed error: %s", in.Error)
+	}
+	respBody, err := decodeBody(in.Response.Body, in.Response.BodyEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("client: replay %s %s: %w", req.Method, req.URL, err)
+	}
+	proto := in.Response.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
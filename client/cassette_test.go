@@ -0,0 +1,231 @@
+package client_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// recordAgainst sends three requests through a recording client to a server
+// that numbers its responses, and returns the cassette path.
+func recordAgainst(t *testing.T, name string) (path, url string) {
+	t.Helper()
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Seq", fmt.Sprint(n.Add(1)))
+		switch r.URL.Path {
+		case "/bin":
+			w.Write([]byte{0xff, 0x00, 0xfe})
+		default:
+			fmt.Fprintf(w, "%s %s", r.Method, body)
+		}
+	}))
+	defer srv.Close()
+
+	path = filepath.Join(t.TempDir(), name)
+	rec, err := client.NewRecorder(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := client.DefaultTransportOptions()
+	opts.Recorder, opts.RecordTag = rec, "7"
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []struct{ method, path, body string }{
+		{"GET", "/a", ""},
+		{"POST", "/a", "x=1"},
+		{"GET", "/a", ""},
+		{"GET", "/bin", ""},
+	} {
+		r, _ := http.NewRequest(req.method, srv.URL+req.path, strings.NewReader(req.body))
+		resp, err := c.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Len() != 4 {
+		t.Fatalf("Len: got %d, want 4", rec.Len())
+	}
+	return path, srv.URL
+}
+
+func TestRecorder_RoundTripsBothFormats(t *testing.T) {
+	for _, name := range []string{"tape.jsonl", "tape.har"} {
+		t.Run(name, func(t *testing.T) {
+			path, url := recordAgainst(t, name)
+			ins, err := client.LoadCassette(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ins) != 4 {
+				t.Fatalf("got %d interactions, want 4", len(ins))
+			}
+			post := ins[1]
+			if post.Tag != "7" || post.Request.Method != "POST" || post.Request.URL != url+"/a" || post.Request.Body != "x=1" {
+				t.Errorf("request: %+v", post.Request)
+			}
+			if post.Response.Status != 200 || post.Response.Body != "POST x=1" || post.Response.Header.Get("X-Seq") != "2" {
+				t.Errorf("response: %+v", post.Response)
+			}
+			if ins[3].Response.BodyEncoding != "base64" {
+				t.Errorf("binary body should be base64-encoded, got %q", ins[3].Response.BodyEncoding)
+			}
+		})
+	}
+}
+
+func TestReplayer_MatchesMethodURLAndBody(t *testing.T) {
+	path, url := recordAgainst(t, "tape.jsonl")
+	rp, err := client.LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := client.DefaultTransportOptions()
+	opts.Replay = rp
+	c, err := client.NewHTTPClientWithOptions("", time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, body string) (*http.Response, []byte, error) {
+		r, _ := http.NewRequest(method, url+path, strings.NewReader(body))
+		resp, err := c.Do(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, b, nil
+	}
+
+	// The two recorded GETs are served in order, then the last repeats.
+	for i, want := range []string{"1", "3", "3"} {
+		resp, body, err := do("GET", "/a", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("X-Seq"); got != want || string(body) != "GET " {
+			t.Errorf("GET %d: got seq %s body %q, want seq %s", i, got, body, want)
+		}
+	}
+	resp, body, err := do("POST", "/a", "x=1")
+	if err != nil || resp.Header.Get("X-Seq") != "2" || string(body) != "POST x=1" {
+		t.Errorf("POST: got %v %q", err, body)
+	}
+	_, body, err = do("GET", "/bin", "")
+	if err != nil || !bytes.Equal(body, []byte{0xff, 0x00, 0xfe}) {
+		t.Errorf("binary body: got %v %x", err, body)
+	}
+	if _, _, err := do("POST", "/a", "x=2"); !errors.Is(err, client.ErrNoRecordedResponse) {
+		t.Errorf("different body: got %v, want ErrNoRecordedResponse", err)
+	}
+}
+
+func TestRecordingTransport_StreamedBodyRecordedOnClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: one\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "stream.jsonl")
+	rec, err := client.NewRecorder(path, client.CassetteJSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: client.NewRecordingTransport(http.DefaultTransport, rec, "")}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 11)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Len() != 0 {
+		t.Error("interaction should not be recorded before the body is done")
+	}
+	resp.Body.Close()
+	rec.Close()
+
+	ins, err := client.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ins) != 1 || ins[0].Response.Body != "data: one\n\n" {
+		t.Errorf("got %+v", ins)
+	}
+}
+
+func TestRecorder_CapsRecordedBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("x", 100))
+	}))
+	defer srv.Close()
+
+	for _, name := range []string{"capped.jsonl", "capped.har"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			rec, err := client.NewRecorderWithOptions(path, client.RecorderOptions{MaxBodyBytes: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &http.Client{Transport: client.NewRecordingTransport(http.DefaultTransport, rec, "")}
+			for range 2 {
+				_, body, err := get(c, srv.URL)
+				if err != nil || len(body) != 100 {
+					t.Fatalf("caller got %d bytes, %v; want the whole body", len(body), err)
+				}
+			}
+			if err := rec.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			ins, err := client.LoadCassette(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ins) != 2 {
+				t.Fatalf("got %d interactions, want 2", len(ins))
+			}
+			for i, in := range ins {
+				if in.Response.Body != strings.Repeat("x", 10) || in.Response.BodySize != 100 {
+					t.Errorf("interaction %d: kept %q of %d bytes, want 10 of 100", i, in.Response.Body, in.Response.BodySize)
+				}
+			}
+		})
+	}
+}
+
+func TestRecorder_EmptyHARIsValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.har")
+	rec, err := client.NewRecorder(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ins, err := client.LoadCassette(path); err != nil || len(ins) != 0 {
+		t.Errorf("got %d interactions, %v; want none", len(ins), err)
+	}
+}
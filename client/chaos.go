@@ -0,0 +1,386 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultFaultTimeout is how long a timeout fault hangs when its rule sets
+// no Delay and the request context has no earlier deadline.
+const DefaultFaultTimeout = 30 * time.Second
+
+// ErrUnknownFaultRule is returned by Chaos.SetRuleEnabled for a rule name
+// that does not exist.
+var ErrUnknownFaultRule = errors.New("client: unknown fault rule")
+
+// FaultKind selects the failure a FaultRule injects.
+type FaultKind string
+
+const (
+	// FaultReset fails the request with a connection reset (ECONNRESET)
+	// without contacting the server.
+	FaultReset FaultKind = "reset"
+
+	// FaultTimeout hangs for the rule's Delay, or until the request
+	// context ends, and then fails with a timeout error.
+	FaultTimeout FaultKind = "timeout"
+
+	// FaultDelay waits for the rule's Delay before sending the request.
+	FaultDelay FaultKind = "delay"
+
+	// FaultStatus answers with the rule's Status (default 503) without
+	// contacting the server.
+	FaultStatus FaultKind = "status"
+
+	// FaultTruncate sends the request and cuts the response body off with
+	// io.ErrUnexpectedEOF after the rule's TruncateAfter bytes.
+	FaultTruncate FaultKind = "truncate"
+)
+
+// ParseFaultKind validates s.
+func ParseFaultKind(s string) (FaultKind, error) {
+	switch k := FaultKind(strings.ToLower(s)); k {
+	case FaultReset, FaultTimeout, FaultDelay, FaultStatus, FaultTruncate:
+		return k, nil
+	}
+	return "", fmt.Errorf("client: unknown fault %q (want reset, timeout, delay, status or truncate)", s)
+}
+
+// FaultRule describes one injected failure.
+type FaultRule struct {
+	// Name identifies the rule for toggling and reporting.  It must be
+	// unique within a Chaos.
+	Name string
+
+	// URL is a pattern matched against the full request URL, in which *
+	// matches any run of characters, e.g. "https://api.example.com/v1/*".
+	// Empty matches every request.
+	URL string
+
+	// Fault is the failure injected.
+	Fault FaultKind
+
+	// Probability, if non-nil, is the chance in [0, 1] that a matching
+	// request is hit; zero never hits.  Nil means always.  Ignored when
+	// Every is set.
+	Probability *float64
+
+	// Every, when positive, hits every Every-th matching request instead
+	// of choosing at random, for reproducible tests.
+	Every int
+
+	// Delay is the wait of delay faults and the hang of timeout faults.
+	Delay time.Duration
+
+	// Status is the response code of status faults.  Zero means 503.
+	Status int
+
+	// TruncateAfter is the number of body bytes delivered by truncate
+	// faults.
+	TruncateAfter int
+
+	// Disabled starts the rule switched off; see Chaos.SetRuleEnabled.
+	Disabled bool
+}
+
+// FaultError is returned for requests failed by a reset or timeout fault.
+// It unwraps to the simulated network error (syscall.ECONNRESET or
+// os.ErrDeadlineExceeded), so the engine handles it like a real failure.
+type FaultError struct {
+	Rule  string
+	Fault FaultKind
+	Err   error
+}
+
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("client: injected %s fault (rule %q): %v", e.Fault, e.Rule, e.Err)
+}
+
+func (e *FaultError) Unwrap() error { return e.Err }
+
+// Timeout reports whether e simulates a timeout, so FaultError satisfies
+// net.Error like the errors it imitates.
+func (e *FaultError) Timeout() bool { return e.Fault == FaultTimeout }
+
+// Temporary is part of net.Error.
+func (e *FaultError) Temporary() bool { return false }
+
+var _ net.Error = (*FaultError)(nil)
+
+// faultRule is a FaultRule with its compiled pattern and live state.
+type faultRule struct {
+	FaultRule
+	pattern  *regexp.Regexp // nil matches everything
+	enabled  atomic.Bool
+	matched  atomic.Uint64
+	injected atomic.Uint64
+}
+
+// Chaos injects faults into requests according to its rules.  It is safe
+// for concurrent use and is usually shared by every session; rules and the
+// whole Chaos can be switched on and off while requests are in flight.
+// Install it with TransportOptions.Chaos or NewChaosTransport.
+type Chaos struct {
+	rules   []*faultRule
+	enabled atomic.Bool
+}
+
+// NewChaos validates rules and returns an enabled Chaos that applies them
+// in order: the first matching rule that hits decides the request's fate.
+func NewChaos(rules []FaultRule) (*Chaos, error) {
+	c := &Chaos{}
+	names := make(map[string]struct{}, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("client: fault rule %d: name is required", i)
+		}
+		if _, dup := names[r.Name]; dup {
+			return nil, fmt.Errorf("client: fault rule %q: duplicate name", r.Name)
+		}
+		names[r.Name] = struct{}{}
+		kind, err := ParseFaultKind(string(r.Fault))
+		if err != nil {
+			return nil, fmt.Errorf("client: fault rule %q: %w", r.Name, err)
+		}
+		r.Fault = kind
+		if p := r.Probability; p != nil && (*p < 0 || *p > 1) {
+			return nil, fmt.Errorf("client: fault rule %q: probability must be between 0 and 1 (got %g)", r.Name, *p)
+		}
+		fr := &faultRule{FaultRule: r}
+		if r.URL != "" {
+			fr.pattern = compileURLPattern(r.URL)
+		}
+		fr.enabled.Store(!r.Disabled)
+		c.rules = append(c.rules, fr)
+	}
+	c.enabled.Store(true)
+	return c, nil
+}
+
+// compileURLPattern turns a pattern in which * matches any run of
+// characters into an anchored regular expression.
+func compileURLPattern(p string) *regexp.Regexp {
+	parts := strings.Split(p, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// Enabled reports whether c injects any faults.
+func (c *Chaos) Enabled() bool { return c.enabled.Load() }
+
+// SetEnabled switches all fault injection on or off.  Rules keep their own
+// state.
+func (c *Chaos) SetEnabled(on bool) { c.enabled.Store(on) }
+
+// SetRuleEnabled switches one rule on or off.
+func (c *Chaos) SetRuleEnabled(name string, on bool) error {
+	for _, r := range c.rules {
+		if r.Name == name {
+			r.enabled.Store(on)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnknownFaultRule, name)
+}
+
+// FaultRuleStatus reports one rule's configuration and activity.
+type FaultRuleStatus struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url,omitempty"`
+	Fault       FaultKind `json:"fault"`
+	Probability *float64  `json:"probability,omitempty"`
+	Every       int       `json:"every,omitempty"`
+	Enabled     bool      `json:"enabled"`
+
+	// Matched counts requests that matched the rule's URL while it was
+	// enabled; Injected counts those that were hit.
+	Matched  uint64 `json:"matched"`
+	Injected uint64 `json:"injected"`
+}
+
+// Rules returns the status of every rule, in evaluation order.
+func (c *Chaos) Rules() []FaultRuleStatus {
+	out := make([]FaultRuleStatus, len(c.rules))
+	for i, r := range c.rules {
+		out[i] = FaultRuleStatus{
+			Name:        r.Name,
+			URL:         r.URL,
+			Fault:       r.Fault,
+			Probability: r.Probability,
+			Every:       r.Every,
+			Enabled:     r.enabled.Load(),
+			Matched:     r.matched.Load(),
+			Injected:    r.injected.Load(),
+		}
+	}
+	return out
+}
+
+// pick returns the rule that hits req, or nil.
+func (c *Chaos) pick(req *http.Request) *faultRule {
+	if !c.enabled.Load() {
+		return nil
+	}
+	u := req.URL.String()
+	for _, r := range c.rules {
+		if !r.enabled.Load() || (r.pattern != nil && !r.pattern.MatchString(u)) {
+			continue
+		}
+		n := r.matched.Add(1)
+		var hit bool
+		switch {
+		case r.Every > 0:
+			hit = n%uint64(r.Every) == 0
+		case r.Probability == nil:
+			hit = true
+		default:
+			hit = rand.Float64() < *r.Probability
+		}
+		if hit {
+			r.injected.Add(1)
+			return r
+		}
+	}
+	return nil
+}
+
+// NewChaosTransport returns a RoundTripper that injects c's faults into
+// requests sent through base.
+func NewChaosTransport(base http.RoundTripper, c *Chaos) http.RoundTripper {
+	return &chaosTransport{base: base, chaos: c}
+}
+
+type chaosTransport struct {
+	base  http.RoundTripper
+	chaos *Chaos
+}
+
+// CloseIdleConnections forwards to the wrapped transport.
+func (t *chaosTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// Unwrap returns the wrapped transport.
+func (t *chaosTransport) Unwrap() http.RoundTripper { return t.base }
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := t.chaos.pick(req)
+	if r == nil {
+		return t.base.RoundTrip(req)
+	}
+	switch r.Fault {
+	case FaultReset:
+		closeBody(req)
+		return nil, &FaultError{Rule: r.Name, Fault: r.Fault, Err: &net.OpError{
+			Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET),
+		}}
+	case FaultTimeout:
+		closeBody(req)
+		d := r.Delay
+		if d <= 0 {
+			d = DefaultFaultTimeout
+		}
+		if err := sleepCtx(req.Context(), d); err != nil {
+			return nil, err
+		}
+		return nil, &FaultError{Rule: r.Name, Fault: r.Fault, Err: os.ErrDeadlineExceeded}
+	case FaultDelay:
+		if err := sleepCtx(req.Context(), r.Delay); err != nil {
+			closeBody(req)
+			return nil, err
+		}
+		return t.base.RoundTrip(req)
+	case FaultStatus:
+		closeBody(req)
+		return faultResponse(req, r), nil
+	case FaultTruncate:
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &truncatedBody{ReadCloser: resp.Body, left: r.TruncateAfter}
+		return resp, nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+// faultResponse builds the synthetic response of a status fault.
+func faultResponse(req *http.Request, r *faultRule) *http.Response {
+	status := r.Status
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	body := fmt.Sprintf("injected fault %q\n", r.Name)
+	h := make(http.Header)
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("X-Chaos-Rule", r.Name)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// closeBody releases the body of a request that will not be sent, as a
+// RoundTripper must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// sleepCtx waits for d or until ctx ends, returning ctx's error then.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// truncatedBody delivers left bytes of a body and then fails with
+// io.ErrUnexpectedEOF, as when a connection drops mid-response.  A body
+// shorter than the cut ends normally.
+type truncatedBody struct {
+	io.ReadCloser
+	left int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= n
+	return n, err
+}
@@ -0,0 +1,169 @@
+package client_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// chaosClient returns a client that injects rules into requests to a test
+// server, and a counter of the requests that reached the server.
+func chaosClient(t *testing.T, rules ...client.FaultRule) (*http.Client, *client.Chaos, string, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	t.Cleanup(srv.Close)
+
+	ch, err := client.NewChaos(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := client.DefaultTransportOptions()
+	opts.Chaos = ch
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ch, srv.URL, &hits
+}
+
+func get(c *http.Client, url string) (int, []byte, error) {
+	resp, err := c.Get(url)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+func TestChaos_Reset(t *testing.T) {
+	c, _, url, hits := chaosClient(t, client.FaultRule{Name: "rst", Fault: client.FaultReset})
+	_, _, err := get(c, url)
+	var fe *client.FaultError
+	if !errors.Is(err, syscall.ECONNRESET) || !errors.As(err, &fe) || fe.Rule != "rst" {
+		t.Errorf("got %v, want an injected ECONNRESET", err)
+	}
+	if hits.Load() != 0 {
+		t.Error("a reset request should not reach the server")
+	}
+}
+
+func TestChaos_Timeout(t *testing.T) {
+	c, _, url, _ := chaosClient(t, client.FaultRule{Name: "hang", Fault: client.FaultTimeout, Delay: 20 * time.Millisecond})
+	_, _, err := get(c, url)
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("got %v, want a timeout", err)
+	}
+}
+
+func TestChaos_StatusEveryNth(t *testing.T) {
+	c, ch, url, hits := chaosClient(t, client.FaultRule{Name: "503", Fault: client.FaultStatus, Every: 2})
+	var got []int
+	for range 4 {
+		status, _, err := get(c, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, status)
+	}
+	if want := []int{200, 503, 200, 503}; !slices.Equal(got, want) {
+		t.Errorf("statuses: got %v, want %v", got, want)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("server saw %d requests, want 2", hits.Load())
+	}
+	if r := ch.Rules()[0]; r.Matched != 4 || r.Injected != 2 {
+		t.Errorf("rule status: %+v", r)
+	}
+}
+
+func TestChaos_TruncateAndDelay(t *testing.T) {
+	c, _, url, _ := chaosClient(t,
+		client.FaultRule{Name: "cut", URL: "*/cut", Fault: client.FaultTruncate, TruncateAfter: 10},
+		client.FaultRule{Name: "slow", URL: "*/slow*", Fault: client.FaultDelay, Delay: 50 * time.Millisecond},
+	)
+	_, body, err := get(c, url+"/cut")
+	if !errors.Is(err, io.ErrUnexpectedEOF) || len(body) != 10 {
+		t.Errorf("truncate: got %d bytes, %v", len(body), err)
+	}
+	start := time.Now()
+	if _, body, err := get(c, url+"/slow?x=1"); err != nil || len(body) != 100 {
+		t.Errorf("delay: got %d bytes, %v", len(body), err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("delay: request took %s, want at least 50ms", elapsed)
+	}
+	if _, body, err := get(c, url+"/other"); err != nil || len(body) != 100 {
+		t.Errorf("unmatched URL: got %d bytes, %v", len(body), err)
+	}
+}
+
+func TestChaos_Toggle(t *testing.T) {
+	c, ch, url, _ := chaosClient(t, client.FaultRule{Name: "503", Fault: client.FaultStatus, Status: 502})
+	status := func() int {
+		s, _, err := get(c, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	if s := status(); s != 502 {
+		t.Errorf("enabled: got %d, want 502", s)
+	}
+	ch.SetEnabled(false)
+	if s := status(); s != 200 {
+		t.Errorf("chaos disabled: got %d, want 200", s)
+	}
+	ch.SetEnabled(true)
+	if err := ch.SetRuleEnabled("503", false); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); s != 200 {
+		t.Errorf("rule disabled: got %d, want 200", s)
+	}
+	if err := ch.SetRuleEnabled("nope", true); !errors.Is(err, client.ErrUnknownFaultRule) {
+		t.Errorf("unknown rule: got %v", err)
+	}
+}
+
+func TestChaos_ZeroProbabilityNeverHits(t *testing.T) {
+	zero := 0.0
+	c, ch, url, hits := chaosClient(t, client.FaultRule{Name: "off", Fault: client.FaultReset, Probability: &zero})
+	for range 5 {
+		if _, _, err := get(c, url); err != nil {
+			t.Fatalf("probability 0 injected a fault: %v", err)
+		}
+	}
+	if st := ch.Rules()[0]; hits.Load() != 5 || st.Matched != 5 || st.Injected != 0 {
+		t.Errorf("got %d server hits and %+v, want 5 and no injections", hits.Load(), st)
+	}
+}
+
+func TestNewChaos_RejectsInvalidRules(t *testing.T) {
+	over := 1.5
+	for name, rules := range map[string][]client.FaultRule{
+		"no name":     {{Fault: client.FaultReset}},
+		"duplicate":   {{Name: "a", Fault: client.FaultReset}, {Name: "a", Fault: client.FaultDelay}},
+		"bad fault":   {{Name: "a", Fault: "explode"}},
+		"probability": {{Name: "a", Fault: client.FaultReset, Probability: &over}},
+	} {
+		if _, err := client.NewChaos(rules); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -3,7 +3,9 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -12,24 +14,6 @@ import (
 	utls "github.com/refraction-networking/utls"
 )
 
-// transportDefaults groups transport-layer knobs that are set once at
-// construction time. Exposing them as a struct makes unit-testing easier and
-// keeps NewHTTPClient's signature small.
-type transportDefaults struct {
-	maxIdleConns        int
-	maxIdleConnsPerHost int
-	maxConnsPerHost     int
-}
-
-// defaultTransport holds the tuning values used when callers do not supply
-// an explicit Config. These numbers are sized for ~500 concurrent sessions
-// hitting a single origin.
-var defaultTransport = transportDefaults{
-	maxIdleConns:        500,
-	maxIdleConnsPerHost: 100,
-	maxConnsPerHost:     200,
-}
-
 // NewHTTPClientWithTLS constructs a *http.Client whose every TLS connection
 // uses the uTLS library to impersonate the browser fingerprint described by
 // helloID, bypassing JA3/JA4 fingerprint detection.
@@ -41,7 +25,14 @@ var defaultTransport = transportDefaults{
 //
 // Use NewHTTPClient when TLS fingerprint bypass is not required.
 func NewHTTPClientWithTLS(proxyStr string, timeout time.Duration, helloID utls.ClientHelloID) (*http.Client, error) {
-	transport, err := buildTransportWithTLS(proxyStr, helloID)
+	return NewHTTPClientWithTLSOptions(proxyStr, timeout, helloID, DefaultTransportOptions())
+}
+
+// NewHTTPClientWithTLSOptions is NewHTTPClientWithTLS with explicit
+// transport tuning.  opts.DialTimeout, opts.KeepAlive and
+// opts.TLSHandshakeTimeout are honoured by the uTLS dial path.
+func NewHTTPClientWithTLSOptions(proxyStr string, timeout time.Duration, helloID utls.ClientHelloID, opts TransportOptions) (*http.Client, error) {
+	transport, err := buildTransportWithTLS(proxyStr, helloID, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -52,34 +43,35 @@ func NewHTTPClientWithTLS(proxyStr string, timeout time.Duration, helloID utls.C
 	}
 
 	return &http.Client{
-		Transport: transport,
-		Jar:       jar,
-		Timeout:   timeout,
+		Transport:     opts.wrap(transport),
+		Jar:           jar,
+		Timeout:       timeout,
+		CheckRedirect: CheckRedirect,
 	}, nil
 }
 
 // buildTransportWithTLS creates an *http.Transport with the uTLS dialer wired
 // in for JA3/JA4 bypass.
-func buildTransportWithTLS(proxyStr string, helloID utls.ClientHelloID) (*http.Transport, error) {
-	t := &http.Transport{
-		DisableKeepAlives:     false,
-		MaxIdleConns:          defaultTransport.maxIdleConns,
-		MaxIdleConnsPerHost:   defaultTransport.maxIdleConnsPerHost,
-		MaxConnsPerHost:       defaultTransport.maxConnsPerHost,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+//
+// http.Transport cannot run HTTP/2 over a uTLS connection, so only
+// ProtocolAuto and ProtocolHTTP1 are accepted; use NewChrome120H2Transport
+// for HTTP/2 with a browser fingerprint.
+func buildTransportWithTLS(proxyStr string, helloID utls.ClientHelloID, opts TransportOptions) (*http.Transport, error) {
+	switch p, err := ParseProtocol(string(opts.Protocol)); {
+	case err != nil:
+		return nil, err
+	case p != ProtocolAuto && p != ProtocolHTTP1:
+		return nil, fmt.Errorf("client: protocol %q is not supported with uTLS; use NewChrome120H2Transport for HTTP/2", p)
 	}
-
-	if proxyStr != "" {
-		proxyURL, err := url.Parse(proxyStr)
-		if err != nil {
-			return nil, fmt.Errorf("client: parse proxy URL %q: %w", proxyStr, err)
-		}
-		t.Proxy = http.ProxyURL(proxyURL)
+	t, err := buildTransport(proxyStr, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	t.DialTLSContext = UTLSDialerHTTP1(helloID)
+	inner := utlsDialer(helloID, opts)
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return inner(ctx, network, addr, nil)
+	}
 	return t, nil
 }
 
@@ -116,10 +108,19 @@ func buildTransportWithTLS(proxyStr string, helloID utls.ClientHelloID) (*http.T
 // Parameters:
 //   - proxy:   optional proxy URL string, e.g. "http://host:port". Empty means direct.
 //   - timeout: end-to-end request timeout passed to http.Client.Timeout.
+//
+// The transport uses DefaultTransportOptions; use NewHTTPClientWithOptions to
+// tune it.
 func NewHTTPClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	return NewHTTPClientWithOptions(proxy, timeout, DefaultTransportOptions())
+}
+
+// NewHTTPClientWithOptions is NewHTTPClient with explicit transport tuning
+// (pool sizes, timeouts, keep-alive and HTTP/2 enablement).
+func NewHTTPClientWithOptions(proxy string, timeout time.Duration, opts TransportOptions) (*http.Client, error) {
 	// Build the transport first; any error here (invalid proxy URL) prevents
 	// constructing an unusable client.
-	transport, err := buildTransport(proxy)
+	transport, err := buildTransport(proxy, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -131,32 +132,66 @@ func NewHTTPClient(proxy string, timeout time.Duration) (*http.Client, error) {
 		return nil, fmt.Errorf("client: create cookie jar: %w", err)
 	}
 
+	// The HTTP/3 modes layer QUIC over the TCP transport, which remains the
+	// fallback.
+	var rt http.RoundTripper = transport
+	if p := opts.Protocol; p.IsHTTP3() {
+		if rt, err = newHTTP3Transport(transport, proxy, opts, p == ProtocolHTTP3AltSvc); err != nil {
+			return nil, err
+		}
+	}
+
 	return &http.Client{
-		Transport: transport,
+		Transport: opts.wrap(rt),
 		Jar:       jar,
 		Timeout:   timeout,
-		// CheckRedirect is intentionally left nil so the client follows
-		// redirects automatically (up to the default limit of 10).
+		// CheckRedirect follows up to 10 redirects, like net/http, unless
+		// the request context carries a RedirectPolicy.
+		CheckRedirect: CheckRedirect,
 	}, nil
 }
 
-// buildTransport creates an *http.Transport with carefully tuned defaults.
+// buildTransport creates an *http.Transport tuned by opts.
 // If proxy is non-empty it is parsed and attached to the transport.
-func buildTransport(proxy string) (*http.Transport, error) {
+func buildTransport(proxy string, opts TransportOptions) (*http.Transport, error) {
+	protocols, err := opts.httpProtocols()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Dial != "" {
+		if _, _, err := ParseDialAddr(opts.Dial); err != nil {
+			return nil, err
+		}
+	}
+
 	t := &http.Transport{
 		// Keep-alives are on by default; making this explicit documents intent.
 		DisableKeepAlives: false,
 
 		// Pool sizing – see module-level comment for rationale.
-		MaxIdleConns:        defaultTransport.maxIdleConns,
-		MaxIdleConnsPerHost: defaultTransport.maxIdleConnsPerHost,
-		MaxConnsPerHost:     defaultTransport.maxConnsPerHost,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+
+		// Evict idle connections so we do not hold dead sockets.
+		IdleConnTimeout: opts.IdleConnTimeout,
+
+		// TLS handshakes that stall are aborted.
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+
+		// Dial timeout and TCP keep-alive come from opts.
+		DialContext: opts.dialContext(),
+
+		// Setting DialContext disables net/http's implicit HTTP/2 support,
+		// so it has to be requested explicitly.
+		ForceAttemptHTTP2: opts.EnableHTTP2,
 
-		// Evict idle connections after 90 s so we do not hold dead sockets.
-		IdleConnTimeout: 90 * time.Second,
+		// An explicit Protocol (h1, h2, h2c) takes precedence over
+		// ForceAttemptHTTP2; nil keeps the auto behaviour.
+		Protocols: protocols,
 
-		// TLS handshakes that stall for more than 10 s are aborted.
-		TLSHandshakeTimeout: 10 * time.Second,
+		// Custom CAs and client certificates; nil keeps net/http's defaults.
+		TLSClientConfig: opts.TLS.stdConfig(),
 
 		// ExpectContinueTimeout limits the time to wait for a server's
 		// first response headers after sending the request headers when
@@ -167,7 +202,8 @@ func buildTransport(proxy string) (*http.Transport, error) {
 		// gzip from the server and decompress transparently, saving bandwidth.
 	}
 
-	if proxy != "" {
+	// A fixed dial address (e.g. a local Unix socket) bypasses the proxy.
+	if proxy != "" && opts.Dial == "" {
 		proxyURL, err := url.Parse(proxy)
 		if err != nil {
 			return nil, fmt.Errorf("client: parse proxy URL %q: %w", proxy, err)
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultMaxDecodedBodySize is the decoded-size cap applied when
+// TransportOptions.MaxDecodedBodySize is zero.  It is large enough for any
+// realistic HTML/JSON page while stopping a hostile or broken server from
+// expanding a few kilobytes of compressed data into gigabytes of heap.
+const DefaultMaxDecodedBodySize = 64 << 20 // 64 MiB
+
+// ErrDecodedBodyTooLarge is returned from a response body's Read method once
+// the decoded stream exceeds the configured limit (a likely decompression
+// bomb).  The bytes read up to the limit are still delivered.
+var ErrDecodedBodyTooLarge = errors.New("client: decoded response body exceeds size limit")
+
+// decompressingTransport decodes gzip, deflate, br and zstd response bodies
+// according to Content-Encoding.
+//
+// net/http only decompresses transparently when it added Accept-Encoding
+// itself.  The Chrome/Firefox header profiles set "accept-encoding: gzip,
+// deflate, br" explicitly – which is required for fingerprint fidelity – so
+// without this layer jobs would receive raw compressed bytes.
+//
+// Decoding is lazy: decoders are created on the first Read, so RoundTrip
+// still returns as soon as the response headers arrive.
+type decompressingTransport struct {
+	base     http.RoundTripper
+	maxBytes int64 // < 0 disables the limit
+}
+
+// NewDecompressingTransport wraps base so that gzip, deflate, br and zstd
+// response bodies are decoded transparently.  maxBytes caps the decoded size
+// of each body: zero selects DefaultMaxDecodedBodySize and a negative value
+// disables the limit.
+//
+// Every client built by this package already includes this layer unless
+// TransportOptions.DisableDecompression is set; use this function when
+// composing a custom transport.
+func NewDecompressingTransport(base http.RoundTripper, maxBytes int64) http.RoundTripper {
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxDecodedBodySize
+	}
+	return &decompressingTransport{base: base, maxBytes: maxBytes}
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t *decompressingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Uncompressed || req.Method == http.MethodHead ||
+		resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	encodings, ok := parseContentEncoding(resp.Header.Get("Content-Encoding"))
+	if !ok || len(encodings) == 0 {
+		// Unknown encodings are passed through untouched rather than
+		// half-decoded.
+		return resp, nil
+	}
+
+	resp.Body = &decodedBody{src: resp.Body, encodings: encodings, maxBytes: t.maxBytes}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// CloseIdleConnections forwards to the wrapped transport so
+// http.Client.CloseIdleConnections keeps working.
+func (t *decompressingTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// Unwrap returns the wrapped transport.
+func (t *decompressingTransport) Unwrap() http.RoundTripper { return t.base }
+
+// parseContentEncoding splits a Content-Encoding header into lower-case
+// codings in the order they were applied, dropping "identity".  ok is false
+// if any coding is not supported.
+func parseContentEncoding(header string) (encodings []string, ok bool) {
+	for _, part := range strings.Split(header, ",") {
+		enc := strings.ToLower(strings.TrimSpace(part))
+		switch enc {
+		case "", "identity":
+		case "gzip", "x-gzip", "deflate", "br", "zstd":
+			encodings = append(encodings, enc)
+		default:
+			return nil, false
+		}
+	}
+	return encodings, true
+}
+
+// decodedBody lazily stacks decoders over src (last-applied coding first)
+// and enforces the decoded-size limit.
+type decodedBody struct {
+	src       io.ReadCloser
+	encodings []string
+	maxBytes  int64
+
+	r       io.Reader
+	closers []func()
+	err     error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.err = b.init()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+// init builds the decoder chain.  Codings are undone in reverse order of
+// application, as required by RFC 9110 §8.4.
+func (b *decodedBody) init() error {
+	var r io.Reader = b.src
+	for i := len(b.encodings) - 1; i >= 0; i-- {
+		enc := b.encodings[i]
+		switch enc {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				return fmt.Errorf("client: gzip response: %w", err)
+			}
+			b.closers = append(b.closers, func() { _ = zr.Close() })
+			r = zr
+		case "deflate":
+			// "deflate" is meant to be zlib-wrapped, but some servers send a
+			// raw DEFLATE stream.  Peek at the header to tell them apart.
+			br := bufio.NewReader(r)
+			if hdr, err := br.Peek(2); err == nil && isZlibHeader(hdr) {
+				zr, err := zlib.NewReader(br)
+				if err != nil {
+					return fmt.Errorf("client: deflate response: %w", err)
+				}
+				b.closers = append(b.closers, func() { _ = zr.Close() })
+				r = zr
+			} else {
+				fr := flate.NewReader(br)
+				b.closers = append(b.closers, func() { _ = fr.Close() })
+				r = fr
+			}
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return fmt.Errorf("client: zstd response: %w", err)
+			}
+			b.closers = append(b.closers, zr.Close)
+			r = zr
+		}
+	}
+	if b.maxBytes > 0 {
+		r = &maxBytesReader{r: r, n: b.maxBytes}
+	}
+	b.r = r
+	return nil
+}
+
+func (b *decodedBody) Close() error {
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		b.closers[i]()
+	}
+	b.closers = nil
+	return b.src.Close()
+}
+
+// isZlibHeader reports whether hdr is a valid RFC 1950 zlib header.
+func isZlibHeader(hdr []byte) bool {
+	return hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0
+}
+
+// maxBytesReader returns ErrDecodedBodyTooLarge once more than n bytes
+// would be produced.
+type maxBytesReader struct {
+	r io.Reader
+	n int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.n <= 0 {
+		// The limit is exactly exhausted: only report an error if the
+		// stream actually has more data.
+		var probe [1]byte
+		n, err := m.r.Read(probe[:])
+		if n > 0 {
+			return 0, ErrDecodedBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > m.n {
+		p = p[:m.n]
+	}
+	n, err := m.r.Read(p)
+	m.n -= int64(n)
+	return n, err
+}
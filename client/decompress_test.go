@@ -0,0 +1,185 @@
+package client_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+const decompressPayload = "hello, compressed world"
+
+func encode(t *testing.T, enc string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch enc {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	default:
+		t.Fatalf("unknown encoding %q", enc)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// serveEncoded starts a server that always answers with body and the given
+// Content-Encoding header.
+func serveEncoded(t *testing.T, header string, body []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", header)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getWithExplicitAcceptEncoding mirrors the browser header profiles, which
+// set Accept-Encoding themselves and so disable net/http's own gzip handling.
+func getWithExplicitAcceptEncoding(t *testing.T, c *http.Client, url string) (*http.Response, []byte, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+func TestDecompression_Encodings(t *testing.T) {
+	c, err := client.NewHTTPClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		header string
+		body   []byte
+	}{
+		{"gzip", "gzip", encode(t, "gzip", []byte(decompressPayload))},
+		{"deflate-zlib", "deflate", encode(t, "deflate", []byte(decompressPayload))},
+		{"deflate-raw", "deflate", encode(t, "raw-deflate", []byte(decompressPayload))},
+		{"br", "br", encode(t, "br", []byte(decompressPayload))},
+		{"zstd", "zstd", encode(t, "zstd", []byte(decompressPayload))},
+		{"stacked", "gzip, br", encode(t, "br", encode(t, "gzip", []byte(decompressPayload)))},
+		{"identity", "identity", []byte(decompressPayload)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := serveEncoded(t, tc.header, tc.body)
+			resp, body, err := getWithExplicitAcceptEncoding(t, c, srv.URL)
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+			if string(body) != decompressPayload {
+				t.Errorf("body: got %q, want %q", body, decompressPayload)
+			}
+			if ce := resp.Header.Get("Content-Encoding"); ce != "" && tc.header != "identity" {
+				t.Errorf("Content-Encoding should be removed after decoding, got %q", ce)
+			}
+		})
+	}
+}
+
+func TestDecompression_UnknownEncodingPassesThrough(t *testing.T) {
+	c, err := client.NewHTTPClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := serveEncoded(t, "compress", []byte("raw-bytes"))
+	resp, body, err := getWithExplicitAcceptEncoding(t, c, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "raw-bytes" || resp.Header.Get("Content-Encoding") != "compress" {
+		t.Errorf("unknown coding should pass through: body=%q ce=%q", body, resp.Header.Get("Content-Encoding"))
+	}
+}
+
+func TestDecompression_SizeLimit(t *testing.T) {
+	bomb := encode(t, "gzip", bytes.Repeat([]byte{'A'}, 1<<20))
+
+	opts := client.DefaultTransportOptions()
+	opts.MaxDecodedBodySize = 1024
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := serveEncoded(t, "gzip", bomb)
+	_, body, err := getWithExplicitAcceptEncoding(t, c, srv.URL)
+	if !errors.Is(err, client.ErrDecodedBodyTooLarge) {
+		t.Fatalf("err: got %v, want ErrDecodedBodyTooLarge", err)
+	}
+	if len(body) != 1024 {
+		t.Errorf("bytes delivered before the limit: got %d, want 1024", len(body))
+	}
+}
+
+func TestDecompression_ExactLimitIsAllowed(t *testing.T) {
+	opts := client.DefaultTransportOptions()
+	opts.MaxDecodedBodySize = int64(len(decompressPayload))
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := serveEncoded(t, "zstd", encode(t, "zstd", []byte(decompressPayload)))
+	_, body, err := getWithExplicitAcceptEncoding(t, c, srv.URL)
+	if err != nil {
+		t.Fatalf("body exactly at the limit should not fail: %v", err)
+	}
+	if string(body) != decompressPayload {
+		t.Errorf("body: got %q", body)
+	}
+}
+
+func TestDecompression_CorruptBody(t *testing.T) {
+	c, err := client.NewHTTPClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := serveEncoded(t, "gzip", []byte("definitely not gzip"))
+	_, _, err = getWithExplicitAcceptEncoding(t, c, srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "gzip") {
+		t.Errorf("corrupt gzip: got err %v", err)
+	}
+}
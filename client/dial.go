@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ParseDialAddr splits a fixed dial address (TransportOptions.Dial) into a
+// network and address for net.Dialer.  Accepted forms:
+//
+//	unix:///var/run/app.sock   Unix domain socket (also unix:/var/run/app.sock)
+//	tcp://10.0.0.7:8080        TCP, bypassing the URL host and DNS overrides
+func ParseDialAddr(s string) (network, address string, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", "", fmt.Errorf("client: parse dial address %q: %w", s, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Host != "" || u.Path == "" {
+			return "", "", fmt.Errorf("client: dial address %q: want unix:///absolute/path.sock", s)
+		}
+		return "unix", u.Path, nil
+	case "tcp":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil || u.Path != "" {
+			return "", "", fmt.Errorf("client: dial address %q: want tcp://host:port", s)
+		}
+		return "tcp", u.Host, nil
+	}
+	return "", "", fmt.Errorf("client: dial address %q: scheme must be unix or tcp", s)
+}
+
+// SplitUnixURL converts a unix:// target URL into the dial address of its
+// socket and the http:// URL to request over it.  The request path follows
+// the socket path after a colon, and the Host header is "localhost":
+//
+//	unix:///var/run/app.sock               -> http://localhost/
+//	unix:///var/run/app.sock:/v1/health?x  -> http://localhost/v1/health?x
+//
+// ok is false when raw does not use the unix scheme.
+func SplitUnixURL(raw string) (dial, httpURL string, ok bool, err error) {
+	if !strings.HasPrefix(raw, "unix:") {
+		return "", "", false, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host != "" || u.Path == "" {
+		return "", "", true, fmt.Errorf("client: unix URL %q: want unix:///path.sock[:/request/path]", raw)
+	}
+	socket, reqPath, _ := strings.Cut(u.Path, ":")
+	if reqPath == "" {
+		reqPath = "/"
+	}
+	r := url.URL{Scheme: "http", Host: "localhost", Path: reqPath, RawQuery: u.RawQuery}
+	return (&url.URL{Scheme: "unix", Path: socket}).String(), r.String(), true, nil
+}
+
+// fixedDial returns a dial function that ignores the requested address and
+// connects to network/address instead.  The transport still keys its pool,
+// Host header and TLS server name on the request URL.
+func fixedDial(dial dialFunc, network, address string) dialFunc {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dial(ctx, network, address)
+	}
+}
@@ -0,0 +1,120 @@
+package client_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// startUnixServer serves handler on a Unix socket in a temporary directory
+// and returns the socket path.
+func startUnixServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return sock
+}
+
+func TestDial_UnixSocket(t *testing.T) {
+	sock := startUnixServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+r.URL.Path)
+	}))
+
+	opts := client.DefaultTransportOptions()
+	opts.Dial = "unix://" + sock
+	// The proxy is bypassed for a fixed dial address.
+	c, err := client.NewHTTPClientWithOptions("http://127.0.0.1:1", 5*time.Second, opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions: %v", err)
+	}
+	resp, err := c.Get("http://localhost/v1/health")
+	if err != nil {
+		t.Fatalf("GET over unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "localhost/v1/health" {
+		t.Errorf("server saw %q, want localhost/v1/health", body)
+	}
+}
+
+func TestDial_FixedTCPAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host)
+	}))
+	defer srv.Close()
+
+	opts := client.DefaultTransportOptions()
+	opts.Dial = "tcp://" + srv.Listener.Addr().String()
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions: %v", err)
+	}
+	resp, err := c.Get("http://api.invalid/")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "api.invalid" {
+		t.Errorf("Host = %q, want api.invalid", body)
+	}
+}
+
+func TestParseDialAddr(t *testing.T) {
+	cases := []struct {
+		in, network, address string
+		ok                   bool
+	}{
+		{"unix:///var/run/app.sock", "unix", "/var/run/app.sock", true},
+		{"unix:/var/run/app.sock", "unix", "/var/run/app.sock", true},
+		{"tcp://10.0.0.7:8080", "tcp", "10.0.0.7:8080", true},
+		{"unix://host/app.sock", "", "", false},
+		{"tcp://10.0.0.7", "", "", false},
+		{"udp://10.0.0.7:53", "", "", false},
+	}
+	for _, c := range cases {
+		network, address, err := client.ParseDialAddr(c.in)
+		if (err == nil) != c.ok || network != c.network || address != c.address {
+			t.Errorf("ParseDialAddr(%q) = %q, %q, %v", c.in, network, address, err)
+		}
+	}
+
+	opts := client.DefaultTransportOptions()
+	opts.Dial = "udp://10.0.0.7:53"
+	if _, err := client.NewHTTPClientWithOptions("", time.Second, opts); err == nil {
+		t.Error("expected an error for an invalid dial address")
+	}
+}
+
+func TestSplitUnixURL(t *testing.T) {
+	cases := []struct{ in, dial, url string }{
+		{"unix:///var/run/app.sock", "unix:///var/run/app.sock", "http://localhost/"},
+		{"unix:///var/run/app.sock:/v1/health?x=1", "unix:///var/run/app.sock", "http://localhost/v1/health?x=1"},
+	}
+	for _, c := range cases {
+		dial, u, ok, err := client.SplitUnixURL(c.in)
+		if !ok || err != nil || dial != c.dial || u != c.url {
+			t.Errorf("SplitUnixURL(%q) = %q, %q, %v, %v", c.in, dial, u, ok, err)
+		}
+	}
+	if _, _, ok, _ := client.SplitUnixURL("https://example.com/"); ok {
+		t.Error("https URL reported as unix")
+	}
+	if _, _, ok, err := client.SplitUnixURL("unix://host/app.sock"); !ok || err == nil {
+		t.Error("expected an error for a unix URL with a host")
+	}
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// NewGRPCConn returns a gRPC client connection to addr (host:port) built from
+// the same settings as the HTTP transports: opts supplies the dial timeout,
+// keep-alive, resolver, fixed dial address and TLS material, and a non-empty
+// proxy is traversed with HTTP CONNECT.  secure selects TLS; otherwise the
+// connection is plaintext HTTP/2.
+//
+// The connection is established lazily on the first call.
+func NewGRPCConn(addr string, secure bool, proxy string, opts TransportOptions) (*grpc.ClientConn, error) {
+	dial := opts.dialContext()
+	if proxy != "" && opts.Dial == "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("client: parse proxy URL %q: %w", proxy, err)
+		}
+		dial = connectDial(dial, proxyURL)
+	}
+
+	creds := insecure.NewCredentials()
+	if secure {
+		cfg := opts.TLS.stdConfig()
+		if cfg == nil {
+			cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+
+	conn, err := grpc.NewClient("passthrough:///"+addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, "tcp", addr)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("client: grpc client for %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// connectDial returns a dial function that tunnels through an HTTP proxy
+// with a CONNECT request, sending Basic credentials from the proxy URL.
+func connectDial(dial dialFunc, proxyURL *url.URL) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		proxyAddr := proxyURL.Host
+		if proxyURL.Port() == "" {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+		conn, err := dial(ctx, network, proxyAddr)
+		if err != nil {
+			return nil, err
+		}
+		req := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if u := proxyURL.User; u != nil {
+			pass, _ := u.Password()
+			req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.Username()+":"+pass)))
+		}
+		if d, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(d)
+			defer conn.SetDeadline(time.Time{})
+		}
+		if err := req.Write(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("client: proxy CONNECT %s: %w", addr, err)
+		}
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, req)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("client: proxy CONNECT %s: %w", addr, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("client: proxy CONNECT %s: %s", addr, resp.Status)
+		}
+		if br.Buffered() > 0 {
+			conn.Close()
+			return nil, fmt.Errorf("client: proxy CONNECT %s: unexpected data after response", addr)
+		}
+		return conn, nil
+	}
+}
@@ -57,7 +57,7 @@ type H2TransportConfig struct {
 	HelloID utls.ClientHelloID
 
 	// IdleConnTimeout is the maximum time an idle HTTP/2 connection is kept
-	// alive.  Defaults to 90 s.
+	// alive.  Defaults to Transport.IdleConnTimeout.
 	IdleConnTimeout time.Duration
 
 	// PingTimeout is the time after which a ping-based health-check fails.
@@ -66,6 +66,12 @@ type H2TransportConfig struct {
 
 	// ReadIdleTimeout enables periodic ping health-checks when > 0.
 	ReadIdleTimeout time.Duration
+
+	// Transport supplies the dial timeout, TCP keep-alive and TLS handshake
+	// timeout used by the uTLS dialer.  Defaults to DefaultTransportOptions
+	// when zero.  Pool-size fields do not apply: HTTP/2 multiplexes streams
+	// over a single connection per host.
+	Transport TransportOptions
 }
 
 // NewChrome120H2Transport returns an http.RoundTripper that mimics a Windows
@@ -87,15 +93,21 @@ type H2TransportConfig struct {
 // The returned transport wraps http2.Transport in a chrome120RoundTripper that
 // applies an OrderedHeader (exact capitalisation and insertion order) to every
 // outgoing request before handing it off to the underlying http2 layer.
+// Because those headers include "accept-encoding: gzip, deflate, br", the
+// result is also wrapped in the decompression layer (see
+// NewDecompressingTransport) unless cfg.Transport disables it.
 func NewChrome120H2Transport(cfg H2TransportConfig) http.RoundTripper {
 	if cfg.HelloID == (utls.ClientHelloID{}) {
 		cfg.HelloID = utls.HelloChrome_120
 	}
+	if cfg.Transport.isZero() {
+		cfg.Transport = DefaultTransportOptions()
+	}
 	if cfg.IdleConnTimeout == 0 {
-		cfg.IdleConnTimeout = 90 * time.Second
+		cfg.IdleConnTimeout = cfg.Transport.IdleConnTimeout
 	}
 
-	dialFn := UTLSDialer(cfg.HelloID)
+	dialFn := utlsDialer(cfg.HelloID, cfg.Transport)
 
 	h2t := &http2.Transport{
 		// Wire the uTLS dialer so every HTTP/2 connection uses the Chrome
@@ -139,7 +151,12 @@ func NewChrome120H2Transport(cfg H2TransportConfig) http.RoundTripper {
 		_ = h1
 	}
 
-	return &chrome120RoundTripper{h2: h2t}
+	return cfg.Transport.wrap(&chrome120RoundTripper{h2: h2t})
+}
+
+// CloseIdleConnections closes idle HTTP/2 connections.
+func (t *chrome120RoundTripper) CloseIdleConnections() {
+	t.h2.CloseIdleConnections()
 }
 
 // chrome120RoundTripper wraps an http2.Transport and applies Chrome 120
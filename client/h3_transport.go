@@ -0,0 +1,321 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// h3BrokenFor is how long an origin is sent over TCP after an HTTP/3 attempt
+// to it failed, mirroring how browsers mark broken alternative services.
+const h3BrokenFor = 5 * time.Minute
+
+// defaultAltSvcMaxAge applies to Alt-Svc entries without an ma parameter
+// (RFC 7838 §3.1).
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// http3Transport sends https requests over HTTP/3 (QUIC) and everything
+// else, plus any request whose QUIC attempt fails, over the TCP transport.
+//
+// With discover set, an origin is only contacted over HTTP/3 after one of its
+// responses advertised h3 in an Alt-Svc header; otherwise HTTP/3 is attempted
+// directly (prior knowledge).  Either way, a failed QUIC attempt marks the
+// origin broken for h3BrokenFor and the request is retried over TCP when its
+// body can be replayed.
+type http3Transport struct {
+	tcp      http.RoundTripper
+	h3       *http3.Transport
+	discover bool
+
+	mu     sync.Mutex
+	alt    map[string]altSvc    // origin host:port -> advertised h3 endpoint
+	broken map[string]time.Time // origin host:port -> retry QUIC after
+}
+
+// altSvc is one learned h3 alternative service.
+type altSvc struct {
+	authority string // host:port to dial over QUIC
+	expires   time.Time
+}
+
+// newHTTP3Transport wraps tcp with an HTTP/3 transport configured from opts.
+// Proxies cannot carry QUIC, so a non-empty proxy is rejected.
+func newHTTP3Transport(tcp http.RoundTripper, proxy string, opts TransportOptions, discover bool) (*http3Transport, error) {
+	if proxy != "" {
+		return nil, fmt.Errorf("client: protocol %q cannot be used through a proxy", opts.Protocol)
+	}
+	if opts.Dial != "" {
+		return nil, fmt.Errorf("client: protocol %q cannot be used with a fixed dial address", opts.Protocol)
+	}
+	tlsCfg := opts.TLS.stdConfig()
+	if tlsCfg == nil {
+		tlsCfg = &tls.Config{MinVersion: tls.VersionTLS13}
+	}
+	quicCfg := &quic.Config{
+		HandshakeIdleTimeout: opts.TLSHandshakeTimeout,
+		MaxIdleTimeout:       opts.IdleConnTimeout,
+		KeepAlivePeriod:      opts.KeepAlive,
+	}
+	if quicCfg.KeepAlivePeriod < 0 {
+		quicCfg.KeepAlivePeriod = 0
+	}
+
+	h3 := &http3.Transport{
+		TLSClientConfig: tlsCfg,
+		QUICConfig:      quicCfg,
+		// Compression is handled by the decompression layer, like on TCP.
+		DisableCompression: true,
+		Dial:               opts.quicDial(),
+	}
+	return &http3Transport{
+		tcp:      tcp,
+		h3:       h3,
+		discover: discover,
+		alt:      make(map[string]altSvc),
+		broken:   make(map[string]time.Time),
+	}, nil
+}
+
+// quicDial returns an http3.Transport.Dial function that resolves host names
+// through o.Resolver and bounds connection setup by o.DialTimeout.
+func (o TransportOptions) quicDial() func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	return func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+		conn, err := o.dialQUIC(ctx, addr, tlsCfg, cfg)
+		if err != nil {
+			return nil, &quicDialError{err: err}
+		}
+		return conn, nil
+	}
+}
+
+// quicDialError marks a failure to set up a QUIC connection, before any
+// request could be sent on it.
+type quicDialError struct{ err error }
+
+func (e *quicDialError) Error() string { return e.err.Error() }
+func (e *quicDialError) Unwrap() error { return e.err }
+
+// dialQUIC resolves addr and dials its addresses in turn.
+func (o TransportOptions) dialQUIC(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+	if o.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.DialTimeout)
+		defer cancel()
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("client: dial %q: %w", addr, err)
+	}
+	ips, err := o.Resolver.LookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := quic.DialAddrEarly(ctx, net.JoinHostPort(ip.String(), port), tlsCfg, cfg)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t *http3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	origin := canonicalAddr(req)
+	if target, ok := t.h3Target(req, origin); ok {
+		resp, err := t.h3.RoundTrip(target)
+		if err == nil {
+			t.learn(origin, resp)
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		t.markBroken(origin)
+		// The request may already have reached the server unless the
+		// connection could not even be set up, so only requests that are
+		// safe to send twice are retried after a later failure.
+		var dialErr *quicDialError
+		if !errors.As(err, &dialErr) && !isReplayable(req) {
+			return nil, fmt.Errorf("client: HTTP/3 %s request failed and is not retried over TCP: %w", req.Method, err)
+		}
+		if req, err = rewindBody(req); err != nil {
+			return nil, fmt.Errorf("client: HTTP/3 request failed and cannot be retried over TCP: %w", err)
+		}
+	}
+
+	resp, err := t.tcp.RoundTrip(req)
+	if err == nil {
+		t.learn(origin, resp)
+	}
+	return resp, err
+}
+
+// isReplayable reports whether req may be sent again after a failure that
+// left unknown whether the server received it, by the rules net/http uses
+// for its own retries: idempotent methods without side effects, or a
+// request carrying an idempotency key.
+func isReplayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	_, key := req.Header["Idempotency-Key"]
+	_, xkey := req.Header["X-Idempotency-Key"]
+	return key || xkey
+}
+
+// h3Target returns the request to send over HTTP/3, pointed at the
+// advertised alternative endpoint when it differs from the origin, or false
+// if the request should go over TCP.
+func (t *http3Transport) h3Target(req *http.Request, origin string) (*http.Request, bool) {
+	// Protocol upgrades such as WebSocket handshakes need HTTP/1.1.
+	if req.URL.Scheme != "https" || req.Header.Get("Upgrade") != "" {
+		return nil, false
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until, ok := t.broken[origin]; ok {
+		if now.Before(until) {
+			return nil, false
+		}
+		delete(t.broken, origin)
+	}
+	if !t.discover {
+		return req, true
+	}
+	as, ok := t.alt[origin]
+	if !ok {
+		return nil, false
+	}
+	if now.After(as.expires) {
+		delete(t.alt, origin)
+		return nil, false
+	}
+	if as.authority == origin {
+		return req, true
+	}
+	r := req.Clone(req.Context())
+	if r.Host == "" {
+		r.Host = req.URL.Host
+	}
+	r.URL.Host = as.authority
+	return r, true
+}
+
+// learn records or clears the h3 alternative service advertised by resp.
+func (t *http3Transport) learn(origin string, resp *http.Response) {
+	header := resp.Header.Get("Alt-Svc")
+	if header == "" || resp.ProtoMajor == 3 {
+		return
+	}
+	host, _, _ := net.SplitHostPort(origin)
+	as, clear, ok := parseAltSvc(header, host)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case clear:
+		delete(t.alt, origin)
+	case ok:
+		t.alt[origin] = as
+	}
+}
+
+func (t *http3Transport) markBroken(origin string) {
+	t.mu.Lock()
+	t.broken[origin] = time.Now().Add(h3BrokenFor)
+	delete(t.alt, origin)
+	t.mu.Unlock()
+}
+
+// CloseIdleConnections closes idle connections of both transports.
+func (t *http3Transport) CloseIdleConnections() {
+	t.h3.CloseIdleConnections()
+	if c, ok := t.tcp.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// Unwrap returns the TCP transport.
+func (t *http3Transport) Unwrap() http.RoundTripper { return t.tcp }
+
+// canonicalAddr returns req's target as host:port, defaulting the port from
+// the scheme.
+func canonicalAddr(req *http.Request) string {
+	host, port := req.URL.Hostname(), req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// rewindBody prepares req for a second attempt after a failed one.
+func rewindBody(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body is not replayable (GetBody is nil)")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := *req
+	r.Body = body
+	return &r, nil
+}
+
+// parseAltSvc extracts the first h3 entry of an Alt-Svc header (RFC 7838).
+// clear reports the special value "clear", which withdraws all alternatives.
+func parseAltSvc(header, originHost string) (as altSvc, clear, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "clear" {
+		return altSvc{}, true, false
+	}
+	for _, entry := range strings.Split(header, ",") {
+		params := strings.Split(entry, ";")
+		proto, value, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !found || proto != "h3" {
+			continue
+		}
+		authority := strings.Trim(value, `"`)
+		host, port, err := net.SplitHostPort(authority)
+		if err != nil || port == "" {
+			continue
+		}
+		if host == "" {
+			host = originHost
+		}
+		maxAge := defaultAltSvcMaxAge
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k == "ma" {
+				if secs, err := strconv.Atoi(strings.Trim(v, `"`)); err == nil {
+					maxAge = time.Duration(secs) * time.Second
+				}
+			}
+		}
+		return altSvc{authority: net.JoinHostPort(host, port), expires: time.Now().Add(maxAge)}, false, true
+	}
+	return altSvc{}, false, false
+}
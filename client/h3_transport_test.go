@@ -0,0 +1,172 @@
+package client_test
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// startH3Pair starts an httptest TLS server and an HTTP/3 server on the same
+// port (TCP and UDP respectively), sharing one certificate.  When altSvc is
+// set the TCP server advertises the HTTP/3 endpoint.
+func startH3Pair(t *testing.T, altSvc bool) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(nil)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Skipf("UDP port %d unavailable: %v", port, err)
+	}
+	h3 := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(srv.TLS.Clone()),
+		Handler:   http.HandlerFunc(echoProto),
+	}
+	go func() { _ = h3.Serve(udp) }()
+	t.Cleanup(func() { _ = h3.Close() })
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if altSvc {
+			w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"; ma=60`, port))
+		}
+		echoProto(w, r)
+	})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return srv, pool
+}
+
+func TestHTTP3_PriorKnowledge(t *testing.T) {
+	srv, pool := startH3Pair(t, false)
+
+	opts := client.DefaultTransportOptions()
+	opts.TLS.RootCAs = pool
+	opts.Protocol = client.ProtocolHTTP3
+	for i := 0; i < 2; i++ {
+		if got := protoGet(t, opts, srv.URL); got != "HTTP/3.0" {
+			t.Fatalf("request %d: got %s, want HTTP/3.0", i, got)
+		}
+	}
+}
+
+func TestHTTP3_AltSvcDiscovery(t *testing.T) {
+	srv, pool := startH3Pair(t, true)
+
+	opts := client.DefaultTransportOptions()
+	opts.TLS.RootCAs = pool
+	opts.Protocol = client.ProtocolHTTP3AltSvc
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions: %v", err)
+	}
+
+	want := []string{"HTTP/2.0", "HTTP/3.0", "HTTP/3.0"}
+	for i, w := range want {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.Proto != w {
+			t.Errorf("request %d: got %s, want %s", i, resp.Proto, w)
+		}
+	}
+}
+
+func TestHTTP3_FallsBackToTCP(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(echoProto))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	opts := client.DefaultTransportOptions()
+	opts.TLS.RootCAs = pool
+	opts.TLSHandshakeTimeout = 300 * time.Millisecond
+	opts.Protocol = client.ProtocolHTTP3
+	if got := protoGet(t, opts, srv.URL); got != "HTTP/2.0" {
+		t.Errorf("got %s, want HTTP/2.0 after QUIC failure", got)
+	}
+}
+
+func TestHTTP3_RejectsProxy(t *testing.T) {
+	opts := client.DefaultTransportOptions()
+	opts.Protocol = client.ProtocolHTTP3
+	if _, err := client.NewHTTPClientWithOptions("http://127.0.0.1:3128", time.Second, opts); err == nil {
+		t.Fatal("expected an error for h3 through a proxy")
+	}
+}
+
+func TestHTTP3_RetriesOverTCPOnlyWhenSafe(t *testing.T) {
+	var tcpHits atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tcpHits.Add(1)
+		echoProto(w, r)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Skipf("UDP port %d unavailable: %v", port, err)
+	}
+	// The HTTP/3 server receives every request and then aborts it, so the
+	// client cannot tell whether it took effect.
+	var h3Hits atomic.Int32
+	h3 := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(srv.TLS.Clone()),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h3Hits.Add(1)
+			panic(http.ErrAbortHandler)
+		}),
+	}
+	go func() { _ = h3.Serve(udp) }()
+	defer h3.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	opts := client.DefaultTransportOptions()
+	opts.TLS.RootCAs = pool
+	opts.Protocol = client.ProtocolHTTP3
+	newClient := func() *http.Client {
+		c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	resp, err := newClient().Post(srv.URL, "text/plain", strings.NewReader("order"))
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("POST: got %s, want an error instead of a retry over TCP", resp.Proto)
+	}
+	if h3Hits.Load() != 1 || tcpHits.Load() != 0 {
+		t.Errorf("POST reached HTTP/3 %d and TCP %d times, want 1 and 0", h3Hits.Load(), tcpHits.Load())
+	}
+
+	resp, err = newClient().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Proto != "HTTP/2.0" || tcpHits.Load() != 1 {
+		t.Errorf("GET: got %s after %d TCP requests, want a retry over HTTP/2.0", resp.Proto, tcpHits.Load())
+	}
+}
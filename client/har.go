@@ -0,0 +1,267 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR is an HTTP Archive (HAR 1.2) document, the format browsers' developer
+// tools export.  Only the fields this package records are modelled.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root object of a HAR document.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that wrote a HAR document.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request/response exchange.
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+
+	// Tag and Error are custom fields (HAR reserves the "_" prefix for
+	// them) carrying Interaction.Tag and Interaction.Error.
+	Tag   string `json:"_tag,omitempty"`
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest describes the request of a HAREntry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	Cookies     []HARNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+}
+
+// HARPostData is a request body.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+
+	// Encoding is "base64" when Text is base64-encoded binary data.  It is
+	// not part of HAR 1.2 for request bodies but is widely understood.
+	Encoding string `json:"_encoding,omitempty"`
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// HARResponse describes the response of a HAREntry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARContent is a response body.
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARNameValue is a header, query parameter or cookie.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARTimings splits HAREntry.Time into phases, in milliseconds.  -1 marks
+// a phase that does not apply, e.g. DNS on a reused connection.  As HAR
+// specifies, Connect includes SSL.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// NewHARTimings converts request Timings to HAR timings.  Time to first
+// byte not spent on DNS, connect or TLS is reported as wait.
+func NewHARTimings(t Timings) HARTimings {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	opt := func(d time.Duration) float64 {
+		if d <= 0 {
+			return -1
+		}
+		return ms(d)
+	}
+	return HARTimings{
+		Blocked: -1,
+		DNS:     opt(t.DNS),
+		Connect: opt(t.Connect + t.TLSHandshake),
+		SSL:     opt(t.TLSHandshake),
+		Wait:    ms(max(t.TTFB-t.DNS-t.Connect-t.TLSHandshake, 0)),
+		Receive: ms(t.BodyRead),
+	}
+}
+
+// NewHAR returns a HAR document holding entries.
+func NewHAR(entries []HAREntry) *HAR {
+	if entries == nil {
+		entries = []HAREntry{}
+	}
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "GoSessionEngine", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// HAREntry converts in to a HAR entry.
+func (in Interaction) HAREntry() HAREntry {
+	ms := float64(in.Duration) / float64(time.Millisecond)
+	e := HAREntry{
+		StartedDateTime: in.Time,
+		Time:            ms,
+		Request: HARRequest{
+			Method:      in.Request.Method,
+			URL:         in.Request.URL,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     HARHeaders(in.Request.Header),
+			QueryString: HARQuery(in.Request.URL),
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    in.Request.size(),
+		},
+		Response: HARResponse{
+			Status:      in.Response.Status,
+			StatusText:  http.StatusText(in.Response.Status),
+			HTTPVersion: in.Response.Proto,
+			Headers:     HARHeaders(in.Response.Header),
+			Cookies:     []HARNameValue{},
+			Content: HARContent{
+				Size:     in.Response.size(),
+				MimeType: in.Response.Header.Get("Content-Type"),
+				Text:     in.Response.Body,
+				Encoding: in.Response.BodyEncoding,
+			},
+			RedirectURL: in.Response.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    in.Response.size(),
+		},
+		Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: ms},
+		Tag:     in.Tag,
+		Error:   in.Error,
+	}
+	if e.Response.HTTPVersion == "" {
+		e.Response.HTTPVersion = "HTTP/1.1"
+	}
+	if in.Response.BodySize > 0 {
+		e.Response.Content.Comment = "body truncated when recorded"
+	}
+	if in.Request.Body != "" {
+		e.Request.PostData = &HARPostData{
+			MimeType: in.Request.Header.Get("Content-Type"),
+			Text:     in.Request.Body,
+			Encoding: in.Request.BodyEncoding,
+		}
+	}
+	return e
+}
+
+// Interaction converts e back to an Interaction.
+func (e HAREntry) Interaction() Interaction {
+	in := Interaction{
+		Time:     e.StartedDateTime,
+		Duration: time.Duration(e.Time * float64(time.Millisecond)),
+		Tag:      e.Tag,
+		Error:    e.Error,
+		Request: RecordedRequest{
+			Method: e.Request.Method,
+			URL:    e.Request.URL,
+			Header: fromHARHeaders(e.Request.Headers),
+		},
+		Response: RecordedResponse{
+			Status:       e.Response.Status,
+			Proto:        e.Response.HTTPVersion,
+			Header:       fromHARHeaders(e.Response.Headers),
+			Body:         e.Response.Content.Text,
+			BodyEncoding: e.Response.Content.Encoding,
+		},
+	}
+	if pd := e.Request.PostData; pd != nil {
+		in.Request.Body, in.Request.BodyEncoding = pd.Text, pd.Encoding
+	}
+	if b, _ := decodeBody(e.Response.Content.Text, e.Response.Content.Encoding); e.Response.Content.Size > len(b) {
+		in.Response.BodySize = int64(e.Response.Content.Size)
+	}
+	return in
+}
+
+// HARHeaders converts h to HAR name/value pairs, sorted by name.
+func HARHeaders(h http.Header) []HARNameValue {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []HARNameValue{}
+	for _, name := range names {
+		for _, v := range h[name] {
+			out = append(out, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+func fromHARHeaders(nvs []HARNameValue) http.Header {
+	h := make(http.Header, len(nvs))
+	for _, nv := range nvs {
+		h.Add(nv.Name, nv.Value)
+	}
+	return h
+}
+
+// HARQuery returns the query parameters of rawURL in their original order.
+func HARQuery(rawURL string) []HARNameValue {
+	out := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return out
+	}
+	// Walk the raw query rather than u.Query() to keep the original order.
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		name, _ = url.QueryUnescape(name)
+		value, _ = url.QueryUnescape(value)
+		out = append(out, HARNameValue{Name: name, Value: value})
+	}
+	return out
+}
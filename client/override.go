@@ -0,0 +1,35 @@
+package client
+
+import "context"
+
+// Override adjusts a client's TransportOptions for a subset of requests, for
+// example the requests sent to one target.  Holders of a default client
+// (such as session.Session) build one additional client per distinct Key and
+// reuse it for every request carrying that override.
+type Override struct {
+	// Key identifies the resulting transport variant.  Two overrides with
+	// the same Key must produce the same options.
+	Key string
+
+	// Apply modifies opts, a copy of the holder's default options.  slot
+	// lets an override pick among alternatives (e.g. one of several client
+	// certificates); sessions pass their ID.
+	Apply func(opts *TransportOptions, slot int)
+}
+
+type overrideKey struct{}
+
+// WithOverride returns a copy of ctx carrying ov.  A nil ov returns ctx
+// unchanged.
+func WithOverride(ctx context.Context, ov *Override) context.Context {
+	if ov == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, overrideKey{}, ov)
+}
+
+// OverrideFrom returns the override stored in ctx by WithOverride, or nil.
+func OverrideFrom(ctx context.Context) *Override {
+	ov, _ := ctx.Value(overrideKey{}).(*Override)
+	return ov
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Protocol selects the HTTP version a transport speaks.
+type Protocol string
+
+const (
+	// ProtocolAuto negotiates HTTP/2 over TLS via ALPN when
+	// TransportOptions.EnableHTTP2 is set and uses HTTP/1.1 otherwise.  The
+	// empty string means the same.
+	ProtocolAuto Protocol = "auto"
+
+	// ProtocolHTTP1 forces HTTP/1.1, including over TLS.
+	ProtocolHTTP1 Protocol = "h1"
+
+	// ProtocolHTTP2 requires HTTP/2 over TLS.  Requests to http:// URLs
+	// fail; use ProtocolH2C for cleartext.
+	ProtocolHTTP2 Protocol = "h2"
+
+	// ProtocolH2C speaks cleartext HTTP/2 with prior knowledge (no Upgrade
+	// dance) to http:// URLs, as gRPC-style services expect.
+	ProtocolH2C Protocol = "h2c"
+
+	// ProtocolHTTP3 sends https requests over HTTP/3 (QUIC) with prior
+	// knowledge.  If the QUIC attempt fails the origin is marked broken for
+	// a few minutes and requests fall back to TCP, which negotiates like
+	// ProtocolAuto.  It cannot be combined with a proxy.
+	ProtocolHTTP3 Protocol = "h3"
+
+	// ProtocolHTTP3AltSvc starts on TCP and switches an origin to HTTP/3
+	// once it advertises h3 in an Alt-Svc response header, as browsers do.
+	// Failures fall back to TCP as with ProtocolHTTP3.
+	ProtocolHTTP3AltSvc Protocol = "h3-altsvc"
+)
+
+// ParseProtocol validates name and returns it as a Protocol.  The empty
+// string yields ProtocolAuto.
+func ParseProtocol(name string) (Protocol, error) {
+	switch p := Protocol(name); p {
+	case "":
+		return ProtocolAuto, nil
+	case ProtocolAuto, ProtocolHTTP1, ProtocolHTTP2, ProtocolH2C, ProtocolHTTP3, ProtocolHTTP3AltSvc:
+		return p, nil
+	}
+	return "", fmt.Errorf("client: unknown protocol %q (want auto, h1, h2, h2c, h3 or h3-altsvc)", name)
+}
+
+// IsHTTP3 reports whether p sends requests over HTTP/3.
+func (p Protocol) IsHTTP3() bool {
+	return p == ProtocolHTTP3 || p == ProtocolHTTP3AltSvc
+}
+
+// httpProtocols returns the http.Transport.Protocols value for o.Protocol, or
+// nil for ProtocolAuto, which keeps the ForceAttemptHTTP2 behaviour.  The
+// HTTP/3 modes also return nil: it describes their TCP fallback.
+func (o TransportOptions) httpProtocols() (*http.Protocols, error) {
+	p, err := ParseProtocol(string(o.Protocol))
+	if err != nil {
+		return nil, err
+	}
+	var ps http.Protocols
+	switch p {
+	case ProtocolAuto, ProtocolHTTP3, ProtocolHTTP3AltSvc:
+		return nil, nil
+	case ProtocolHTTP1:
+		ps.SetHTTP1(true)
+	case ProtocolHTTP2:
+		ps.SetHTTP2(true)
+	case ProtocolH2C:
+		ps.SetUnencryptedHTTP2(true)
+	}
+	return &ps, nil
+}
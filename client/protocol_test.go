@@ -0,0 +1,117 @@
+package client_test
+
+import (
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+func protoGet(t *testing.T, opts client.TransportOptions, url string) string {
+	t.Helper()
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions: %v", err)
+	}
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != resp.Proto {
+		t.Errorf("client saw %q but server saw %q", resp.Proto, body)
+	}
+	return resp.Proto
+}
+
+func echoProto(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, r.Proto)
+}
+
+func TestProtocol_TLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(echoProto))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	cases := map[client.Protocol]string{
+		"":                   "HTTP/2.0",
+		client.ProtocolAuto:  "HTTP/2.0",
+		client.ProtocolHTTP1: "HTTP/1.1",
+		client.ProtocolHTTP2: "HTTP/2.0",
+	}
+	for proto, want := range cases {
+		opts := client.DefaultTransportOptions()
+		opts.TLS.RootCAs = pool
+		opts.Protocol = proto
+		if got := protoGet(t, opts, srv.URL); got != want {
+			t.Errorf("protocol %q: got %s, want %s", proto, got, want)
+		}
+	}
+}
+
+func TestProtocol_H2C(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(echoProto))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	defer srv.Close()
+
+	opts := client.DefaultTransportOptions()
+	if got := protoGet(t, opts, srv.URL); got != "HTTP/1.1" {
+		t.Errorf("auto over cleartext: got %s, want HTTP/1.1", got)
+	}
+	opts.Protocol = client.ProtocolH2C
+	if got := protoGet(t, opts, srv.URL); got != "HTTP/2.0" {
+		t.Errorf("h2c: got %s, want HTTP/2.0", got)
+	}
+}
+
+func TestProtocol_Invalid(t *testing.T) {
+	opts := client.DefaultTransportOptions()
+	opts.Protocol = "spdy"
+	if _, err := client.NewHTTPClientWithOptions("", time.Second, opts); err == nil {
+		t.Error("expected an error for an unknown protocol")
+	}
+
+	opts.Protocol = client.ProtocolHTTP2
+	if _, err := client.NewHTTPClientWithTLSOptions("", time.Second, utls.HelloChrome_120, opts); err == nil {
+		t.Error("expected an error for h2 on the uTLS http.Transport path")
+	}
+}
+
+func TestProtocol_UTLSHTTP1RestrictsALPN(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(echoProto))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	opts := client.DefaultTransportOptions()
+	opts.TLS.RootCAs = x509.NewCertPool()
+	opts.TLS.RootCAs.AddCert(srv.Certificate())
+	opts.Protocol = client.ProtocolHTTP1
+	c, err := client.NewHTTPClientWithTLSOptions("", 5*time.Second, utls.HelloChrome_120, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET over uTLS with h1: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/1.1" {
+		t.Errorf("server saw %q, want HTTP/1.1 (h2 must not be offered in ALPN)", body)
+	}
+}
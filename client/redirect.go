@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxRedirects is the hop limit used when a policy sets none; it
+// matches net/http's default.
+const DefaultMaxRedirects = 10
+
+// ErrTooManyRedirects is returned (wrapped in a *url.Error) when a request
+// is redirected more often than its policy allows.
+var ErrTooManyRedirects = errors.New("client: too many redirects")
+
+// RedirectMode selects how redirects are handled.
+type RedirectMode string
+
+const (
+	// RedirectFollow follows redirects up to the hop limit.
+	RedirectFollow RedirectMode = "follow"
+
+	// RedirectNone returns the first redirect response to the caller.
+	RedirectNone RedirectMode = "none"
+
+	// RedirectSameHost follows redirects that stay on the original host
+	// name and returns the first one that leaves it.
+	RedirectSameHost RedirectMode = "same-host"
+)
+
+// ParseRedirectMode validates s.  The empty string means RedirectFollow.
+func ParseRedirectMode(s string) (RedirectMode, error) {
+	switch m := RedirectMode(strings.ToLower(s)); m {
+	case "":
+		return RedirectFollow, nil
+	case RedirectFollow, RedirectNone, RedirectSameHost:
+		return m, nil
+	}
+	return "", fmt.Errorf("client: unknown redirect mode %q (want follow, none or same-host)", s)
+}
+
+// RedirectPolicy decides which redirects a request follows.
+type RedirectPolicy struct {
+	Mode RedirectMode
+
+	// MaxHops caps the redirects followed.  Zero selects
+	// DefaultMaxRedirects.
+	MaxHops int
+}
+
+type redirectKey struct{}
+
+// WithRedirectPolicy returns a copy of ctx carrying p.  Every client built
+// by this package applies the policy to requests sent with that context;
+// requests without one follow up to DefaultMaxRedirects hops.
+func WithRedirectPolicy(ctx context.Context, p RedirectPolicy) context.Context {
+	return context.WithValue(ctx, redirectKey{}, p)
+}
+
+// RedirectPolicyFrom returns the policy stored in ctx by WithRedirectPolicy.
+func RedirectPolicyFrom(ctx context.Context) (RedirectPolicy, bool) {
+	p, ok := ctx.Value(redirectKey{}).(RedirectPolicy)
+	return p, ok
+}
+
+// CheckRedirect is the http.Client.CheckRedirect hook installed by this
+// package.  It enforces the RedirectPolicy carried by the request context:
+// a redirect that must not be followed ends the request with the redirect
+// response itself, and exceeding the hop limit fails with
+// ErrTooManyRedirects.
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	p, _ := RedirectPolicyFrom(req.Context())
+	switch p.Mode {
+	case RedirectNone:
+		return http.ErrUseLastResponse
+	case RedirectSameHost:
+		if len(via) > 0 && !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+			return http.ErrUseLastResponse
+		}
+	}
+	maxHops := p.MaxHops
+	if maxHops <= 0 {
+		maxHops = DefaultMaxRedirects
+	}
+	if len(via) > maxHops {
+		return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, maxHops)
+	}
+	return nil
+}
+
+// RedirectHop is one followed redirect.
+type RedirectHop struct {
+	// URL is the address that answered with the redirect.
+	URL string `json:"url"`
+
+	// Status is the redirect status code, e.g. 302.
+	Status int `json:"status"`
+
+	// Location is the redirect target as sent by the server.
+	Location string `json:"location"`
+
+	// SetCookies holds the Set-Cookie headers of the redirect response.
+	SetCookies []string `json:"set_cookies,omitempty"`
+}
+
+// RedirectChain returns the redirects followed to obtain resp, in order.
+// It is empty when resp answered the original request.  The chain is read
+// from the Response links net/http keeps on redirected requests, so it works
+// with any client; intermediate bodies have already been closed.
+func RedirectChain(resp *http.Response) []RedirectHop {
+	var chain []RedirectHop
+	for r := resp; r != nil && r.Request != nil && r.Request.Response != nil; r = r.Request.Response {
+		prev := r.Request.Response
+		hop := RedirectHop{Status: prev.StatusCode, Location: prev.Header.Get("Location"), SetCookies: prev.Header.Values("Set-Cookie")}
+		if prev.Request != nil {
+			hop.URL = prev.Request.URL.String()
+		}
+		chain = append(chain, hop)
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// redirectServer redirects /hop/N to /hop/N-1, setting a cookie per hop,
+// and answers /hop/0 with 200.
+func redirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := strings.TrimPrefix(r.URL.Path, "/hop/")
+		if n == "0" {
+			w.Write([]byte("done"))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "h" + n, Value: "1"})
+		next := string(rune(n[0] - 1))
+		http.Redirect(w, r, "/hop/"+next, http.StatusFound)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getWithPolicy(t *testing.T, url string, p *client.RedirectPolicy) (*http.Response, error) {
+	t.Helper()
+	c, err := client.NewHTTPClient("", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if p != nil {
+		ctx = client.WithRedirectPolicy(ctx, *p)
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := c.Do(req)
+	if err == nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestRedirectChain_Follow(t *testing.T) {
+	srv := redirectServer(t)
+	resp, err := getWithPolicy(t, srv.URL+"/hop/3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := client.RedirectChain(resp)
+	if resp.StatusCode != 200 || len(chain) != 3 {
+		t.Fatalf("got status %d and %d hops, want 200 and 3", resp.StatusCode, len(chain))
+	}
+	for i, hop := range chain {
+		n := string(rune('3' - i))
+		if hop.URL != srv.URL+"/hop/"+n || hop.Status != 302 || hop.Location != "/hop/"+string(rune('2'-i)) {
+			t.Errorf("hop %d: %+v", i, hop)
+		}
+		if len(hop.SetCookies) != 1 || !strings.HasPrefix(hop.SetCookies[0], "h"+n+"=1") {
+			t.Errorf("hop %d Set-Cookie: %v", i, hop.SetCookies)
+		}
+	}
+}
+
+func TestRedirectPolicy_None(t *testing.T) {
+	srv := redirectServer(t)
+	resp, err := getWithPolicy(t, srv.URL+"/hop/2", &client.RedirectPolicy{Mode: client.RedirectNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 302 || len(client.RedirectChain(resp)) != 0 {
+		t.Errorf("got %d with %d hops, want the first 302 and no hops", resp.StatusCode, len(client.RedirectChain(resp)))
+	}
+}
+
+func TestRedirectPolicy_MaxHops(t *testing.T) {
+	srv := redirectServer(t)
+	p := &client.RedirectPolicy{Mode: client.RedirectFollow, MaxHops: 2}
+	if _, err := getWithPolicy(t, srv.URL+"/hop/3", p); !errors.Is(err, client.ErrTooManyRedirects) {
+		t.Errorf("3 hops with a limit of 2: got %v, want ErrTooManyRedirects", err)
+	}
+	resp, err := getWithPolicy(t, srv.URL+"/hop/2", p)
+	if err != nil || resp.StatusCode != 200 {
+		t.Errorf("2 hops with a limit of 2 should succeed: %v", err)
+	}
+}
+
+func TestRedirectPolicy_SameHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other host"))
+	}))
+	defer other.Close()
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/away", http.StatusMovedPermanently)
+		case "/away":
+			http.Redirect(w, r, otherURL+"/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := getWithPolicy(t, srv.URL+"/start", &client.RedirectPolicy{Mode: client.RedirectSameHost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := client.RedirectChain(resp)
+	if resp.StatusCode != 302 || len(chain) != 1 || chain[0].Status != 301 {
+		t.Errorf("got %d with chain %+v, want the cross-host 302 after one same-host hop", resp.StatusCode, chain)
+	}
+	if resp.Header.Get("Location") != otherURL+"/" {
+		t.Errorf("Location: %q", resp.Header.Get("Location"))
+	}
+}
+
+func TestParseRedirectMode(t *testing.T) {
+	for in, want := range map[string]client.RedirectMode{"": client.RedirectFollow, "NONE": client.RedirectNone, "same-host": client.RedirectSameHost} {
+		if got, err := client.ParseRedirectMode(in); err != nil || got != want {
+			t.Errorf("ParseRedirectMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := client.ParseRedirectMode("sometimes"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,439 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// defaultDNSTimeout bounds a query to ResolverOptions.Server when the dial
+// context carries no deadline of its own.
+const defaultDNSTimeout = 5 * time.Second
+
+// ResolverOptions controls how transports built by this package turn host
+// names into IP addresses.  The zero value uses the system resolver with no
+// caching, exactly like net/http.
+//
+// Only the hosts this process dials are resolved here: with a proxy that is
+// the proxy host, while the target host is resolved by the proxy.
+type ResolverOptions struct {
+	// Hosts pins host names to fixed IP addresses, like curl --resolve.
+	// Keys are matched case-insensitively and the addresses are tried in
+	// order.  Overrides bypass both the DNS server and the cache.
+	Hosts map[string][]string
+
+	// Server is the address ("host" or "host:port", port 53 by default) of
+	// a DNS server to query directly instead of the system resolver.
+	// /etc/hosts is not consulted in that case.
+	Server string
+
+	// IPVersion restricts resolution and dialing to IPv4 (4) or IPv6 (6).
+	// Zero allows both, preferring IPv4 addresses.
+	IPVersion int
+
+	// CacheTTL enables the process-wide DNS cache when positive.  Answers
+	// from Server are cached for their record TTL, capped at CacheTTL; the
+	// system resolver does not expose TTLs, so its answers are cached for
+	// CacheTTL.
+	CacheTTL time.Duration
+}
+
+// enabled reports whether o differs from the plain system resolver.
+func (o ResolverOptions) enabled() bool {
+	return len(o.Hosts) > 0 || o.Server != "" || o.IPVersion != 0 || o.CacheTTL > 0
+}
+
+// network returns the Go network name ("ip", "ip4" or "ip6") for o.IPVersion.
+func (o ResolverOptions) network() string {
+	switch o.IPVersion {
+	case 4:
+		return "ip4"
+	case 6:
+		return "ip6"
+	}
+	return "ip"
+}
+
+// LookupIP resolves host according to o: static overrides first, then the
+// cache, then Server or the system resolver.  IP literals are returned
+// unchanged.
+func (o ResolverOptions) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	ips, _, err := o.lookup(ctx, host)
+	return ips, err
+}
+
+// lookup implements LookupIP and also reports whether the answer came from
+// an override or the cache rather than a fresh query.
+func (o ResolverOptions) lookup(ctx context.Context, host string) (ips []net.IP, cached bool, err error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return filterIPs([]net.IP{ip}, o.IPVersion), true, nil
+	}
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if addrs, ok := o.hostOverride(name); ok {
+		for _, a := range addrs {
+			if ip := net.ParseIP(a); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+		ips = filterIPs(ips, o.IPVersion)
+		if len(ips) == 0 {
+			return nil, true, fmt.Errorf("client: resolve %s: no IPv%d address in host override", host, o.IPVersion)
+		}
+		return ips, true, nil
+	}
+
+	key := o.Server + "|" + o.network() + "|" + name
+	if o.CacheTTL > 0 {
+		if ips, ok := dnsCache.get(key); ok {
+			return ips, true, nil
+		}
+	}
+
+	var ttl time.Duration
+	if o.Server != "" {
+		ips, ttl, err = o.queryServer(ctx, name)
+	} else {
+		ips, err = net.DefaultResolver.LookupIP(ctx, o.network(), name)
+		ips = preferIPv4(ips)
+		ttl = o.CacheTTL
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(ips) == 0 {
+		return nil, false, fmt.Errorf("client: resolve %s: no addresses", host)
+	}
+	if o.CacheTTL > 0 {
+		dnsCache.put(key, ips, min(ttl, o.CacheTTL))
+	}
+	return ips, false, nil
+}
+
+// hostOverride returns the Hosts entry matching name case-insensitively.
+func (o ResolverOptions) hostOverride(name string) ([]string, bool) {
+	if addrs, ok := o.Hosts[name]; ok {
+		return addrs, true
+	}
+	for host, addrs := range o.Hosts {
+		if strings.EqualFold(strings.TrimSuffix(host, "."), name) {
+			return addrs, true
+		}
+	}
+	return nil, false
+}
+
+// dialContext wraps dial so that host names are resolved through o and each
+// resulting address is tried in turn.  httptrace DNS events are emitted so
+// request timings stay complete.
+func (o ResolverOptions) dialContext(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("client: dial %q: %w", addr, err)
+		}
+		switch o.IPVersion {
+		case 4:
+			network = strings.TrimRight(network, "46") + "4"
+		case 6:
+			network = strings.TrimRight(network, "46") + "6"
+		}
+
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.DNSStart != nil {
+			trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+		}
+		ips, cached, err := o.lookup(ctx, host)
+		if trace != nil && trace.DNSDone != nil {
+			addrs := make([]net.IPAddr, len(ips))
+			for i, ip := range ips {
+				addrs[i] = net.IPAddr{IP: ip}
+			}
+			trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err})
+		}
+		if cached {
+			if tr, ok := ctx.Value(traceKey{}).(*Trace); ok {
+				tr.markDNSCached()
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
+
+// queryServer resolves name by querying o.Server directly, returning the
+// addresses and the smallest record TTL.
+func (o ResolverOptions) queryServer(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	server := o.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultDNSTimeout)
+		defer cancel()
+	}
+
+	var types []dnsmessage.Type
+	switch o.IPVersion {
+	case 4:
+		types = []dnsmessage.Type{dnsmessage.TypeA}
+	case 6:
+		types = []dnsmessage.Type{dnsmessage.TypeAAAA}
+	default:
+		types = []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+	}
+
+	var (
+		ips     []net.IP
+		ttl     = time.Duration(-1)
+		lastErr error
+	)
+	for _, qt := range types {
+		got, recTTL, err := exchangeDNS(ctx, server, name, qt)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ips = append(ips, got...)
+		if len(got) > 0 && (ttl < 0 || recTTL < ttl) {
+			ttl = recTTL
+		}
+	}
+	if len(ips) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("client: resolve %s via %s: no addresses", name, server)
+		}
+		return nil, 0, lastErr
+	}
+	return ips, max(ttl, 0), nil
+}
+
+// exchangeDNS sends one query over UDP, retrying over TCP if the answer is
+// truncated, and returns the A/AAAA records it contains.
+func exchangeDNS(ctx context.Context, server, name string, qtype dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("client: resolve %s: %w", name, err)
+	}
+	id := uint16(rand.UintN(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("client: resolve %s: pack query: %w", name, err)
+	}
+
+	resp, err := dnsRoundTrip(ctx, "udp", server, packed)
+	if err == nil && resp.Header.Truncated {
+		resp, err = dnsRoundTrip(ctx, "tcp", server, packed)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("client: resolve %s via %s: %w", name, server, err)
+	}
+	if resp.Header.ID != id {
+		return nil, 0, fmt.Errorf("client: resolve %s via %s: mismatched response ID", name, server)
+	}
+	switch resp.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, 0, &net.DNSError{Err: "no such host", Name: name, Server: server, IsNotFound: true}
+	default:
+		return nil, 0, fmt.Errorf("client: resolve %s via %s: %s", name, server, resp.Header.RCode)
+	}
+
+	var (
+		ips []net.IP
+		ttl uint32
+	)
+	for _, ans := range resp.Answers {
+		var ip net.IP
+		switch body := ans.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			ip = net.IP(body.AAAA[:])
+		default:
+			continue // CNAMEs etc.; the resolver includes the final records
+		}
+		if len(ips) == 0 || ans.Header.TTL < ttl {
+			ttl = ans.Header.TTL
+		}
+		ips = append(ips, ip)
+	}
+	return ips, time.Duration(ttl) * time.Second, nil
+}
+
+// dnsRoundTrip writes msg to server over network ("udp" or "tcp") and parses
+// the reply.
+func dnsRoundTrip(ctx context.Context, network, server string, msg []byte) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	var buf []byte
+	if network == "tcp" {
+		framed := make([]byte, 2+len(msg))
+		binary.BigEndian.PutUint16(framed, uint16(len(msg)))
+		copy(framed[2:], msg)
+		if _, err := conn.Write(framed); err != nil {
+			return nil, err
+		}
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := conn.Write(msg); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf); err != nil {
+		return nil, fmt.Errorf("unpack response: %w", err)
+	}
+	if !resp.Header.Response {
+		return nil, errors.New("reply is not a DNS response")
+	}
+	return &resp, nil
+}
+
+// filterIPs keeps only the addresses of the requested IP version (0 = all).
+func filterIPs(ips []net.IP, version int) []net.IP {
+	if version == 0 {
+		return ips
+	}
+	out := ips[:0:0]
+	for _, ip := range ips {
+		if is4 := ip.To4() != nil; is4 == (version == 4) {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
+
+// preferIPv4 stably moves IPv4 addresses ahead of IPv6 ones.
+func preferIPv4(ips []net.IP) []net.IP {
+	out := make([]net.IP, 0, len(ips))
+	out = append(out, filterIPs(ips, 4)...)
+	return append(out, filterIPs(ips, 6)...)
+}
+
+// dnsCache is the process-wide cache shared by every transport whose
+// ResolverOptions enable caching.  Entries are keyed by server, IP version
+// and host name, so differently configured resolvers never see each other's
+// answers.
+//
+// Expired entries are swept whenever the map has doubled since the last
+// sweep, and the cache never holds more than maxDNSCacheEntries, so a run
+// that touches many short-lived host names does not grow it without bound.
+var dnsCache = &resolverCache{entries: make(map[string]cacheEntry)}
+
+// maxDNSCacheEntries caps dnsCache.  When a sweep leaves it full, arbitrary
+// entries are dropped to make room; they are simply queried again.
+const maxDNSCacheEntries = 10_000
+
+// minDNSCacheSweep is the size below which dnsCache is never swept.
+const minDNSCacheSweep = 256
+
+// FlushDNSCache discards every cached DNS answer.
+func FlushDNSCache() {
+	dnsCache.mu.Lock()
+	clear(dnsCache.entries)
+	dnsCache.sweepAt = 0
+	dnsCache.mu.Unlock()
+}
+
+type cacheEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+type resolverCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	sweepAt int // size at which put next sweeps expired entries
+}
+
+func (c *resolverCache) get(key string) ([]net.IP, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.ips, true
+}
+
+func (c *resolverCache) put(key string, ips []net.IP, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= max(c.sweepAt, minDNSCacheSweep) {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry{ips: ips, expires: now.Add(ttl)}
+}
+
+// evict removes expired entries and, if the cache is still full, enough
+// others to make room for one more.  c.mu must be held.
+func (c *resolverCache) evict(now time.Time) {
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for k := range c.entries {
+		if len(c.entries) < maxDNSCacheEntries {
+			break
+		}
+		delete(c.entries, k)
+	}
+	c.sweepAt = min(2*len(c.entries), maxDNSCacheEntries)
+}
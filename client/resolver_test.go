@@ -0,0 +1,205 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// fakeDNS is a UDP DNS server answering every A query with 127.0.0.1 and
+// every AAAA query with ::1, using the given record TTL.
+type fakeDNS struct {
+	addr    string
+	queries atomic.Int64
+}
+
+func startFakeDNS(t *testing.T, ttl uint32) *fakeDNS {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	f := &fakeDNS{addr: pc.LocalAddr().String()}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, peer, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var q dnsmessage.Message
+			if err := q.Unpack(buf[:n]); err != nil || len(q.Questions) != 1 {
+				continue
+			}
+			f.queries.Add(1)
+			question := q.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: q.Header.ID, Response: true, RecursionAvailable: true},
+				Questions: q.Questions,
+			}
+			hdr := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: ttl}
+			switch question.Type {
+			case dnsmessage.TypeA:
+				hdr.Type = dnsmessage.TypeA
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}})
+			case dnsmessage.TypeAAAA:
+				hdr.Type = dnsmessage.TypeAAAA
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}}})
+			}
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = pc.WriteTo(out, peer)
+		}
+	}()
+	return f
+}
+
+func TestResolver_HostOverride(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host)
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	opts := client.DefaultTransportOptions()
+	opts.Resolver.Hosts = map[string][]string{"Pinned.Example": {"127.0.0.1"}}
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("http://pinned.example:" + port + "/")
+	if err != nil {
+		t.Fatalf("GET via override: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "pinned.example:"+port {
+		t.Errorf("Host header should keep the original name, got %q", body)
+	}
+}
+
+func TestResolver_OverrideIPVersionFilter(t *testing.T) {
+	r := client.ResolverOptions{
+		Hosts:     map[string][]string{"dual.example": {"::1", "127.0.0.1"}},
+		IPVersion: 4,
+	}
+	ips, err := r.LookupIP(context.Background(), "dual.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("IPv4-only lookup: got %v", ips)
+	}
+
+	r.Hosts = map[string][]string{"v6.example": {"::1"}}
+	if _, err := r.LookupIP(context.Background(), "v6.example"); err == nil {
+		t.Error("IPv4-only lookup of an IPv6-only override should fail")
+	}
+}
+
+func TestResolver_CustomServerAndCache(t *testing.T) {
+	client.FlushDNSCache()
+	dns := startFakeDNS(t, 60)
+	r := client.ResolverOptions{Server: dns.addr, IPVersion: 4, CacheTTL: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		ips, err := r.LookupIP(context.Background(), "cached.example")
+		if err != nil {
+			t.Fatalf("lookup %d: %v", i, err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.1")) {
+			t.Fatalf("lookup %d: got %v", i, ips)
+		}
+	}
+	if got := dns.queries.Load(); got != 1 {
+		t.Errorf("queries with a warm cache: got %d, want 1", got)
+	}
+
+	client.FlushDNSCache()
+	if _, err := r.LookupIP(context.Background(), "cached.example"); err != nil {
+		t.Fatal(err)
+	}
+	if got := dns.queries.Load(); got != 2 {
+		t.Errorf("queries after flush: got %d, want 2", got)
+	}
+}
+
+func TestResolver_ZeroTTLIsNotCached(t *testing.T) {
+	client.FlushDNSCache()
+	dns := startFakeDNS(t, 0)
+	r := client.ResolverOptions{Server: dns.addr, IPVersion: 6, CacheTTL: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		ips, err := r.LookupIP(context.Background(), "volatile.example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.IPv6loopback) {
+			t.Fatalf("got %v, want [::1]", ips)
+		}
+	}
+	if got := dns.queries.Load(); got != 2 {
+		t.Errorf("a zero record TTL should bypass the cache: got %d queries, want 2", got)
+	}
+}
+
+func TestUTLSDialerWithOptions_UsesResolver(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	opts := client.DefaultTransportOptions()
+	opts.Resolver.Hosts = map[string][]string{"utls.example": {"127.0.0.1"}}
+	dial := client.UTLSDialerWithOptions(utls.HelloChrome_120, opts)
+
+	conn, err := dial(context.Background(), "tcp", "utls.example:"+port, &tls.Config{InsecureSkipVerify: true}) // #nosec G402 – test server
+	if err != nil {
+		t.Fatalf("uTLS dial via override: %v", err)
+	}
+	conn.Close()
+}
+
+func TestResolver_TimingsReportCacheHits(t *testing.T) {
+	client.FlushDNSCache()
+	dns := startFakeDNS(t, 60)
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	opts := client.DefaultTransportOptions()
+	opts.Resolver = client.ResolverOptions{Server: dns.addr, IPVersion: 4, CacheTTL: time.Minute}
+	for i, want := range []bool{false, true} {
+		c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "http://timed.example:"+port+"/", nil)
+		req, tr := client.TraceRequest(req)
+		resp, err := c.Do(req)
+		tr.Finish(resp, err)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+		if got := tr.Timings().DNSCached; got != want {
+			t.Errorf("request %d: DNSCached = %v, want %v", i, got, want)
+		}
+	}
+}
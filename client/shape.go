@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShapeProfile describes emulated network conditions for a Shaper.
+type ShapeProfile struct {
+	// Name labels the profile in metrics.
+	Name string
+
+	// DownloadKbps and UploadKbps cap the read and write bandwidth in
+	// kilobits per second.  Zero means unlimited.
+	DownloadKbps int64
+	UploadKbps   int64
+
+	// Latency is added once per round trip: after connecting and before
+	// the first data read following a write.
+	Latency time.Duration
+
+	// Jitter varies each injected latency uniformly by up to ±Jitter.
+	Jitter time.Duration
+}
+
+// shapeProfiles are the built-in profiles, loosely following the presets of
+// browser developer tools.
+var shapeProfiles = map[string]ShapeProfile{
+	"gprs": {Name: "gprs", DownloadKbps: 50, UploadKbps: 20, Latency: 500 * time.Millisecond, Jitter: 100 * time.Millisecond},
+	"2g":   {Name: "2g", DownloadKbps: 250, UploadKbps: 50, Latency: 300 * time.Millisecond, Jitter: 50 * time.Millisecond},
+	"3g":   {Name: "3g", DownloadKbps: 750, UploadKbps: 250, Latency: 100 * time.Millisecond, Jitter: 20 * time.Millisecond},
+	"4g":   {Name: "4g", DownloadKbps: 4000, UploadKbps: 3000, Latency: 20 * time.Millisecond, Jitter: 5 * time.Millisecond},
+	"dsl":  {Name: "dsl", DownloadKbps: 2000, UploadKbps: 1000, Latency: 5 * time.Millisecond},
+	"wifi": {Name: "wifi", DownloadKbps: 30000, UploadKbps: 15000, Latency: 2 * time.Millisecond, Jitter: time.Millisecond},
+}
+
+// LookupShapeProfile returns the built-in profile with the given name.
+func LookupShapeProfile(name string) (ShapeProfile, bool) {
+	p, ok := shapeProfiles[name]
+	return p, ok
+}
+
+// ShapeProfileNames returns the names of the built-in profiles, sorted.
+func ShapeProfileNames() []string {
+	names := make([]string, 0, len(shapeProfiles))
+	for name := range shapeProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ShapeObserver receives the activity of shaped connections for
+// aggregation.  *metrics.Metrics implements it.  Methods are called
+// concurrently.
+type ShapeObserver interface {
+	// ShapedIO is called after every read (upload false) or write that
+	// moved data, with the byte count and the time the call took.  The
+	// time includes bandwidth throttling and, for reads, waiting for the
+	// peer, but not injected latency.
+	ShapedIO(profile string, upload bool, n int, d time.Duration)
+
+	// ShapedDelay is called for every injected latency.
+	ShapedDelay(profile string, d time.Duration)
+}
+
+// Shaper throttles connections to the bandwidth and latency of a
+// ShapeProfile.  The bandwidth limits are shared by every connection the
+// Shaper wraps, so one Shaper per session shapes the session as a whole.
+// Set TransportOptions.Shaper to shape a client's TCP connections; HTTP/3
+// (UDP) traffic is not shaped.
+type Shaper struct {
+	profile  ShapeProfile
+	observer ShapeObserver
+	down, up *bandwidth
+}
+
+// NewShaper returns a Shaper for p.  o may be nil.
+func NewShaper(p ShapeProfile, o ShapeObserver) *Shaper {
+	return &Shaper{
+		profile:  p,
+		observer: o,
+		down:     newBandwidth(p.DownloadKbps),
+		up:       newBandwidth(p.UploadKbps),
+	}
+}
+
+// Profile returns the profile s was built with.
+func (s *Shaper) Profile() ShapeProfile { return s.profile }
+
+// Conn wraps c so its reads and writes are shaped.
+func (s *Shaper) Conn(c net.Conn) net.Conn {
+	return &shapedConn{Conn: c, s: s, closed: make(chan struct{})}
+}
+
+// dial wraps dial so new connections pay one round trip of latency and are
+// shaped from then on.
+func (s *Shaper) dial(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if d := s.latency(); d > 0 {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				c.Close()
+				return nil, ctx.Err()
+			}
+			s.delayed(d)
+		}
+		return s.Conn(c), nil
+	}
+}
+
+// latency returns the next latency to inject, jitter applied.
+func (s *Shaper) latency() time.Duration {
+	d := s.profile.Latency
+	if j := s.profile.Jitter; j > 0 {
+		d += time.Duration(rand.Int64N(int64(2*j)+1)) - j
+	}
+	return max(d, 0)
+}
+
+func (s *Shaper) delayed(d time.Duration) {
+	if s.observer != nil {
+		s.observer.ShapedDelay(s.profile.Name, d)
+	}
+}
+
+func (s *Shaper) moved(upload bool, n int, d time.Duration) {
+	if s.observer != nil && n > 0 {
+		s.observer.ShapedIO(s.profile.Name, upload, n, d)
+	}
+}
+
+// bandwidth paces bytes to a fixed rate.  Each transfer is scheduled after
+// the previous one, so concurrent connections share the rate.
+type bandwidth struct {
+	bytesPerSec float64
+	chunk       int // largest transfer scheduled at once
+
+	mu   sync.Mutex
+	next time.Time // when the link is free again
+}
+
+// newBandwidth returns a limiter for kbps, or nil for unlimited.
+func newBandwidth(kbps int64) *bandwidth {
+	if kbps <= 0 {
+		return nil
+	}
+	bps := float64(kbps) * 1000 / 8
+	// Scheduling about 50 ms of data at a time keeps the pacing smooth.
+	return &bandwidth{bytesPerSec: bps, chunk: max(int(bps/20), 512)}
+}
+
+// reserve books n bytes on the link and returns how long the caller must
+// wait for them to pass.
+func (b *bandwidth) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	b.next = b.next.Add(time.Duration(float64(n) / b.bytesPerSec * float64(time.Second)))
+	return b.next.Sub(now)
+}
+
+// shapedConn applies a Shaper to one connection.
+type shapedConn struct {
+	net.Conn
+	s *Shaper
+
+	// wrote is set by a write and cleared by the next read that returns
+	// data, which then pays the round-trip latency.
+	wrote atomic.Bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *shapedConn) Read(p []byte) (int, error) {
+	start := time.Now()
+	if b := c.s.down; b != nil && len(p) > b.chunk {
+		p = p[:b.chunk]
+	}
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if b := c.s.down; b != nil {
+			c.sleep(b.reserve(n))
+		}
+		elapsed := time.Since(start)
+		if c.wrote.Swap(false) {
+			if d := c.s.latency(); d > 0 {
+				c.sleep(d)
+				c.s.delayed(d)
+			}
+		}
+		c.s.moved(false, n, elapsed)
+	}
+	return n, err
+}
+
+func (c *shapedConn) Write(p []byte) (int, error) {
+	start := time.Now()
+	b := c.s.up
+	if b == nil {
+		n, err := c.Conn.Write(p)
+		c.wrote.Store(true)
+		c.s.moved(true, n, time.Since(start))
+		return n, err
+	}
+	var written int
+	var err error
+	for written < len(p) && err == nil {
+		chunk := p[written:min(len(p), written+b.chunk)]
+		c.sleep(b.reserve(len(chunk)))
+		var n int
+		n, err = c.Conn.Write(chunk)
+		written += n
+	}
+	c.wrote.Store(true)
+	c.s.moved(true, written, time.Since(start))
+	return written, err
+}
+
+func (c *shapedConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// sleep waits for d or until the connection is closed.
+func (c *shapedConn) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-c.closed:
+	}
+}
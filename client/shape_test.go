@@ -0,0 +1,138 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// shapeRecorder is a client.ShapeObserver that sums what it is told.
+type shapeRecorder struct {
+	mu       sync.Mutex
+	down, up int
+	delays   []time.Duration
+}
+
+func (r *shapeRecorder) ShapedIO(_ string, upload bool, n int, _ time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if upload {
+		r.up += n
+	} else {
+		r.down += n
+	}
+}
+
+func (r *shapeRecorder) ShapedDelay(_ string, d time.Duration) {
+	r.mu.Lock()
+	r.delays = append(r.delays, d)
+	r.mu.Unlock()
+}
+
+// shapedClient returns a client whose connections are shaped by p.
+func shapedClient(t *testing.T, p client.ShapeProfile, o client.ShapeObserver) *http.Client {
+	t.Helper()
+	opts := client.DefaultTransportOptions()
+	opts.Shaper = client.NewShaper(p, o)
+	c, err := client.NewHTTPClientWithOptions("", 10*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestShaper_ThrottlesDownload(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 40_000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	rec := &shapeRecorder{}
+	// 1600 kbit/s is 200 kB/s, so 40 kB takes about 200 ms.
+	c := shapedClient(t, client.ShapeProfile{Name: "slow", DownloadKbps: 1600}, rec)
+	start := time.Now()
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("download took %s, want at least 150ms", elapsed)
+	}
+	if len(body) != len(payload) {
+		t.Fatalf("got %d bytes, want %d", len(body), len(payload))
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.down < len(payload) || rec.up == 0 {
+		t.Errorf("observer saw %d bytes down, %d up", rec.down, rec.up)
+	}
+}
+
+func TestShaper_ThrottlesUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	// 800 kbit/s is 100 kB/s, so 20 kB takes about 200 ms.
+	c := shapedClient(t, client.ShapeProfile{Name: "slow-up", UploadKbps: 800}, nil)
+	start := time.Now()
+	resp, err := c.Post(srv.URL, "application/octet-stream", bytes.NewReader(make([]byte, 20_000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("upload took %s, want at least 150ms", elapsed)
+	}
+}
+
+func TestShaper_InjectsLatencyPerRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	rec := &shapeRecorder{}
+	c := shapedClient(t, client.ShapeProfile{Name: "far", Latency: 50 * time.Millisecond}, rec)
+	start := time.Now()
+	for range 2 {
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	// One round trip to connect and one per request on the reused
+	// connection.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("two requests took %s, want at least 150ms", elapsed)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.delays) != 3 {
+		t.Errorf("got %d injected delays, want 3", len(rec.delays))
+	}
+}
+
+func TestLookupShapeProfile(t *testing.T) {
+	for _, name := range client.ShapeProfileNames() {
+		p, ok := client.LookupShapeProfile(name)
+		if !ok || p.Name != name || p.DownloadKbps <= 0 {
+			t.Errorf("%s: got %+v, %v", name, p, ok)
+		}
+	}
+	if _, ok := client.LookupShapeProfile("5g"); ok {
+		t.Error("unknown profile should not be found")
+	}
+}
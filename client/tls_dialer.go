@@ -7,6 +7,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http/httptrace"
 
 	utls "github.com/refraction-networking/utls"
 )
@@ -33,7 +34,26 @@ import (
 // tlsCfg may be nil; if provided, its ServerName is used as the SNI hostname
 // (the dialer also derives SNI from the addr argument when tlsCfg.ServerName
 // is empty).
+//
+// The raw TCP connection uses a zero net.Dialer (no dial timeout) and the
+// system resolver; use UTLSDialerWithOptions to apply TransportOptions such as
+// timeouts and resolver overrides.
 func UTLSDialer(helloID utls.ClientHelloID) func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	return utlsDialer(helloID, TransportOptions{})
+}
+
+// UTLSDialerWithOptions is UTLSDialer with the dial timeout, keep-alive, TLS
+// handshake timeout and resolver taken from opts.  SNI is still derived from
+// the host name in addr, so pinning a host to an IP via opts.Resolver.Hosts
+// does not change the ClientHello.
+func UTLSDialerWithOptions(helloID utls.ClientHelloID, opts TransportOptions) func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	return utlsDialer(helloID, opts)
+}
+
+// utlsDialer implements UTLSDialer on top of the raw dial function and TLS
+// handshake timeout described by opts.
+func utlsDialer(helloID utls.ClientHelloID, opts TransportOptions) func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	dial := opts.dialContext()
 	return func(ctx context.Context, network, addr string, tlsCfg *tls.Config) (net.Conn, error) {
 		// Resolve the SNI hostname from the address or from the caller-supplied
 		// TLS config (the http2 layer passes its TLSClientConfig here).
@@ -48,8 +68,7 @@ func UTLSDialer(helloID utls.ClientHelloID) func(ctx context.Context, network, a
 
 		// Establish the raw TCP connection, honouring the context deadline /
 		// cancellation.
-		var d net.Dialer
-		rawConn, err := d.DialContext(ctx, network, addr)
+		rawConn, err := dial(ctx, network, addr)
 		if err != nil {
 			return nil, fmt.Errorf("utls dialer: dial %s: %w", addr, err)
 		}
@@ -57,27 +76,62 @@ func UTLSDialer(helloID utls.ClientHelloID) func(ctx context.Context, network, a
 		// Build the uTLS config.  We deliberately do not copy the caller's
 		// *tls.Config verbatim because many of its fields (CipherSuites,
 		// CurvePreferences, …) are overridden by the ClientHelloSpec anyway.
-		// We only forward the fields that uTLS still respects.
+		// We only forward the fields that uTLS still respects: verification
+		// settings and certificates, taken from opts.TLS first and the
+		// caller's config second.
 		uCfg := &utls.Config{
 			ServerName:         sni,
-			InsecureSkipVerify: tlsCfg != nil && tlsCfg.InsecureSkipVerify, // #nosec G402 – caller-controlled
+			RootCAs:            opts.TLS.RootCAs,
+			Certificates:       utlsCertificates(opts.TLS.Certificates),
+			InsecureSkipVerify: opts.TLS.InsecureSkipVerify || (tlsCfg != nil && tlsCfg.InsecureSkipVerify), // #nosec G402 – caller-controlled
+		}
+		if tlsCfg != nil {
+			if uCfg.RootCAs == nil {
+				uCfg.RootCAs = tlsCfg.RootCAs
+			}
+			if uCfg.Certificates == nil {
+				uCfg.Certificates = utlsCertificates(tlsCfg.Certificates)
+			}
 		}
 
-		// Wrap the TCP connection with a uTLS client.
-		uConn := utls.UClient(rawConn, uCfg, helloID)
-
-		// Apply the ClientHelloSpec for the chosen helloID.  This is where
+		// Build the ClientHelloSpec for the chosen helloID.  This is where
 		// GREASE values are randomised, cipher-suite order is set, and all
 		// extensions (SNI, supported-groups, key-share, ALPN, …) are
 		// configured to match the real browser.
 		spec := buildClientHelloSpec(helloID)
+		id := helloID
+		if opts.Protocol == ProtocolHTTP1 && len(spec.Extensions) > 0 {
+			restrictALPN(&spec, "http/1.1")
+			// uTLS re-applies the stock preset of a named ID during the
+			// handshake; HelloCustom keeps the modified spec.
+			id = utls.HelloCustom
+		}
+
+		// Wrap the TCP connection with a uTLS client and apply the spec.
+		uConn := utls.UClient(rawConn, uCfg, id)
 		if err := uConn.ApplyPreset(&spec); err != nil {
 			_ = rawConn.Close()
 			return nil, fmt.Errorf("utls dialer: apply preset for %s: %w", helloID.Str(), err)
 		}
 
-		// Perform the TLS handshake.
-		if err := uConn.HandshakeContext(ctx); err != nil {
+		// Perform the TLS handshake, bounded by the handshake timeout.
+		hsCtx := ctx
+		if opts.TLSHandshakeTimeout > 0 {
+			var cancel context.CancelFunc
+			hsCtx, cancel = context.WithTimeout(ctx, opts.TLSHandshakeTimeout)
+			defer cancel()
+		}
+		// net/http only reports handshakes it performs itself, so emit the
+		// httptrace events here to keep request timings complete.
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.TLSHandshakeStart != nil {
+			trace.TLSHandshakeStart()
+		}
+		err = uConn.HandshakeContext(hsCtx)
+		if trace != nil && trace.TLSHandshakeDone != nil {
+			trace.TLSHandshakeDone(traceConnectionState(uConn), err)
+		}
+		if err != nil {
 			_ = uConn.Close()
 			return nil, fmt.Errorf("utls dialer: TLS handshake with %s: %w", addr, err)
 		}
@@ -86,6 +140,32 @@ func UTLSDialer(helloID utls.ClientHelloID) func(ctx context.Context, network, a
 	}
 }
 
+// restrictALPN replaces the protocols offered in spec's ALPN extension.  The
+// rest of the ClientHello is left untouched.
+func restrictALPN(spec *utls.ClientHelloSpec, protos ...string) {
+	for _, ext := range spec.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = protos
+		}
+	}
+}
+
+// traceConnectionState converts the uTLS connection state into the
+// crypto/tls form expected by httptrace.  Only fields with a direct
+// equivalent are copied.
+func traceConnectionState(uConn *utls.UConn) tls.ConnectionState {
+	st := uConn.ConnectionState()
+	return tls.ConnectionState{
+		Version:            st.Version,
+		HandshakeComplete:  st.HandshakeComplete,
+		DidResume:          st.DidResume,
+		CipherSuite:        st.CipherSuite,
+		NegotiatedProtocol: st.NegotiatedProtocol,
+		ServerName:         st.ServerName,
+		PeerCertificates:   st.PeerCertificates,
+	}
+}
+
 // UTLSDialerHTTP1 is identical to UTLSDialer but returns a function whose
 // signature matches http.Transport.DialTLSContext, which does not receive a
 // *tls.Config argument (the SNI is derived solely from the addr parameter).
@@ -0,0 +1,196 @@
+package client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// testPKI is a throwaway CA with one server and one client certificate,
+// written to PEM files in a temporary directory.
+type testPKI struct {
+	caFile, certFile, keyFile string
+	pool                      *x509.CertPool
+	server                    tls.Certificate
+}
+
+func newTestPKI(t *testing.T, serverName string) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, dns []string) ([]byte, *ecdsa.PrivateKey) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "leaf"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     dns,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der, key
+	}
+	writePEM := func(name, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	p := &testPKI{pool: x509.NewCertPool()}
+	p.pool.AddCert(caCert)
+	p.caFile = writePEM("ca.pem", "CERTIFICATE", caDER)
+
+	srvDER, srvKey := issue(2, x509.ExtKeyUsageServerAuth, []string{serverName})
+	p.server = tls.Certificate{Certificate: [][]byte{srvDER}, PrivateKey: srvKey}
+
+	cliDER, cliKey := issue(3, x509.ExtKeyUsageClientAuth, nil)
+	keyDER, _ := x509.MarshalECPrivateKey(cliKey)
+	p.certFile = writePEM("client.pem", "CERTIFICATE", cliDER)
+	p.keyFile = writePEM("client-key.pem", "EC PRIVATE KEY", keyDER)
+	return p
+}
+
+// startMTLSServer serves HTTPS with the PKI's server certificate and requires
+// a client certificate issued by the same CA.
+func startMTLSServer(t *testing.T, p *testPKI) string {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "no client cert", http.StatusUnauthorized)
+		}
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{p.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    p.pool,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	return port
+}
+
+func mtlsOptions(t *testing.T, p *testPKI, withCert bool) client.TransportOptions {
+	t.Helper()
+	pool, err := client.LoadCertPool(p.caFile)
+	if err != nil {
+		t.Fatalf("LoadCertPool: %v", err)
+	}
+	opts := client.DefaultTransportOptions()
+	opts.Resolver.Hosts = map[string][]string{"mtls.example": {"127.0.0.1"}}
+	opts.TLS.RootCAs = pool
+	if withCert {
+		cert, err := client.LoadClientCertificate(p.certFile, p.keyFile)
+		if err != nil {
+			t.Fatalf("LoadClientCertificate: %v", err)
+		}
+		opts.TLS.Certificates = []tls.Certificate{cert}
+	}
+	return opts
+}
+
+func TestTLSOptions_MutualTLS(t *testing.T) {
+	p := newTestPKI(t, "mtls.example")
+	url := "https://mtls.example:" + startMTLSServer(t, p) + "/"
+
+	build := map[string]func(client.TransportOptions) (*http.Client, error){
+		"standard": func(o client.TransportOptions) (*http.Client, error) {
+			return client.NewHTTPClientWithOptions("", 5*time.Second, o)
+		},
+		"utls": func(o client.TransportOptions) (*http.Client, error) {
+			return client.NewHTTPClientWithTLSOptions("", 5*time.Second, utls.HelloChrome_120, o)
+		},
+	}
+	for name, newClient := range build {
+		t.Run(name, func(t *testing.T) {
+			c, err := newClient(mtlsOptions(t, p, true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Get(url)
+			if err != nil {
+				t.Fatalf("GET with client certificate: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status: got %d, want 200", resp.StatusCode)
+			}
+
+			c, err = newClient(mtlsOptions(t, p, false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp, err := c.Get(url); err == nil {
+				resp.Body.Close()
+				t.Error("server requiring a client certificate should reject the request")
+			}
+		})
+	}
+}
+
+func TestTLSOptions_UnknownCAIsRejected(t *testing.T) {
+	p := newTestPKI(t, "mtls.example")
+	url := "https://mtls.example:" + startMTLSServer(t, p) + "/"
+
+	opts := mtlsOptions(t, p, true)
+	opts.TLS.RootCAs = nil // system roots do not know the test CA
+	c, err := client.NewHTTPClientWithOptions("", 5*time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := c.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("server certificate from a private CA should fail verification without ca_files")
+	}
+}
+
+func TestLoadCertPool_RejectsNonPEM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bogus.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.LoadCertPool(path); err == nil {
+		t.Error("expected an error for a file without certificates")
+	}
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings is the per-phase breakdown of one HTTP request, collected with
+// net/http/httptrace.
+//
+// When the session uses a proxy, DNS and Connect describe the hop to the
+// proxy, while TLSHandshake (for https targets) is the handshake with the
+// origin through the CONNECT tunnel.  A reused connection skips DNS, Connect
+// and TLSHandshake, leaving them zero.
+type Timings struct {
+	// DNS is the time spent resolving the host name.
+	DNS time.Duration
+
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+
+	// TLSHandshake is the time spent in the TLS handshake (crypto/tls or
+	// uTLS).
+	TLSHandshake time.Duration
+
+	// TTFB (time to first byte) runs from the start of the request to the
+	// first byte of the response, including any connection setup.
+	TTFB time.Duration
+
+	// BodyRead runs from the first response byte until the body has been
+	// read to EOF or closed.
+	BodyRead time.Duration
+
+	// Total runs from the start of the request until the body has been read
+	// to EOF or closed (or until RoundTrip failed).
+	Total time.Duration
+
+	// DNSCached reports whether the host name was answered by a host
+	// override or the DNS cache of ResolverOptions instead of a fresh
+	// query.  It is false when no ResolverOptions are in effect.
+	DNSCached bool
+
+	// Reused reports whether the request was sent on a pooled connection.
+	Reused bool
+
+	// WasIdle reports whether a reused connection came from the idle pool.
+	WasIdle bool
+
+	// RemoteAddr is the address of the peer (proxy or origin) the request
+	// was sent to, if a connection was obtained.
+	RemoteAddr string
+
+	// Proto is the protocol of the response, e.g. "HTTP/1.1" or
+	// "HTTP/2.0".  It is empty when the request failed.
+	Proto string
+}
+
+// Trace records Timings for a single request.  Create one with TraceRequest,
+// send the returned request, then pass the outcome to Finish.  All methods
+// are safe for concurrent use: httptrace hooks may fire from dialing
+// goroutines while the caller reads the body.
+type Trace struct {
+	mu sync.Mutex
+
+	start     time.Time
+	dnsStart  time.Time
+	connStart time.Time
+	tlsStart  time.Time
+	firstByte time.Time
+	finished  bool
+
+	t Timings
+}
+
+// TraceRequest returns a shallow copy of req whose context carries an
+// httptrace.ClientTrace feeding a new Trace.  The clock starts now.
+func TraceRequest(req *http.Request) (*http.Request, *Trace) {
+	tr := &Trace{start: time.Now()}
+	ct := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tr.mark(&tr.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.since(&tr.dnsStart, &tr.t.DNS)
+		},
+		ConnectStart: func(string, string) {
+			tr.mark(&tr.connStart)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				tr.since(&tr.connStart, &tr.t.Connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			tr.mark(&tr.tlsStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				tr.since(&tr.tlsStart, &tr.t.TLSHandshake)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.mu.Lock()
+			tr.t.Reused = info.Reused
+			tr.t.WasIdle = info.WasIdle
+			if info.Conn != nil {
+				tr.t.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+			tr.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tr.mu.Lock()
+			tr.firstByte = time.Now()
+			tr.t.TTFB = tr.firstByte.Sub(tr.start)
+			tr.mu.Unlock()
+		},
+	}
+	ctx := context.WithValue(req.Context(), traceKey{}, tr)
+	return req.WithContext(httptrace.WithClientTrace(ctx, ct)), tr
+}
+
+// traceKey is the context key under which TraceRequest stores its Trace, so
+// the dialer can report what httptrace has no field for.
+type traceKey struct{}
+
+// markDNSCached sets Timings.DNSCached.
+func (tr *Trace) markDNSCached() {
+	tr.mu.Lock()
+	tr.t.DNSCached = true
+	tr.mu.Unlock()
+}
+
+// Finish completes the trace with the outcome of the round trip.  On error
+// the trace is closed immediately; otherwise resp.Body is wrapped so that
+// BodyRead and Total are recorded when the body reaches EOF or is closed.
+func (tr *Trace) Finish(resp *http.Response, err error) {
+	if err != nil || resp == nil {
+		tr.finish()
+		return
+	}
+	tr.mu.Lock()
+	tr.t.Proto = resp.Proto
+	tr.mu.Unlock()
+	resp.Body = &tracedBody{ReadCloser: resp.Body, tr: tr}
+}
+
+// Timings returns a copy of the timings recorded so far.  BodyRead and Total
+// are zero until the response body has been consumed or closed.
+func (tr *Trace) Timings() Timings {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.t
+}
+
+// Done reports whether the trace has been completed, i.e. whether Total is
+// final.
+func (tr *Trace) Done() bool {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.finished
+}
+
+func (tr *Trace) mark(at *time.Time) {
+	tr.mu.Lock()
+	// Keep the first start when several addresses are attempted.
+	if at.IsZero() {
+		*at = time.Now()
+	}
+	tr.mu.Unlock()
+}
+
+func (tr *Trace) since(at *time.Time, d *time.Duration) {
+	tr.mu.Lock()
+	if !at.IsZero() {
+		*d = time.Since(*at)
+	}
+	tr.mu.Unlock()
+}
+
+func (tr *Trace) finish() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.finished {
+		return
+	}
+	tr.finished = true
+	now := time.Now()
+	if !tr.firstByte.IsZero() {
+		tr.t.BodyRead = now.Sub(tr.firstByte)
+	}
+	tr.t.Total = now.Sub(tr.start)
+}
+
+// tracedBody completes its Trace on EOF, read error or Close.
+type tracedBody struct {
+	io.ReadCloser
+	tr *Trace
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.tr.finish()
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.tr.finish()
+	return err
+}
@@ -0,0 +1,77 @@
+package client_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+func tracedGet(t *testing.T, c *http.Client, url string) client.Timings {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, tr := client.TraceRequest(req)
+	resp, err := c.Do(req)
+	tr.Finish(resp, err)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if tr.Done() {
+		t.Error("trace should stay open until the body is consumed")
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if !tr.Done() {
+		t.Error("trace should be done after the body is closed")
+	}
+	return tr.Timings()
+}
+
+func TestTraceRequest_NewAndReusedConnection(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	c := srv.Client()
+
+	first := tracedGet(t, c, srv.URL)
+	if first.Reused {
+		t.Error("first request should use a new connection")
+	}
+	if first.Connect <= 0 || first.TLSHandshake <= 0 {
+		t.Errorf("new connection should record connect and TLS: %+v", first)
+	}
+	if first.TTFB <= 0 || first.Total < first.TTFB {
+		t.Errorf("TTFB/Total inconsistent: %+v", first)
+	}
+	if first.RemoteAddr == "" {
+		t.Error("RemoteAddr should be recorded")
+	}
+
+	second := tracedGet(t, c, srv.URL)
+	if !second.Reused {
+		t.Error("second request should reuse the pooled connection")
+	}
+	if second.Connect != 0 || second.TLSHandshake != 0 {
+		t.Errorf("reused connection should skip connect and TLS: %+v", second)
+	}
+}
+
+func TestTraceRequest_FinishOnError(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:1", nil)
+	req, tr := client.TraceRequest(req)
+	resp, err := http.DefaultClient.Do(req)
+	tr.Finish(resp, err)
+	if err == nil {
+		resp.Body.Close()
+		t.Skip("port 1 unexpectedly accepted a connection")
+	}
+	if !tr.Done() || tr.Timings().Total <= 0 {
+		t.Errorf("failed request should finish the trace: %+v", tr.Timings())
+	}
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// TransportOptions groups the connection-level knobs applied to every
+// transport built by this package (NewHTTPClientWithOptions,
+// NewHTTPClientWithTLSOptions and NewChrome120H2Transport).
+//
+// Zero values keep net/http's meaning for the corresponding field: a zero
+// pool size means "no limit" and a zero timeout means "no timeout".  Start
+// from DefaultTransportOptions and override individual fields rather than
+// building the struct from scratch.
+type TransportOptions struct {
+	// MaxIdleConns caps idle (keep-alive) connections across all hosts.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost caps idle connections to a single host.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost caps idle + active connections to a single host.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout evicts connections that have been idle this long.
+	IdleConnTimeout time.Duration
+
+	// TLSHandshakeTimeout aborts TLS handshakes that take longer than this.
+	// It applies to both the crypto/tls and the uTLS dial paths.
+	TLSHandshakeTimeout time.Duration
+
+	// DialTimeout bounds TCP connection establishment.
+	DialTimeout time.Duration
+
+	// KeepAlive is the TCP keep-alive probe interval for new connections.
+	// A negative value disables keep-alive probes.
+	KeepAlive time.Duration
+
+	// EnableHTTP2 lets the standard transport negotiate HTTP/2 via ALPN.
+	// It has no effect on uTLS transports, whose protocol is fixed by the
+	// ClientHello and the transport type.
+	EnableHTTP2 bool
+
+	// DisableDecompression turns off transparent decoding of gzip, deflate,
+	// br and zstd response bodies (see NewDecompressingTransport).
+	DisableDecompression bool
+
+	// MaxDecodedBodySize caps the decoded size of a compressed response
+	// body.  Zero selects DefaultMaxDecodedBodySize; negative disables the
+	// cap.
+	MaxDecodedBodySize int64
+
+	// Resolver customises host-name resolution for both the standard and
+	// the uTLS dial paths.  The zero value uses the system resolver.
+	Resolver ResolverOptions
+
+	// Protocol selects HTTP/1.1, HTTP/2 or cleartext HTTP/2 (h2c).  Empty
+	// or ProtocolAuto negotiates according to EnableHTTP2.
+	Protocol Protocol
+
+	// TLS supplies custom root CAs, client certificates (mTLS) and
+	// verification settings for both the crypto/tls and uTLS handshakes.
+	TLS TLSOptions
+
+	// Dial, when set, sends every connection to this fixed address instead
+	// of the request URL's host, e.g. "unix:///var/run/app.sock" for a
+	// sidecar on a Unix domain socket or "tcp://10.0.0.7:8080".  The URL
+	// still supplies the Host header, TLS server name and cookie domain.
+	// A fixed address bypasses any proxy.  See ParseDialAddr.
+	Dial string
+
+	// Recorder, when set, records every request and response to a
+	// cassette, labelled with RecordTag.  See NewRecordingTransport.
+	Recorder  *Recorder
+	RecordTag string
+
+	// Replay, when set, answers every request from recorded interactions
+	// instead of the network.  See Replayer.
+	Replay *Replayer
+
+	// Shaper, when set, throttles bandwidth and injects latency on every
+	// TCP connection.  Clients built from copies of these options share
+	// its bandwidth.  See NewShaper.
+	Shaper *Shaper
+
+	// Chaos, when set, injects the faults of its rules into requests.
+	// See NewChaos.
+	Chaos *Chaos
+
+	// Breakers, when set, short-circuits requests to hosts (or targets)
+	// whose circuit breaker is open.  See NewBreakers.
+	Breakers *Breakers
+}
+
+// DefaultTransportOptions returns the tuning used when callers do not supply
+// explicit options.  The pool sizes are sized for ~500 concurrent sessions
+// hitting a single origin.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		MaxIdleConns:        500,
+		MaxIdleConnsPerHost: 100,
+		MaxConnsPerHost:     200,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DialTimeout:         30 * time.Second,
+		KeepAlive:           30 * time.Second,
+		EnableHTTP2:         true,
+		MaxDecodedBodySize:  DefaultMaxDecodedBodySize,
+	}
+}
+
+// isZero reports whether o is the zero value (no options supplied).
+func (o TransportOptions) isZero() bool {
+	return reflect.ValueOf(o).IsZero()
+}
+
+// wrap layers the response-processing round trippers selected by o on top of
+// base.  Every constructor in this package passes its transport through wrap
+// so all clients behave identically.
+//
+// A Replayer replaces base entirely; injected faults sit directly above the
+// network (or the replayer) so truncated bodies reach the decoder as they
+// would from a dropped connection, and the recorder sits above the decoder
+// so it sees decoded bodies.  Circuit breakers sit outermost so
+// short-circuited requests are neither sent nor recorded.
+func (o TransportOptions) wrap(base http.RoundTripper) http.RoundTripper {
+	rt := base
+	if o.Replay != nil {
+		rt = o.Replay
+	}
+	if o.Chaos != nil {
+		rt = NewChaosTransport(rt, o.Chaos)
+	}
+	if !o.DisableDecompression {
+		rt = NewDecompressingTransport(rt, o.MaxDecodedBodySize)
+	}
+	if o.Recorder != nil {
+		rt = NewRecordingTransport(rt, o.Recorder, o.RecordTag)
+	}
+	if o.Breakers != nil {
+		rt = NewBreakerTransport(rt, o.Breakers)
+	}
+	return rt
+}
+
+// dialFunc matches http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialContext returns the raw (pre-TLS) dial function described by o.  An
+// invalid o.Dial yields a function that always fails; constructors report it
+// up front through ParseDialAddr.  A Shaper wraps the raw connection, so TLS
+// handshakes are shaped too.
+func (o TransportOptions) dialContext() dialFunc {
+	d := &net.Dialer{
+		Timeout:   o.DialTimeout,
+		KeepAlive: o.KeepAlive,
+	}
+	dial := dialFunc(d.DialContext)
+	if o.Dial != "" {
+		network, address, err := ParseDialAddr(o.Dial)
+		if err != nil {
+			return func(context.Context, string, string) (net.Conn, error) { return nil, err }
+		}
+		if network != "unix" && o.Resolver.enabled() {
+			dial = o.Resolver.dialContext(dial)
+		}
+		dial = fixedDial(dial, network, address)
+	} else if o.Resolver.enabled() {
+		dial = o.Resolver.dialContext(dial)
+	}
+	if o.Shaper != nil {
+		dial = o.Shaper.dial(dial)
+	}
+	return dial
+}
@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+func TestNewHTTPClientWithOptions_AppliesOptions(t *testing.T) {
+	opts := client.DefaultTransportOptions()
+	opts.MaxIdleConns = 7
+	opts.MaxIdleConnsPerHost = 3
+	opts.MaxConnsPerHost = 5
+	opts.IdleConnTimeout = 11 * time.Second
+	opts.TLSHandshakeTimeout = 2 * time.Second
+	opts.EnableHTTP2 = false
+
+	c, err := client.NewHTTPClientWithOptions("", time.Second, opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithOptions: %v", err)
+	}
+	tr := baseTransport(t, c.Transport)
+	if tr.MaxIdleConns != 7 || tr.MaxIdleConnsPerHost != 3 || tr.MaxConnsPerHost != 5 {
+		t.Errorf("pool sizes: got %d/%d/%d, want 7/3/5", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.MaxConnsPerHost)
+	}
+	if tr.IdleConnTimeout != 11*time.Second || tr.TLSHandshakeTimeout != 2*time.Second {
+		t.Errorf("timeouts: got idle=%v tls=%v", tr.IdleConnTimeout, tr.TLSHandshakeTimeout)
+	}
+	if tr.ForceAttemptHTTP2 {
+		t.Error("ForceAttemptHTTP2 should follow EnableHTTP2=false")
+	}
+	if tr.DialContext == nil {
+		t.Error("DialContext should be set from DialTimeout/KeepAlive")
+	}
+}
+
+func TestNewHTTPClient_UsesDefaults(t *testing.T) {
+	c, err := client.NewHTTPClient("", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def := client.DefaultTransportOptions()
+	tr := baseTransport(t, c.Transport)
+	if tr.MaxIdleConns != def.MaxIdleConns || tr.MaxConnsPerHost != def.MaxConnsPerHost {
+		t.Errorf("NewHTTPClient should use DefaultTransportOptions, got %d/%d", tr.MaxIdleConns, tr.MaxConnsPerHost)
+	}
+	if !tr.ForceAttemptHTTP2 {
+		t.Error("default transport should attempt HTTP/2")
+	}
+}
+
+func TestNewHTTPClientWithTLSOptions_AppliesOptions(t *testing.T) {
+	opts := client.DefaultTransportOptions()
+	opts.MaxConnsPerHost = 9
+
+	c, err := client.NewHTTPClientWithTLSOptions("", time.Second, utls.HelloChrome_120, opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientWithTLSOptions: %v", err)
+	}
+	tr := baseTransport(t, c.Transport)
+	if tr.MaxConnsPerHost != 9 {
+		t.Errorf("MaxConnsPerHost: got %d, want 9", tr.MaxConnsPerHost)
+	}
+	if tr.DialTLSContext == nil {
+		t.Error("uTLS DialTLSContext should be wired in")
+	}
+}
+
+// baseTransport unwraps the response-processing layers around rt and returns
+// the underlying *http.Transport.
+func baseTransport(t *testing.T, rt http.RoundTripper) *http.Transport {
+	t.Helper()
+	for {
+		switch v := rt.(type) {
+		case *http.Transport:
+			return v
+		case interface{ Unwrap() http.RoundTripper }:
+			rt = v.Unwrap()
+		default:
+			t.Fatalf("Transport: got %T, want *http.Transport", rt)
+			return nil
+		}
+	}
+}
+
+func TestNewHTTPClientWithOptions_DisableDecompression(t *testing.T) {
+	opts := client.DefaultTransportOptions()
+	opts.DisableDecompression = true
+
+	c, err := client.NewHTTPClientWithOptions("", time.Second, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Transport.(*http.Transport); !ok {
+		t.Errorf("DisableDecompression: got %T, want bare *http.Transport", c.Transport)
+	}
+}
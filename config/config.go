@@ -1,75 +1,620 @@
 // Package config provides production-grade configuration management for GoSessionEngine.
-// It supports JSON-based configuration loading with safe defaults optimized for high concurrency.
+// It supports JSON and YAML configuration files with safe defaults optimized
+// for high concurrency, plus GSE_* environment-variable and CLI-flag overrides
+// layered on top (see Load).
 package config
 
 import (
 	"encoding/json"
-	"fmt"
-	"os"
 	"time"
 )
 
 // Config holds all tunable parameters for the session engine.
-// The struct is designed to be loaded once at startup and then shared across
-// goroutines as a read-only value, making it inherently thread-safe after
-// initialization. Fields cover HTTP transport tuning, session limits, and
-// proxy configuration.
+// A Config value is treated as an immutable snapshot once it is shared across
+// goroutines: runtime changes go through a Store, which builds a new Config
+// and swaps it in atomically.  Fields cover HTTP transport tuning, session
+// limits, and proxy configuration.
+//
+// Fields tagged `reload:"restart"` are baked into long-lived resources at
+// startup; a Store rejects reloads that change them.
 type Config struct {
 	// NumberOfSessions controls how many independent sessions the engine
 	// will maintain concurrently. Keep this <= 2000 for safe operation.
+	// Changing it at runtime grows or shrinks the session pool.
 	NumberOfSessions int `json:"number_of_sessions"`
 
 	// RequestTimeout is the end-to-end timeout for a single HTTP request,
 	// including connection setup, TLS handshake, sending the request body,
-	// and reading the full response. Use time.Duration JSON encoding
-	// (e.g. "30s", "1m").
-	RequestTimeout time.Duration `json:"request_timeout"`
+	// and reading the full response. Written as a duration string
+	// (e.g. "30s", "1m"); integer nanoseconds are also accepted.
+	RequestTimeout Duration `json:"request_timeout" reload:"restart"`
 
 	// MaxRetries is the number of times a failed request will be retried
 	// before the session marks it as a permanent failure.
 	MaxRetries int `json:"max_retries"`
 
-	// TargetURL is the base URL the engine will interact with.
+	// TargetURL is the base URL the engine will interact with.  It is a
+	// shorthand for a single GET target and is ignored when Targets is set.
 	TargetURL string `json:"target_url"`
 
+	// Targets lists the endpoints the default job exercises.  Each
+	// iteration picks one target at random, weighted by Target.Weight.
+	Targets []Target `json:"targets"`
+
 	// ProxyFile is the path to a newline-delimited file containing proxy
 	// addresses (host:port or scheme://host:port). Leave empty to run
-	// without proxies.
+	// without proxies.  Reloading it only affects sessions created
+	// afterwards.
 	ProxyFile string `json:"proxy_file"`
 
 	// MaxIdleConns is the total maximum number of idle (keep-alive)
 	// connections across all hosts in the HTTP transport pool.
 	// A higher value reduces connection setup overhead at the cost of
 	// memory. Defaults to 500 for high-throughput scenarios.
-	MaxIdleConns int `json:"max_idle_conns"`
+	MaxIdleConns int `json:"max_idle_conns" reload:"restart"`
 
 	// MaxIdleConnsPerHost caps idle connections to a single host.
 	// Setting this close to NumberOfSessions avoids connection churn
 	// when all sessions target the same host.
-	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host"`
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host" reload:"restart"`
 
 	// MaxConnsPerHost limits the total number of connections (idle +
 	// active) to a single host. This prevents a runaway host from
 	// exhausting all available file descriptors.
-	MaxConnsPerHost int `json:"max_conns_per_host"`
+	MaxConnsPerHost int `json:"max_conns_per_host" reload:"restart"`
+
+	// IdleConnTimeout evicts keep-alive connections that have been idle for
+	// this long, so dead sockets closed by servers or proxies are not reused.
+	IdleConnTimeout Duration `json:"idle_conn_timeout" reload:"restart"`
+
+	// TLSHandshakeTimeout aborts TLS handshakes that stall, protecting
+	// against servers that accept TCP but never finish the TLS exchange.
+	TLSHandshakeTimeout Duration `json:"tls_handshake_timeout" reload:"restart"`
+
+	// DialTimeout bounds TCP connection establishment.
+	DialTimeout Duration `json:"dial_timeout" reload:"restart"`
+
+	// KeepAlive is the TCP keep-alive probe interval for new connections.
+	// A negative value disables keep-alive probes.
+	KeepAlive Duration `json:"keep_alive" reload:"restart"`
+
+	// EnableHTTP2 lets session transports negotiate HTTP/2 with servers
+	// that offer it.  Disable to force HTTP/1.1.
+	EnableHTTP2 bool `json:"enable_http2" reload:"restart"`
+
+	// MaxDecodedBodySize caps, in bytes, the decompressed size of a gzip,
+	// deflate, br or zstd response body, guarding against decompression
+	// bombs.  Negative disables the cap.
+	MaxDecodedBodySize int64 `json:"max_decoded_body_size" reload:"restart"`
+
+	// DNSHosts pins host names to fixed IP addresses (like curl --resolve),
+	// e.g. {"api.example.com": ["10.0.0.5"]}.
+	DNSHosts map[string][]string `json:"dns_hosts,omitempty" reload:"restart"`
+
+	// DNSServer is the address of a DNS server ("1.1.1.1" or
+	// "1.1.1.1:53") queried instead of the system resolver.
+	DNSServer string `json:"dns_server" reload:"restart"`
+
+	// IPVersion restricts connections to IPv4 (4) or IPv6 (6).  Zero allows
+	// both.
+	IPVersion int `json:"ip_version" reload:"restart"`
+
+	// DNSCacheTTL enables the DNS cache and caps how long an answer is kept.
+	// Zero disables caching.
+	DNSCacheTTL Duration `json:"dns_cache_ttl" reload:"restart"`
+
+	// Protocol selects the HTTP version sessions speak: "auto" (ALPN, see
+	// EnableHTTP2), "h1", "h2", "h2c", "h3" (HTTP/3 with TCP fallback) or
+	// "h3-altsvc" (HTTP/3 after Alt-Svc discovery).  Targets may override
+	// it.
+	Protocol string `json:"protocol" reload:"restart"`
+
+	// TLS holds custom CA bundles and mTLS client certificates applied to
+	// every session.  Targets may override it.
+	TLS TLSConfig `json:"tls" reload:"restart"`
+
+	// Record writes the traffic of selected sessions to a cassette file
+	// that ReplayFile can serve later.
+	Record RecordConfig `json:"record" reload:"restart"`
+
+	// ReplayFile is a cassette (JSONL or HAR) whose recorded responses are
+	// served instead of contacting the targets.  Requests are matched by
+	// method, URL and body.
+	ReplayFile string `json:"replay_file,omitempty" reload:"restart"`
+
+	// History keeps each session's recent HTTP exchanges for HAR export.
+	History HistoryConfig `json:"history" reload:"restart"`
+
+	// Shaping throttles session bandwidth and adds latency to emulate slow
+	// networks such as mobile links.
+	Shaping ShapingConfig `json:"shaping" reload:"restart"`
+
+	// Chaos injects faults into session requests to exercise retry and
+	// error handling.  Injection can be toggled from the dashboard.
+	Chaos ChaosConfig `json:"chaos" reload:"restart"`
+
+	// Results streams one record per request to files or webhooks for
+	// offline analysis.
+	Results ResultsConfig `json:"results" reload:"restart"`
+
+	// Feeders load rows of test data from CSV or JSONL files for targets
+	// to render into their requests; see Target.Feeder.
+	Feeders []Feeder `json:"feeders,omitempty" reload:"restart"`
+
+	// CircuitBreaker stops sending requests to hosts or targets that keep
+	// failing until they have had time to recover.
+	CircuitBreaker BreakerConfig `json:"circuit_breaker" reload:"restart"`
+
+	// RateLimit caps the engine-wide rate at which the scheduler dispatches
+	// jobs, in jobs per second.  Zero disables the limit.
+	RateLimit float64 `json:"rate_limit"`
+
+	// LogLevel is the minimum level written by the logger: "debug",
+	// "info" or "error".  Empty means "info".
+	LogLevel string `json:"log_level"`
 }
 
-// LoadConfig reads a JSON file at filename and deserialises it into a Config.
-// It returns an error if the file cannot be opened or if the JSON is malformed.
-// The returned *Config is ready to use; zero-value fields retain Go's zero
-// values, so callers should validate required fields after loading.
-func LoadConfig(filename string) (*Config, error) {
-	f, err := os.Open(filename) // #nosec G304 – filename is caller-provided config path
-	if err != nil {
-		return nil, fmt.Errorf("config: open %q: %w", filename, err)
+// Target describes one request the default job can send.
+type Target struct {
+	// Name identifies the target in logs and per-target metrics.  Must be
+	// unique within Config.Targets.
+	Name string `json:"name"`
+
+	// Method is the HTTP method.  Defaults to GET.
+	Method string `json:"method,omitempty"`
+
+	// URL is the absolute http or https URL to request, or a Unix socket
+	// URL of the form unix:///path.sock[:/request/path].
+	URL string `json:"url"`
+
+	// Headers are set on every request to this target, overriding session
+	// headers with the same name.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body is an inline request body.  Mutually exclusive with BodyFile.
+	Body string `json:"body,omitempty"`
+
+	// BodyFile is the path of a file whose contents are sent as the request
+	// body.  It is read once when the target set is built.
+	BodyFile string `json:"body_file,omitempty"`
+
+	// ExpectStatus lists the status codes counted as success.  When empty,
+	// any 2xx or 3xx status is a success.
+	ExpectStatus []int `json:"expect_status,omitempty"`
+
+	// Checks are assertions every response must also pass to count as a
+	// success.  Each check's outcome is counted by name.
+	Checks []Check `json:"checks,omitempty"`
+
+	// Weight is the relative probability of picking this target.  Defaults
+	// to 1 when omitted.
+	Weight int `json:"weight,omitempty"`
+
+	// Protocol overrides the session-level protocol for this target.
+	Protocol string `json:"protocol,omitempty"`
+
+	// TLS overrides the session-level TLS settings for this target.  Each
+	// non-empty field replaces its session-level counterpart.
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Dial sends this target's connections to a fixed address instead of
+	// the URL host: "unix:///path.sock" or "tcp://host:port".  A URL of the
+	// form unix:///path.sock[:/request/path] sets it implicitly.
+	Dial string `json:"dial,omitempty"`
+
+	// Redirects sets how this target's redirects are followed.  When nil,
+	// up to 10 redirects are followed.
+	Redirects *RedirectConfig `json:"redirects,omitempty"`
+
+	// GRPC turns the target into a gRPC call.  URL then names the server:
+	// http://host:port for plaintext, https://host:port for TLS.
+	GRPC *GRPCTarget `json:"grpc,omitempty"`
+
+	// Feeder names the entry of Config.Feeders that supplies a row of data
+	// to every request.  The URL, header values and body are Go
+	// text/templates whenever they contain "{{"; the row is available as
+	// .Row, e.g. {{.Row.username}}.
+	Feeder string `json:"feeder,omitempty"`
+}
+
+// Check is one named response assertion.  Exactly one of Status, Header,
+// BodyMatch, JSONPath, MaxLatency and MaxBodySize must be set.
+type Check struct {
+	// Name identifies the check in metrics and reports.  Must be unique
+	// within the target.
+	Name string `json:"name"`
+
+	// Status lists the accepted status codes.
+	Status []int `json:"status,omitempty"`
+
+	// Header requires the named response header.  With HeaderMatch, one
+	// of its values must also match that regular expression.
+	Header      string `json:"header,omitempty"`
+	HeaderMatch string `json:"header_match,omitempty"`
+
+	// BodyMatch is a regular expression the body must match.
+	BodyMatch string `json:"body_match,omitempty"`
+
+	// JSONPath selects a value in a JSON body, e.g. "$.items[0].id"; the
+	// check fails if there is none.  With Equals, the value must also equal
+	// that JSON value.
+	JSONPath string          `json:"json_path,omitempty"`
+	Equals   json.RawMessage `json:"equals,omitempty"`
+
+	// MaxLatency caps the request's total duration, body read included.
+	MaxLatency Duration `json:"max_latency,omitempty"`
+
+	// MaxBodySize caps the response body size in bytes.
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+}
+
+// Feeder is a file of test data handed out row by row to targets.
+type Feeder struct {
+	// Name identifies the feeder in Target.Feeder.
+	Name string `json:"name"`
+
+	// File is a CSV file with a header row, or a JSONL file with one
+	// object per line.
+	File string `json:"file"`
+
+	// Format is "csv" or "jsonl".  Empty picks CSV for a .csv file and
+	// JSONL otherwise.
+	Format string `json:"format,omitempty"`
+
+	// Strategy is "sequential" (the default: each row once, in order),
+	// "random", "unique" (one row per session, by session ID) or
+	// "circular" (in order, starting over after the last row).
+	Strategy string `json:"strategy,omitempty"`
+
+	// OnExhausted is "stop" (the default: the target sends no more
+	// requests once the rows run out) or "recycle" (start over).  It
+	// applies to the sequential and unique strategies.
+	OnExhausted string `json:"on_exhausted,omitempty"`
+}
+
+// RedirectConfig is a target's redirect policy.
+type RedirectConfig struct {
+	// Mode is "follow" (the default), "none" to return the first redirect
+	// response, or "same-host" to follow only redirects that keep the
+	// original host name.
+	Mode string `json:"mode,omitempty"`
+
+	// MaxHops caps the redirects followed.  Zero means 10.
+	MaxHops int `json:"max_hops,omitempty"`
+}
+
+// GRPCTarget describes a unary or server-streaming gRPC call.
+type GRPCTarget struct {
+	// Method is the full method name, "package.Service/Method".
+	Method string `json:"method"`
+
+	// DescriptorSet is a FileDescriptorSet file describing the service,
+	// as written by protoc --descriptor_set_out --include_imports.  When
+	// empty, the method is looked up through server reflection.
+	DescriptorSet string `json:"descriptor_set,omitempty"`
+
+	// Request is the request message in protobuf JSON form.  It is a Go
+	// text/template, rendered for every call.  Empty sends an empty
+	// message.  Mutually exclusive with RequestFile.
+	Request string `json:"request,omitempty"`
+
+	// RequestFile is the path of a file holding the request template.
+	RequestFile string `json:"request_file,omitempty"`
+
+	// Metadata is sent with every call.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TLSConfig describes certificate material for HTTPS connections.
+type TLSConfig struct {
+	// CAFiles are PEM bundles of private root CAs trusted in addition to
+	// the system roots.
+	CAFiles []string `json:"ca_files,omitempty"`
+
+	// ClientCerts are mTLS certificate/key pairs.  Sessions are assigned
+	// one each, round-robin by session ID.
+	ClientCerts []ClientCert `json:"client_certs,omitempty"`
+
+	// InsecureSkipVerify disables server certificate verification.  Only
+	// use it against test servers.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// RecordConfig selects the sessions whose traffic is recorded and where
+// the cassette is written.
+type RecordConfig struct {
+	// File is the cassette path.  Empty disables recording.
+	File string `json:"file,omitempty"`
+
+	// Format is "jsonl" or "har".  Empty picks HAR for a .har file and
+	// JSONL otherwise.
+	Format string `json:"format,omitempty"`
+
+	// Sessions lists the IDs of the recorded sessions.  Empty records
+	// every session.
+	Sessions []int `json:"sessions,omitempty"`
+
+	// MaxBodyBytes caps the response body bytes recorded per interaction.
+	// Zero records whole bodies.
+	MaxBodyBytes int `json:"max_body_bytes"`
+}
+
+// HistoryConfig sizes the per-session request history.
+type HistoryConfig struct {
+	// Size is the number of exchanges each session keeps.  Zero disables
+	// the history.
+	Size int `json:"size"`
+
+	// MaxBodyBytes caps the request and response body bytes kept per
+	// exchange.
+	MaxBodyBytes int `json:"max_body_bytes"`
+
+	// RedactHeaders names headers whose values are replaced in the
+	// history, in addition to Authorization, Cookie, Set-Cookie and the
+	// other built-in sensitive headers.
+	RedactHeaders []string `json:"redact_headers,omitempty"`
+}
+
+// ShapingConfig assigns network profiles to sessions.
+type ShapingConfig struct {
+	// Profiles are assigned to sessions round-robin by session ID.  Each
+	// names a built-in profile ("gprs", "2g", "3g", "4g", "dsl", "wifi") or
+	// a key of Custom.  Empty disables shaping.
+	Profiles []string `json:"profiles,omitempty"`
+
+	// Custom defines additional profiles by name.  A custom profile
+	// replaces a built-in one of the same name.
+	Custom map[string]ShapeProfile `json:"custom,omitempty"`
+}
+
+// ShapeProfile describes the emulated network of a custom profile.
+type ShapeProfile struct {
+	// DownloadKbps and UploadKbps cap bandwidth in kilobits per second.
+	// Zero means unlimited.
+	DownloadKbps int64 `json:"download_kbps,omitempty"`
+	UploadKbps   int64 `json:"upload_kbps,omitempty"`
+
+	// Latency is added once per round trip; Jitter varies it by up to
+	// ±Jitter.
+	Latency Duration `json:"latency,omitempty"`
+	Jitter  Duration `json:"jitter,omitempty"`
+}
+
+// ChaosConfig lists the fault-injection rules shared by all sessions.
+type ChaosConfig struct {
+	// Enabled starts injection switched on.  When false the rules are
+	// loaded but inactive until enabled from the dashboard.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Rules are evaluated in order; the first matching rule that hits
+	// decides the request's fault.
+	Rules []FaultRule `json:"rules,omitempty"`
+}
+
+// FaultRule is one fault-injection rule.
+type FaultRule struct {
+	// Name identifies the rule in the dashboard.  Names are unique.
+	Name string `json:"name"`
+
+	// URL is a pattern matched against the full request URL, in which *
+	// matches any run of characters.  Empty matches every request.
+	URL string `json:"url,omitempty"`
+
+	// Fault is "reset", "timeout", "delay", "status" or "truncate".
+	Fault string `json:"fault"`
+
+	// Probability is the chance in [0, 1] that a matching request is
+	// hit; 0 never hits.  Omitted means always.
+	Probability *float64 `json:"probability,omitempty"`
+
+	// Every, when positive, hits every Every-th matching request instead
+	// of choosing at random.
+	Every int `json:"every,omitempty"`
+
+	// Delay is the wait of delay faults and the hang of timeout faults.
+	Delay Duration `json:"delay,omitempty"`
+
+	// Status is the response code of status faults.  Zero means 503.
+	Status int `json:"status,omitempty"`
+
+	// TruncateAfter is the number of body bytes truncate faults deliver.
+	TruncateAfter int `json:"truncate_after,omitempty"`
+
+	// Disabled starts the rule switched off.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// BreakerConfig configures the circuit breakers shared by all sessions.  A
+// request fails, for a breaker, when it returns an error or a 5xx response.
+type BreakerConfig struct {
+	// Enabled turns the breakers on.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Key is "host" for one breaker per request host or "target" for one
+	// per target.  Empty means "host".
+	Key string `json:"key,omitempty"`
+
+	// Window is the period over which a closed breaker counts requests
+	// and failures.  Zero means 10s.
+	Window Duration `json:"window,omitempty"`
+
+	// MinRequests is the number of requests a window needs before it can
+	// open the breaker.  Zero means 20.
+	MinRequests int `json:"min_requests,omitempty"`
+
+	// FailureRate in (0, 1] opens the breaker once reached.  Zero means
+	// 0.5.
+	FailureRate float64 `json:"failure_rate,omitempty"`
+
+	// CoolDown is how long an open breaker short-circuits requests before
+	// probing the host again.  Zero means 30s.
+	CoolDown Duration `json:"cool_down,omitempty"`
+
+	// HalfOpenRequests is the number of probe requests that must succeed
+	// to close the breaker.  Zero means 1.
+	HalfOpenRequests int `json:"half_open_requests,omitempty"`
+}
+
+// ResultsConfig lists the sinks that receive per-request records.
+type ResultsConfig struct {
+	// Sinks receive every record.  Empty disables per-request results.
+	Sinks []ResultSink `json:"sinks,omitempty"`
+
+	// BufferSize is the number of records queued for the sinks before new
+	// records are dropped.  Zero means 10000.
+	BufferSize int `json:"buffer_size,omitempty"`
+
+	// FlushInterval bounds how long a record waits before it is written.
+	// Zero means 1s.
+	FlushInterval Duration `json:"flush_interval,omitempty"`
+}
+
+// ResultSink is one destination for per-request records.
+type ResultSink struct {
+	// Type is "jsonl", "csv" or "webhook".
+	Type string `json:"type"`
+
+	// Path is the output file of jsonl and csv sinks.
+	Path string `json:"path,omitempty"`
+
+	// MaxBytes and MaxAge rotate the file once it reaches the size or
+	// age; MaxFiles caps the rotated files kept.  Zero disables each.
+	MaxBytes int64    `json:"max_bytes,omitempty"`
+	MaxAge   Duration `json:"max_age,omitempty"`
+	MaxFiles int      `json:"max_files,omitempty"`
+
+	// URL receives webhook batches as JSON arrays.
+	URL string `json:"url,omitempty"`
+
+	// Headers are added to every webhook POST.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Timeout bounds each webhook POST.  Zero means 10s.
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// ClientCert is one PEM certificate/key pair.
+type ClientCert struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+// clone returns a deep copy of c.
+func (c TLSConfig) clone() TLSConfig {
+	c.CAFiles = append([]string(nil), c.CAFiles...)
+	c.ClientCerts = append([]ClientCert(nil), c.ClientCerts...)
+	return c
+}
+
+// EffectiveTargets returns Targets, or – when Targets is empty and TargetURL
+// is set – a single GET target named "default" for TargetURL.  It returns nil
+// when neither is configured.
+func (c *Config) EffectiveTargets() []Target {
+	if len(c.Targets) > 0 {
+		return c.Targets
+	}
+	if c.TargetURL != "" {
+		return []Target{{Name: "default", Method: "GET", URL: c.TargetURL, Weight: 1}}
 	}
-	defer f.Close()
+	return nil
+}
+
+// Clone returns a deep copy of c, suitable for modification before being
+// applied through Store.Update.
+func (c *Config) Clone() *Config {
+	out := *c
+	if c.Targets != nil {
+		out.Targets = make([]Target, len(c.Targets))
+		for i, t := range c.Targets {
+			out.Targets[i] = t.clone()
+		}
+	}
+	out.TLS = c.TLS.clone()
+	out.Record.Sessions = append([]int(nil), c.Record.Sessions...)
+	out.History.RedactHeaders = append([]string(nil), c.History.RedactHeaders...)
+	out.Shaping.Profiles = append([]string(nil), c.Shaping.Profiles...)
+	out.Chaos.Rules = append([]FaultRule(nil), c.Chaos.Rules...)
+	out.Feeders = append([]Feeder(nil), c.Feeders...)
+	if c.Results.Sinks != nil {
+		out.Results.Sinks = make([]ResultSink, len(c.Results.Sinks))
+		for i, rs := range c.Results.Sinks {
+			if rs.Headers != nil {
+				h := make(map[string]string, len(rs.Headers))
+				for k, v := range rs.Headers {
+					h[k] = v
+				}
+				rs.Headers = h
+			}
+			out.Results.Sinks[i] = rs
+		}
+	}
+	if c.Shaping.Custom != nil {
+		out.Shaping.Custom = make(map[string]ShapeProfile, len(c.Shaping.Custom))
+		for name, p := range c.Shaping.Custom {
+			out.Shaping.Custom[name] = p
+		}
+	}
+	if c.DNSHosts != nil {
+		out.DNSHosts = make(map[string][]string, len(c.DNSHosts))
+		for host, addrs := range c.DNSHosts {
+			out.DNSHosts[host] = append([]string(nil), addrs...)
+		}
+	}
+	return &out
+}
+
+// clone returns a deep copy of t.
+func (t Target) clone() Target {
+	if t.Headers != nil {
+		h := make(map[string]string, len(t.Headers))
+		for k, v := range t.Headers {
+			h[k] = v
+		}
+		t.Headers = h
+	}
+	if t.ExpectStatus != nil {
+		t.ExpectStatus = append([]int(nil), t.ExpectStatus...)
+	}
+	if t.Checks != nil {
+		checks := make([]Check, len(t.Checks))
+		for i, c := range t.Checks {
+			c.Status = append([]int(nil), c.Status...)
+			c.Equals = append(json.RawMessage(nil), c.Equals...)
+			checks[i] = c
+		}
+		t.Checks = checks
+	}
+	if t.TLS != nil {
+		tc := t.TLS.clone()
+		t.TLS = &tc
+	}
+	if t.Redirects != nil {
+		r := *t.Redirects
+		t.Redirects = &r
+	}
+	if t.GRPC != nil {
+		g := *t.GRPC
+		if g.Metadata != nil {
+			g.Metadata = make(map[string]string, len(t.GRPC.Metadata))
+			for k, v := range t.GRPC.Metadata {
+				g.Metadata[k] = v
+			}
+		}
+		t.GRPC = &g
+	}
+	return t
+}
 
+// LoadConfig reads a JSON or YAML file at filename and deserialises it into a
+// Config.  Files ending in ".yaml" or ".yml" are parsed as YAML; everything
+// else is parsed as JSON.  It returns an error if the file cannot be opened,
+// is malformed, or contains unknown keys.  Zero-value fields retain Go's zero
+// values, so callers should call Validate after loading.  Use Load to merge
+// the file over DefaultConfig together with env and flag overrides.
+func LoadConfig(filename string) (*Config, error) {
 	var cfg Config
-	dec := json.NewDecoder(f)
-	dec.DisallowUnknownFields() // catch typos in config files early
-	if err := dec.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("config: decode %q: %w", filename, err)
+	if _, err := decodeFile(filename, &cfg); err != nil {
+		return nil, err
 	}
 	return &cfg, nil
 }
@@ -82,12 +627,23 @@ func LoadConfig(filename string) (*Config, error) {
 func DefaultConfig() *Config {
 	return &Config{
 		NumberOfSessions:    500,
-		RequestTimeout:      30 * time.Second,
+		RequestTimeout:      Duration(30 * time.Second),
 		MaxRetries:          3,
 		TargetURL:           "",
 		ProxyFile:           "",
 		MaxIdleConns:        500,
 		MaxIdleConnsPerHost: 100,
 		MaxConnsPerHost:     200,
+		IdleConnTimeout:     Duration(90 * time.Second),
+		TLSHandshakeTimeout: Duration(10 * time.Second),
+		DialTimeout:         Duration(30 * time.Second),
+		KeepAlive:           Duration(30 * time.Second),
+		EnableHTTP2:         true,
+		MaxDecodedBodySize:  64 << 20,
+		Protocol:            "auto",
+		Record:              RecordConfig{MaxBodyBytes: 1 << 20},
+		History:             HistoryConfig{Size: 16, MaxBodyBytes: 1024},
+		RateLimit:           0,
+		LogLevel:            "info",
 	}
 }
@@ -1,8 +1,14 @@
 package config_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,3 +86,540 @@ func TestLoadConfig_InvalidJSON(t *testing.T) {
 		t.Error("expected error for invalid JSON, got nil")
 	}
 }
+
+func writeTemp(t *testing.T, pattern, content string) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return f.Name()
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	path := writeTemp(t, "config*.yaml", "number_of_sessions: 7\ntarget_url: http://example.com\n")
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.NumberOfSessions != 7 {
+		t.Errorf("got NumberOfSessions=%d, want 7", cfg.NumberOfSessions)
+	}
+	if cfg.TargetURL != "http://example.com" {
+		t.Errorf("got TargetURL=%q, want http://example.com", cfg.TargetURL)
+	}
+}
+
+func TestLoadConfig_YAMLUnknownField(t *testing.T) {
+	path := writeTemp(t, "config*.yml", "number_of_sesions: 7\n")
+
+	if _, err := config.LoadConfig(path); err == nil {
+		t.Error("expected error for unknown YAML key, got nil")
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeTemp(t, "config*.json", `{"number_of_sessions": 10, "max_retries": 5, "target_url": "http://file.example"}`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	overrides := config.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-max-retries", "9"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, src, err := config.Load(config.Options{
+		File: path,
+		Environ: []string{
+			"GSE_TARGET_URL=http://env.example",
+			"GSE_MAX_RETRIES=7",
+			"GSE_REQUEST_TIMEOUT=5s",
+			"UNRELATED=1",
+		},
+		Flags: overrides,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		key  string
+		got  interface{}
+		want interface{}
+		src  config.Source
+	}{
+		{"number_of_sessions", cfg.NumberOfSessions, 10, config.SourceFile},
+		{"target_url", cfg.TargetURL, "http://env.example", config.SourceEnv},
+		{"request_timeout", cfg.RequestTimeout, config.Duration(5 * time.Second), config.SourceEnv},
+		{"max_retries", cfg.MaxRetries, 9, config.SourceFlag},
+		{"max_idle_conns", cfg.MaxIdleConns, config.DefaultConfig().MaxIdleConns, config.SourceDefault},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.key, c.got, c.want)
+		}
+		if src[c.key] != c.src {
+			t.Errorf("%s: got source %q, want %q", c.key, src[c.key], c.src)
+		}
+	}
+}
+
+func TestLoad_InvalidEnv(t *testing.T) {
+	_, _, err := config.Load(config.Options{Environ: []string{"GSE_NUMBER_OF_SESSIONS=many"}})
+	if err == nil {
+		t.Error("expected error for non-numeric env override, got nil")
+	}
+}
+
+func TestRegisterFlags_RejectsBadValue(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	config.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-request-timeout", "soon"}); err == nil {
+		t.Error("expected parse error for malformed duration flag, got nil")
+	}
+}
+
+func TestWriteEffective(t *testing.T) {
+	cfg, src, err := config.Load(config.Options{Environ: []string{"GSE_MAX_RETRIES=4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := config.WriteEffective(&buf, cfg, src); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"max_retries", "4", "env", "request_timeout", "30s", "default"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestLoadConfig_DurationString(t *testing.T) {
+	path := writeTemp(t, "config*.json", `{"request_timeout": "1m30s"}`)
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.RequestTimeout.Std(); got != 90*time.Second {
+		t.Errorf("got RequestTimeout=%v, want 1m30s", got)
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	for _, in := range []string{`"30s"`, `30000000000`, `"30000000000"`} {
+		var d config.Duration
+		if err := json.Unmarshal([]byte(in), &d); err != nil {
+			t.Errorf("Unmarshal(%s): %v", in, err)
+			continue
+		}
+		if d.Std() != 30*time.Second {
+			t.Errorf("Unmarshal(%s) = %v, want 30s", in, d)
+		}
+	}
+
+	var d config.Duration
+	if err := json.Unmarshal([]byte(`"thirty seconds"`), &d); err == nil {
+		t.Error("expected error for malformed duration, got nil")
+	}
+
+	out, err := json.Marshal(config.Duration(2 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `"2m0s"` {
+		t.Errorf("Marshal = %s, want \"2m0s\"", out)
+	}
+}
+
+// wantPaths fails t unless err is a *config.ValidationError whose field
+// paths are exactly paths, in order.
+func wantPaths(t *testing.T, err error, paths ...string) {
+	t.Helper()
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T (%v)", err, err)
+	}
+	if len(verr.Errors) != len(paths) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(verr.Errors), len(paths), err)
+	}
+	for i, path := range paths {
+		if verr.Errors[i].Path != path {
+			t.Errorf("error %d: got path %q, want %q", i, verr.Errors[i].Path, path)
+		}
+	}
+}
+
+func TestValidate_DefaultIsValid(t *testing.T) {
+	if err := config.DefaultConfig().Validate(); err != nil {
+		t.Errorf("DefaultConfig should be valid, got: %v", err)
+	}
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	cfg := &config.Config{
+		NumberOfSessions:    0,
+		RequestTimeout:      0,
+		MaxRetries:          -1,
+		TargetURL:           "ftp://example.com",
+		ProxyFile:           "/nonexistent/proxies.txt",
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 20,
+		MaxConnsPerHost:     -5,
+	}
+	wantPaths(t, cfg.Validate(),
+		"number_of_sessions",
+		"request_timeout",
+		"max_retries",
+		"target_url",
+		"proxy_file",
+		"max_idle_conns_per_host",
+		"max_conns_per_host",
+	)
+}
+
+func TestValidate_ProxyFileExists(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ProxyFile = writeTemp(t, "proxies*.txt", "http://127.0.0.1:8080\n")
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_DNS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DNSHosts = map[string][]string{"api.example.com": {"10.0.0.5", "::1"}}
+	cfg.DNSServer = "1.1.1.1:53"
+	cfg.IPVersion = 6
+	cfg.DNSCacheTTL = config.Duration(time.Minute)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("valid DNS settings rejected: %v", err)
+	}
+
+	cfg.DNSHosts = map[string][]string{"b.example": {"not-an-ip"}, "a.example": nil}
+	cfg.IPVersion = 5
+	cfg.DNSCacheTTL = config.Duration(-time.Second)
+	wantPaths(t, cfg.Validate(), `dns_hosts["a.example"]`, `dns_hosts["b.example"]`, "ip_version", "dns_cache_ttl")
+}
+
+func TestValidate_TLS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TLS.CAFiles = []string{"/nonexistent/ca.pem"}
+	cfg.TLS.ClientCerts = []config.ClientCert{{CertFile: writeTemp(t, "cert*.pem", "x")}}
+	cfg.Targets = []config.Target{{
+		Name: "a", URL: "https://example.com/",
+		TLS: &config.TLSConfig{ClientCerts: []config.ClientCert{{KeyFile: writeTemp(t, "key*.pem", "x")}}},
+	}}
+	wantPaths(t, cfg.Validate(),
+		"targets[0].tls.client_certs[0].cert_file",
+		"tls.ca_files[0]",
+		"tls.client_certs[0].key_file",
+	)
+}
+
+func TestValidate_Protocol(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Protocol = "spdy"
+	cfg.Targets = []config.Target{
+		{Name: "grpc", URL: "http://127.0.0.1:8080/", Protocol: "h2c"},
+		{Name: "bad-h2c", URL: "https://example.com/", Protocol: "h2c"},
+		{Name: "bad-h2", URL: "http://example.com/", Protocol: "h2"},
+		{Name: "quic", URL: "https://example.com/", Protocol: "h3"},
+		{Name: "bad-h3", URL: "http://example.com/", Protocol: "h3-altsvc"},
+	}
+	wantPaths(t, cfg.Validate(), "targets[1].protocol", "targets[2].protocol", "targets[4].protocol", "protocol")
+}
+
+func TestValidate_HTTP3WithProxy(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ProxyFile = writeTemp(t, "proxies*.txt", "http://127.0.0.1:3128\n")
+	cfg.Protocol = "h3"
+	cfg.Targets = []config.Target{
+		{Name: "inherit", URL: "https://api.internal/"},
+		{Name: "h1", URL: "https://api.internal/", Protocol: "h1"},
+		{Name: "quic", URL: "https://api.internal/", Protocol: "h3-altsvc"},
+	}
+	wantPaths(t, cfg.Validate(), "protocol", "targets[2].protocol")
+}
+
+func TestValidate_SessionHTTP3WithDialAndUnix(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Protocol = "h3"
+	cfg.Targets = []config.Target{
+		{Name: "dial", URL: "https://api.internal/", Dial: "tcp://10.0.0.7:443"},
+		{Name: "sock", URL: "unix:///var/run/app.sock:/health"},
+		{Name: "h1-dial", URL: "https://api.internal/", Dial: "tcp://10.0.0.7:443", Protocol: "h1"},
+		{Name: "h1-sock", URL: "unix:///var/run/app.sock:/health", Protocol: "h1"},
+	}
+	wantPaths(t, cfg.Validate(), "targets[0].dial", "targets[1].url")
+}
+
+func TestValidate_Dial(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = []config.Target{
+		{Name: "sock", URL: "unix:///var/run/app.sock:/health"},
+		{Name: "fixed", URL: "http://api.internal/", Dial: "tcp://10.0.0.7:8080"},
+		{Name: "bad-url", URL: "unix://host/app.sock"},
+		{Name: "both", URL: "unix:///var/run/app.sock", Dial: "unix:///other.sock"},
+		{Name: "bad-dial", URL: "http://api.internal/", Dial: "udp://10.0.0.7:53"},
+		{Name: "quic", URL: "https://api.internal/", Dial: "tcp://10.0.0.7:443", Protocol: "h3"},
+	}
+	wantPaths(t, cfg.Validate(), "targets[2].url", "targets[3].dial", "targets[4].dial", "targets[5].dial")
+}
+
+func TestValidate_GRPC(t *testing.T) {
+	req := writeTemp(t, "req-*.json", `{"pc_id": "a"}`)
+	cfg := config.DefaultConfig()
+	cfg.Targets = []config.Target{
+		{Name: "ok", URL: "http://grpc.internal:50051", GRPC: &config.GRPCTarget{Method: "pb.Svc/Get", RequestFile: req}},
+		{Name: "bad-method", URL: "http://grpc.internal:50051", GRPC: &config.GRPCTarget{Method: "Get"}},
+		{Name: "both", URL: "http://grpc.internal:50051", GRPC: &config.GRPCTarget{Method: "pb.Svc/Get", Request: "{}", RequestFile: req}},
+		{Name: "http-fields", URL: "http://grpc.internal:50051", Method: "POST", GRPC: &config.GRPCTarget{Method: "pb.Svc/Get"}},
+		{Name: "sock", URL: "unix:///var/run/grpc.sock", GRPC: &config.GRPCTarget{Method: "pb.Svc/Get"}},
+		{Name: "no-set", URL: "http://grpc.internal:50051", GRPC: &config.GRPCTarget{Method: "pb.Svc/Get", DescriptorSet: "/does/not/exist.pb"}},
+	}
+	wantPaths(t, cfg.Validate(), "targets[1].grpc.method", "targets[2].grpc.request_file", "targets[3].grpc", "targets[4].url", "targets[5].grpc.descriptor_set")
+}
+
+func TestValidate_Record(t *testing.T) {
+	replay := writeTemp(t, "tape-*.jsonl", "")
+	cfg := config.DefaultConfig()
+	cfg.Record = config.RecordConfig{File: replay, Format: "yaml", Sessions: []int{0, -1}, MaxBodyBytes: -1}
+	cfg.ReplayFile = replay
+	wantPaths(t, cfg.Validate(), "record.format", "record.sessions[1]", "record.max_body_bytes", "record.file")
+
+	cfg = config.DefaultConfig()
+	cfg.Record.Sessions = []int{1}
+	cfg.ReplayFile = "/does/not/exist.jsonl"
+	wantPaths(t, cfg.Validate(), "record.file", "replay_file")
+}
+
+func TestValidate_History(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.History = config.HistoryConfig{Size: -1, MaxBodyBytes: -5, RedactHeaders: []string{"X-Ok", "bad header"}}
+	wantPaths(t, cfg.Validate(), "history.size", "history.max_body_bytes", "history.redact_headers[1]")
+}
+
+func TestValidate_Redirects(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = []config.Target{
+		{Name: "ok", URL: "https://example.com/", Redirects: &config.RedirectConfig{Mode: "same-host", MaxHops: 5}},
+		{Name: "mode", URL: "https://example.com/", Redirects: &config.RedirectConfig{Mode: "always"}},
+		{Name: "none", URL: "https://example.com/", Redirects: &config.RedirectConfig{Mode: "none", MaxHops: 2}},
+		{Name: "hops", URL: "https://example.com/", Redirects: &config.RedirectConfig{MaxHops: -1}},
+	}
+	wantPaths(t, cfg.Validate(), "targets[1].redirects.mode", "targets[2].redirects.max_hops", "targets[3].redirects.max_hops")
+}
+
+func TestValidate_Targets(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = []config.Target{
+		{Name: "ok", Method: "POST", URL: "https://example.com/a", ExpectStatus: []int{200}, Weight: 2},
+		{Name: "ok", URL: "not a url", Body: "x", BodyFile: "/tmp/x", ExpectStatus: []int{42}, Weight: -1},
+		{Method: "BAD METHOD"},
+	}
+	err := cfg.Validate()
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	paths := map[string]bool{}
+	for _, fe := range verr.Errors {
+		paths[fe.Path] = true
+	}
+	for _, want := range []string{
+		"targets[1].name",
+		"targets[1].url",
+		"targets[1].body_file",
+		"targets[1].expect_status[0]",
+		"targets[1].weight",
+		"targets[2].name",
+		"targets[2].method",
+		"targets[2].url",
+	} {
+		if !paths[want] {
+			t.Errorf("missing error for %s; got:\n%v", want, err)
+		}
+	}
+	if paths["targets[0].name"] || paths["targets[0].url"] {
+		t.Errorf("valid target reported as invalid:\n%v", err)
+	}
+}
+
+func TestEffectiveTargets(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if got := cfg.EffectiveTargets(); got != nil {
+		t.Errorf("no target configured: got %v, want nil", got)
+	}
+	cfg.TargetURL = "http://example.com"
+	if got := cfg.EffectiveTargets(); len(got) != 1 || got[0].URL != "http://example.com" || got[0].Method != "GET" {
+		t.Errorf("target_url shorthand: got %+v", got)
+	}
+	cfg.Targets = []config.Target{{Name: "a", URL: "http://a.example"}}
+	if got := cfg.EffectiveTargets(); len(got) != 1 || got[0].Name != "a" {
+		t.Errorf("targets should win over target_url: got %+v", got)
+	}
+
+	clone := cfg.Clone()
+	clone.Targets[0].Name = "changed"
+	if cfg.Targets[0].Name != "a" {
+		t.Error("Clone must deep-copy Targets")
+	}
+}
+
+func TestValidate_Shaping(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Shaping = config.ShapingConfig{
+		Profiles: []string{"3g", "satellite", "5g"},
+		Custom: map[string]config.ShapeProfile{
+			"satellite": {DownloadKbps: 10000, UploadKbps: -1, Latency: config.Duration(600 * time.Millisecond)},
+			"bad":       {DownloadKbps: -1, Latency: config.Duration(10 * time.Millisecond), Jitter: config.Duration(20 * time.Millisecond)},
+		},
+	}
+	wantPaths(t, cfg.Validate(),
+		`shaping.custom["bad"].download_kbps`,
+		`shaping.custom["bad"].jitter`,
+		`shaping.custom["satellite"].upload_kbps`,
+		"shaping.profiles[2]",
+	)
+}
+
+func TestValidate_Chaos(t *testing.T) {
+	tenth, two := 0.1, 2.0
+	cfg := config.DefaultConfig()
+	cfg.Chaos.Rules = []config.FaultRule{
+		{Name: "ok", URL: "*/api/*", Fault: "status", Status: 503, Probability: &tenth},
+		{Name: "ok", Fault: "explode"},
+		{Fault: "delay", Probability: &two},
+		{Name: "neg", Fault: "truncate", Every: -1, TruncateAfter: -1, Status: 42},
+	}
+	wantPaths(t, cfg.Validate(),
+		"chaos.rules[1].name",
+		"chaos.rules[1].fault",
+		"chaos.rules[2].name",
+		"chaos.rules[2].delay",
+		"chaos.rules[2].probability",
+		"chaos.rules[3].every",
+		"chaos.rules[3].status",
+		"chaos.rules[3].truncate_after",
+	)
+}
+
+func TestValidate_Results(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Results = config.ResultsConfig{
+		BufferSize: -1,
+		Sinks: []config.ResultSink{
+			{Type: "jsonl", Path: "/tmp/results.jsonl", MaxBytes: 1 << 20, MaxFiles: 5},
+			{Type: "csv", Path: "/tmp/../tmp/results.jsonl", MaxFiles: -1},
+			{Type: "webhook", URL: "ftp://example.com", Headers: map[string]string{"bad header": "x"}},
+			{Type: "webhook"},
+			{Type: "parquet"},
+		},
+	}
+	wantPaths(t, cfg.Validate(),
+		"results.buffer_size",
+		"results.sinks[1].path",
+		"results.sinks[1].max_files",
+		"results.sinks[2].url",
+		"results.sinks[2].headers",
+		"results.sinks[3].url",
+		"results.sinks[4].type",
+	)
+}
+
+func TestValidate_Feeders(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(data, []byte("username\nalice\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Feeders = []config.Feeder{
+		{Name: "users", File: data, Strategy: "unique", OnExhausted: "recycle"},
+		{Name: "users", File: data, Format: "xml"},
+		{Name: "terms", File: filepath.Join(t.TempDir(), "missing.jsonl"), Strategy: "shuffle"},
+		{Name: "ids", File: data, Strategy: "random", OnExhausted: "stop"},
+		{File: data, OnExhausted: "wrap"},
+	}
+	cfg.Targets = []config.Target{
+		{Name: "login", URL: "https://example.com/login", Feeder: "users"},
+		{Name: "search", URL: "https://example.com/search?q={{.Row.term}}", Feeder: "queries"},
+	}
+	wantPaths(t, cfg.Validate(),
+		"feeders[1].name",
+		"feeders[1].format",
+		"feeders[2].file",
+		"feeders[2].strategy",
+		"feeders[3].on_exhausted",
+		"feeders[4].name",
+		"feeders[4].on_exhausted",
+		"targets[1].feeder",
+	)
+}
+
+func TestValidate_Checks(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Targets = []config.Target{
+		{Name: "api", URL: "https://example.com/api", Checks: []config.Check{
+			{Name: "ok", Status: []int{200, 204}},
+			{Name: "json", Header: "Content-Type", HeaderMatch: "^application/json"},
+			{Name: "id", JSONPath: "$.id", Equals: json.RawMessage(`42`)},
+			{Name: "fast", MaxLatency: config.Duration(time.Second)},
+			{Name: "ok", Status: []int{99}},
+			{Name: "both", BodyMatch: "x", MaxBodySize: 10},
+			{Name: "none"},
+			{Name: "bad", BodyMatch: "(", Equals: json.RawMessage(`1`)},
+			{Name: "path", JSONPath: "id", Equals: json.RawMessage(`{`)},
+			{HeaderMatch: "x", MaxBodySize: -1},
+		}},
+		{Name: "rpc", URL: "http://grpc.example.com:50051", GRPC: &config.GRPCTarget{Method: "pkg.Svc/Call"},
+			Checks: []config.Check{{Name: "ok", Status: []int{200}}}},
+	}
+	wantPaths(t, cfg.Validate(),
+		"targets[0].checks[4].name",
+		"targets[0].checks[4].status[0]",
+		"targets[0].checks[5]",
+		"targets[0].checks[6]",
+		"targets[0].checks[7].body_match",
+		"targets[0].checks[7].equals",
+		"targets[0].checks[8].json_path",
+		"targets[0].checks[8].equals",
+		"targets[0].checks[9].name",
+		"targets[0].checks[9].header_match",
+		"targets[0].checks[9].max_body_size",
+		"targets[1].checks",
+	)
+}
+
+func TestValidate_CircuitBreaker(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.CircuitBreaker = config.BreakerConfig{
+		Enabled:          true,
+		Key:              "path",
+		Window:           config.Duration(-time.Second),
+		MinRequests:      -1,
+		FailureRate:      1.5,
+		CoolDown:         config.Duration(-time.Second),
+		HalfOpenRequests: -1,
+	}
+	wantPaths(t, cfg.Validate(),
+		"circuit_breaker.key",
+		"circuit_breaker.window",
+		"circuit_breaker.min_requests",
+		"circuit_breaker.failure_rate",
+		"circuit_breaker.cool_down",
+		"circuit_breaker.half_open_requests",
+	)
+
+	cfg.CircuitBreaker = config.BreakerConfig{Enabled: true, Key: "target", FailureRate: 0.25}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid breaker config rejected: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration is a time.Duration that can be written in config files, GSE_*
+// environment variables and CLI flags in human-readable form.
+//
+// It decodes from either a Go duration string ("30s", "1m30s", "250ms") or an
+// integer number of nanoseconds, so configs written for the original
+// nanosecond-only encoding keep working.  It always encodes as a duration
+// string, which is also what --print-config shows.
+type Duration time.Duration
+
+// Std returns d as a standard library time.Duration.
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+// String formats d like time.Duration.String (e.g. "30s").
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalText encodes d as a duration string.
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+// UnmarshalText accepts a Go duration string or an integer nanosecond count.
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := string(text)
+	if parsed, err := time.ParseDuration(s); err == nil {
+		*d = Duration(parsed)
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (use e.g. \"30s\", \"1m\" or integer nanoseconds)", s)
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// MarshalJSON encodes d as a JSON string such as "30s".
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+
+// UnmarshalJSON accepts either a JSON string ("30s") or a JSON number of
+// nanoseconds (30000000000).
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return d.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid duration %s (use e.g. \"30s\", \"1m\" or integer nanoseconds)", data)
+	}
+	*d = Duration(n)
+	return nil
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source identifies the configuration layer that supplied a field's value.
+//
+// Layers are applied in the following order, each one overriding the values
+// set by the layers before it:
+//
+//	default < file < env < flag
+//
+// so a CLI flag always wins over an environment variable, which always wins
+// over the config file, which always wins over DefaultConfig.
+type Source string
+
+const (
+	// SourceDefault means the value comes from DefaultConfig.
+	SourceDefault Source = "default"
+	// SourceFile means the value was read from the JSON or YAML config file.
+	SourceFile Source = "file"
+	// SourceEnv means the value was read from a GSE_* environment variable.
+	SourceEnv Source = "env"
+	// SourceFlag means the value was supplied as a command-line flag.
+	SourceFlag Source = "flag"
+)
+
+// EnvPrefix is prepended to the upper-cased JSON key of every Config field to
+// form its environment-variable name (e.g. GSE_NUMBER_OF_SESSIONS).
+const EnvPrefix = "GSE_"
+
+// Sources maps a Config field's JSON key to the layer that last set it.
+type Sources map[string]Source
+
+// FlagOverrides collects the raw values of per-field CLI flags registered by
+// RegisterFlags.  Only flags that were actually passed on the command line
+// are present.
+type FlagOverrides map[string]string
+
+// Options controls which layers Load merges on top of DefaultConfig.
+type Options struct {
+	// File is the path to a JSON or YAML config file.  Files ending in
+	// ".yaml" or ".yml" are parsed as YAML; everything else as JSON.  Leave
+	// empty to skip the file layer.
+	File string
+
+	// Environ is the environment in "KEY=value" form.  A nil slice means
+	// os.Environ(); pass an empty non-nil slice to disable the env layer.
+	Environ []string
+
+	// Flags holds per-field CLI overrides returned by RegisterFlags.
+	Flags FlagOverrides
+}
+
+// Load builds the effective configuration by layering, in order, the
+// defaults, the config file, GSE_* environment variables and CLI flags.
+//
+// The returned Sources records which layer supplied every field so operators
+// can audit the merged result (see WriteEffective).  Load does not validate
+// the merged values.
+func Load(opts Options) (*Config, Sources, error) {
+	cfg := DefaultConfig()
+	src := make(Sources)
+	for _, f := range configFields() {
+		src[f.key] = SourceDefault
+	}
+
+	if opts.File != "" {
+		keys, err := decodeFile(opts.File, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, k := range keys {
+			if _, ok := src[k]; ok {
+				src[k] = SourceFile
+			}
+		}
+	}
+
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			env[k] = v
+		}
+	}
+
+	for _, f := range configFields() {
+		v := reflect.ValueOf(cfg).Elem().Field(f.index)
+		if raw, ok := env[f.envName()]; ok {
+			if err := setFromString(v, raw); err != nil {
+				return nil, nil, fmt.Errorf("config: env %s: %w", f.envName(), err)
+			}
+			src[f.key] = SourceEnv
+		}
+		if raw, ok := opts.Flags[f.key]; ok {
+			if err := setFromString(v, raw); err != nil {
+				return nil, nil, fmt.Errorf("config: flag -%s: %w", f.flagName(), err)
+			}
+			src[f.key] = SourceFlag
+		}
+	}
+
+	return cfg, src, nil
+}
+
+// RegisterFlags defines one flag per Config field on fs (e.g.
+// -number-of-sessions, -request-timeout) and returns the map that Load reads
+// them from once fs has been parsed.  Values are checked at parse time so a
+// malformed flag is reported by the flag package with the usual usage text.
+func RegisterFlags(fs *flag.FlagSet) FlagOverrides {
+	overrides := make(FlagOverrides)
+	for _, f := range configFields() {
+		f := f
+		usage := fmt.Sprintf("override %q (env %s)", f.key, f.envName())
+		fs.Func(f.flagName(), usage, func(raw string) error {
+			var scratch Config
+			if err := setFromString(reflect.ValueOf(&scratch).Elem().Field(f.index), raw); err != nil {
+				return err
+			}
+			overrides[f.key] = raw
+			return nil
+		})
+	}
+	return overrides
+}
+
+// WriteEffective writes a table of every Config field, its effective value
+// and the layer it came from.  It backs the --print-config CLI option.
+func WriteEffective(w io.Writer, cfg *Config, src Sources) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tVALUE\tSOURCE")
+	rv := reflect.ValueOf(cfg).Elem()
+	for _, f := range configFields() {
+		s := src[f.key]
+		if s == "" {
+			s = SourceDefault
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.key, formatValue(rv.Field(f.index)), s)
+	}
+	return tw.Flush()
+}
+
+// decodeFile reads filename into cfg, overwriting only the fields present in
+// the file, and returns the top-level keys that were set.  YAML documents are
+// converted to JSON first so that the json struct tags remain the single
+// source of truth for field names and unknown keys are rejected the same way
+// for both formats.
+func decodeFile(filename string, cfg *Config) ([]string, error) {
+	data, err := os.ReadFile(filename) // #nosec G304 – filename is caller-provided config path
+	if err != nil {
+		return nil, fmt.Errorf("config: open %q: %w", filename, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("config: decode %q: %w", filename, err)
+		}
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("config: decode %q: %w", filename, err)
+		}
+	}
+
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("config: decode %q: %w", filename, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // catch typos in config files early
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("config: decode %q: %w", filename, err)
+	}
+
+	keys := make([]string, 0, len(top))
+	for k := range top {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// configField describes one JSON-tagged field of Config.
+type configField struct {
+	key   string
+	index int
+}
+
+func (f configField) envName() string { return EnvPrefix + strings.ToUpper(f.key) }
+
+func (f configField) flagName() string { return strings.ReplaceAll(f.key, "_", "-") }
+
+// configFields lists every JSON-tagged Config field in declaration order.
+func configFields() []configField {
+	t := reflect.TypeOf(Config{})
+	fields := make([]configField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		fields = append(fields, configField{key: key, index: i})
+	}
+	return fields
+}
+
+// setFromString parses raw into v according to v's type.  Types that
+// implement encoding.TextUnmarshaler (such as Duration) parse themselves;
+// slices, maps and structs are parsed as JSON.
+func setFromString(v reflect.Value, raw string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	default:
+		// Composite fields (lists, nested objects) are supplied as JSON.
+		ptr := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(raw), ptr.Interface()); err != nil {
+			return fmt.Errorf("invalid JSON value: %w", err)
+		}
+		v.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// formatValue renders v for WriteEffective.
+func formatValue(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprintf("%v", v.Interface())
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v.Interface())
+	}
+}
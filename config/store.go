@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the live engine configuration and coordinates hot reloads.
+//
+// Concurrency model:
+//   - The current *Config is published through an atomic.Pointer, so
+//     Current is lock-free and readers always see a complete, validated
+//     snapshot.  Snapshots are never mutated after they are published;
+//     every change builds a new Config and swaps the pointer.
+//   - A sync.Mutex serialises writers (Reload, Update) and subscriber
+//     notification, so subscribers observe changes one at a time and in
+//     order.
+//
+// Fields tagged `reload:"restart"` are baked into long-lived resources (e.g.
+// HTTP transports) at startup.  Changing them through Reload or Update is
+// rejected with a *RestartRequiredError instead of being silently ignored.
+type Store struct {
+	current atomic.Pointer[Config]
+	opts    Options
+
+	mu   sync.Mutex // serialises reloads and guards subs
+	subs map[int]func(prev, next *Config)
+	next int
+}
+
+// RestartRequiredError is returned when a reload or update would change
+// fields that only take effect after a restart.
+type RestartRequiredError struct {
+	// Fields lists the JSON keys of the offending fields.
+	Fields []string
+}
+
+// Error implements the error interface.
+func (e *RestartRequiredError) Error() string {
+	return fmt.Sprintf("config: %s cannot be changed without a restart", strings.Join(e.Fields, ", "))
+}
+
+// NewStore creates a Store whose initial snapshot is cfg.  opts are the layer
+// options used to build cfg; Reload re-runs Load with them so the file, env
+// and flag layers keep the same precedence after a reload.
+func NewStore(cfg *Config, opts Options) *Store {
+	s := &Store{
+		opts: opts,
+		subs: make(map[int]func(prev, next *Config)),
+	}
+	s.current.Store(cfg)
+	return s
+}
+
+// Current returns the live configuration snapshot.  The returned value is
+// shared and must be treated as read-only.  Safe for concurrent use.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// Subscribe registers fn to be called after every successful reload or
+// update with the previous and the new snapshot.  Subscribers run
+// synchronously, one at a time, in registration order; they should return
+// promptly.  The returned function removes the subscription.
+func (s *Store) Subscribe(fn func(prev, next *Config)) (unsubscribe func()) {
+	s.mu.Lock()
+	id := s.next
+	s.next++
+	s.subs[id] = fn
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		delete(s.subs, id)
+		s.mu.Unlock()
+	}
+}
+
+// Reload rebuilds the configuration from the layers the Store was created
+// with (file, env, flags), validates it, and applies it.  On any error the
+// live configuration is left unchanged.
+func (s *Store) Reload() error {
+	next, _, err := Load(s.opts)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apply(next)
+}
+
+// Update applies fn to a copy of the current configuration, validates the
+// result and applies it.  On any error the live configuration is left
+// unchanged.  The whole read-modify-write holds the store's lock, so
+// concurrent updates are applied one after another and none is lost.
+func (s *Store) Update(fn func(c *Config)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.Current().Clone()
+	fn(next)
+	return s.apply(next)
+}
+
+// apply validates next, rejects restart-only changes, swaps it in and
+// notifies subscribers.  s.mu must be held.
+func (s *Store) apply(next *Config) error {
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	prev := s.Current()
+	if fields := restartFields(prev, next); len(fields) > 0 {
+		return &RestartRequiredError{Fields: fields}
+	}
+	if reflect.DeepEqual(prev, next) {
+		return nil
+	}
+	s.current.Store(next)
+
+	ids := make([]int, 0, len(s.subs))
+	for id := range s.subs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		s.subs[id](prev, next)
+	}
+	return nil
+}
+
+// Watch polls the config file every interval and calls Reload whenever its
+// modification time or size changes.  report, if non-nil, receives the
+// result of every triggered reload (nil on success).  Watch blocks until stop
+// is closed; run it in its own goroutine.  It returns immediately if the
+// Store has no config file.
+func (s *Store) Watch(stop <-chan struct{}, interval time.Duration, report func(error)) {
+	if s.opts.File == "" {
+		return
+	}
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(s.opts.File)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+
+	lastMod, lastSize := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			mod, size := stat()
+			if size < 0 || (mod.Equal(lastMod) && size == lastSize) {
+				continue
+			}
+			lastMod, lastSize = mod, size
+			err := s.Reload()
+			if report != nil {
+				report(err)
+			}
+		}
+	}
+}
+
+// restartFields returns the JSON keys of fields tagged `reload:"restart"`
+// whose values differ between old and next.
+func restartFields(old, next *Config) []string {
+	ov := reflect.ValueOf(old).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	t := ov.Type()
+
+	var fields []string
+	for _, f := range configFields() {
+		if t.Field(f.index).Tag.Get("reload") != "restart" {
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(f.index).Interface(), nv.Field(f.index).Interface()) {
+			fields = append(fields, f.key)
+		}
+	}
+	return fields
+}
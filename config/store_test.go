@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/config"
+)
+
+func TestStore_UpdateNotifiesSubscribers(t *testing.T) {
+	store := config.NewStore(config.DefaultConfig(), config.Options{Environ: []string{}})
+
+	var gotPrev, gotNext *config.Config
+	calls := 0
+	store.Subscribe(func(prev, next *config.Config) {
+		calls++
+		gotPrev, gotNext = prev, next
+	})
+
+	before := store.Current()
+	if err := store.Update(func(c *config.Config) { c.MaxRetries = 9 }); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("subscriber called %d times, want 1", calls)
+	}
+	if gotPrev != before || gotPrev.MaxRetries != 3 {
+		t.Errorf("prev snapshot: got %+v", gotPrev)
+	}
+	if gotNext.MaxRetries != 9 || store.Current() != gotNext {
+		t.Errorf("next snapshot not published: got %+v", gotNext)
+	}
+	if before.MaxRetries != 3 {
+		t.Error("previous snapshot must not be mutated")
+	}
+
+	// A no-op update does not notify.
+	if err := store.Update(func(c *config.Config) {}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("no-op update notified subscribers")
+	}
+}
+
+func TestStore_ConcurrentUpdatesAreNotLost(t *testing.T) {
+	store := config.NewStore(config.DefaultConfig(), config.Options{Environ: []string{}})
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Update(func(c *config.Config) { c.MaxRetries++ }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := store.Current().MaxRetries; got != 53 {
+		t.Errorf("MaxRetries: got %d, want 53 after 50 increments", got)
+	}
+}
+
+func TestStore_UpdateRejectsInvalid(t *testing.T) {
+	store := config.NewStore(config.DefaultConfig(), config.Options{Environ: []string{}})
+	store.Subscribe(func(prev, next *config.Config) { t.Error("subscriber must not run for invalid config") })
+
+	err := store.Update(func(c *config.Config) { c.NumberOfSessions = -1 })
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if store.Current().NumberOfSessions != 500 {
+		t.Error("invalid update must leave config unchanged")
+	}
+}
+
+func TestStore_UpdateRejectsRestartFields(t *testing.T) {
+	store := config.NewStore(config.DefaultConfig(), config.Options{Environ: []string{}})
+
+	err := store.Update(func(c *config.Config) {
+		c.MaxIdleConns = 420
+		c.TargetURL = "http://example.com"
+	})
+	var rerr *config.RestartRequiredError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RestartRequiredError, got %v", err)
+	}
+	if len(rerr.Fields) != 1 || rerr.Fields[0] != "max_idle_conns" {
+		t.Errorf("Fields: got %v, want [max_idle_conns]", rerr.Fields)
+	}
+	if store.Current().TargetURL != "" {
+		t.Error("rejected update must not apply any field")
+	}
+}
+
+func TestStore_Reload(t *testing.T) {
+	path := writeTemp(t, "config*.yaml", "max_retries: 2\n")
+	opts := config.Options{File: path, Environ: []string{}}
+	cfg, _, err := config.Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := config.NewStore(cfg, opts)
+
+	if err := os.WriteFile(path, []byte("max_retries: 6\nlog_level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := store.Current(); got.MaxRetries != 6 || got.LogLevel != "debug" {
+		t.Errorf("after reload: max_retries=%d log_level=%q", got.MaxRetries, got.LogLevel)
+	}
+
+	if err := os.WriteFile(path, []byte("request_timeout: 5s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var rerr *config.RestartRequiredError
+	if err := store.Reload(); !errors.As(err, &rerr) {
+		t.Errorf("expected restart-required error, got %v", err)
+	}
+}
+
+func TestStore_Watch(t *testing.T) {
+	path := writeTemp(t, "config*.json", `{"max_retries": 1}`)
+	opts := config.Options{File: path, Environ: []string{}}
+	cfg, _, err := config.Load(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := config.NewStore(cfg, opts)
+
+	var mu sync.Mutex
+	var results []error
+	stop := make(chan struct{})
+	defer close(stop)
+	go store.Watch(stop, 10*time.Millisecond, func(err error) {
+		mu.Lock()
+		results = append(results, err)
+		mu.Unlock()
+	})
+
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"max_retries": 12}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if store.Current().MaxRetries == 12 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if store.Current().MaxRetries != 12 {
+		t.Fatal("watcher did not reload the changed file")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) == 0 || results[len(results)-1] != nil {
+		t.Errorf("report results: %v", results)
+	}
+}
@@ -0,0 +1,738 @@
+package config
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MaxSessions is the upper bound enforced on NumberOfSessions.  Beyond this
+// the engine routinely exhausts default OS file-descriptor limits.
+const MaxSessions = 2000
+
+// MaxRetriesLimit is the upper bound enforced on MaxRetries.
+const MaxRetriesLimit = 100
+
+// MaxRedirectHops is the upper bound enforced on RedirectConfig.MaxHops.
+const MaxRedirectHops = 100
+
+// MaxHistorySize is the upper bound enforced on History.Size.
+const MaxHistorySize = 1000
+
+// FieldError describes a single invalid configuration value.
+type FieldError struct {
+	// Path is the JSON path of the offending field, e.g. "request_timeout"
+	// or "targets[2].url".
+	Path string
+
+	// Message explains what is wrong with the value.
+	Message string
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string { return e.Path + ": " + e.Message }
+
+// ValidationError is returned by Config.Validate and lists every problem found
+// in one pass, so operators can fix a config file without repeated restarts.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error formats all field errors, one per line.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "config: %d invalid field(s):", len(e.Errors))
+	for _, fe := range e.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+// Validate checks every field of c and returns a *ValidationError listing all
+// problems, or nil if the configuration is usable.  It checks numeric ranges,
+// URL syntax and the existence of referenced files.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.NumberOfSessions < 1 || c.NumberOfSessions > MaxSessions {
+		v.addf("number_of_sessions", "must be between 1 and %d (got %d)", MaxSessions, c.NumberOfSessions)
+	}
+	if c.RequestTimeout <= 0 {
+		v.addf("request_timeout", "must be positive (got %s)", c.RequestTimeout)
+	}
+	if c.MaxRetries < 0 || c.MaxRetries > MaxRetriesLimit {
+		v.addf("max_retries", "must be between 0 and %d (got %d)", MaxRetriesLimit, c.MaxRetries)
+	}
+	if c.TargetURL != "" {
+		v.checkTargetURL("target_url", c.TargetURL)
+	}
+	v.checkTargets(c.Targets, c.Protocol)
+	if c.ProxyFile != "" {
+		v.checkFile("proxy_file", c.ProxyFile)
+	}
+	if c.MaxIdleConns < 0 {
+		v.addf("max_idle_conns", "must not be negative (got %d)", c.MaxIdleConns)
+	}
+	if c.MaxIdleConnsPerHost < 0 {
+		v.addf("max_idle_conns_per_host", "must not be negative (got %d)", c.MaxIdleConnsPerHost)
+	} else if c.MaxIdleConns > 0 && c.MaxIdleConnsPerHost > c.MaxIdleConns {
+		v.addf("max_idle_conns_per_host", "must not exceed max_idle_conns (%d > %d)", c.MaxIdleConnsPerHost, c.MaxIdleConns)
+	}
+	if c.MaxConnsPerHost < 0 {
+		v.addf("max_conns_per_host", "must not be negative (got %d)", c.MaxConnsPerHost)
+	}
+	if c.IdleConnTimeout < 0 {
+		v.addf("idle_conn_timeout", "must not be negative (got %s)", c.IdleConnTimeout)
+	}
+	if c.TLSHandshakeTimeout < 0 {
+		v.addf("tls_handshake_timeout", "must not be negative (got %s)", c.TLSHandshakeTimeout)
+	}
+	if c.DialTimeout < 0 {
+		v.addf("dial_timeout", "must not be negative (got %s)", c.DialTimeout)
+	}
+	v.checkDNS(c)
+	v.checkProtocol("protocol", c.Protocol, c.TargetURL)
+	if c.ProxyFile != "" {
+		v.checkHTTP3Proxy(c)
+	}
+	v.checkTLS("tls", &c.TLS)
+	v.checkRecord(c)
+	v.checkHistory(&c.History)
+	v.checkShaping(&c.Shaping)
+	v.checkChaos(&c.Chaos)
+	v.checkResults(&c.Results)
+	v.checkFeeders(c)
+	v.checkBreaker(&c.CircuitBreaker)
+	if c.RateLimit < 0 {
+		v.addf("rate_limit", "must not be negative (got %g)", c.RateLimit)
+	}
+	switch c.LogLevel {
+	case "", "debug", "info", "error":
+	default:
+		v.addf("log_level", "must be one of debug, info, error (got %q)", c.LogLevel)
+	}
+
+	return v.err()
+}
+
+// validator accumulates FieldErrors.
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkRecord validates the record and replay_file fields.
+func (v *validator) checkRecord(c *Config) {
+	r := c.Record
+	switch r.Format {
+	case "", "jsonl", "har":
+	default:
+		v.addf("record.format", "must be jsonl or har (got %q)", r.Format)
+	}
+	if r.File == "" && (r.Format != "" || len(r.Sessions) > 0) {
+		v.addf("record.file", "is required when record.format or record.sessions is set")
+	}
+	for i, id := range r.Sessions {
+		if id < 0 {
+			v.addf(fmt.Sprintf("record.sessions[%d]", i), "must not be negative (got %d)", id)
+		}
+	}
+	if r.MaxBodyBytes < 0 {
+		v.addf("record.max_body_bytes", "must not be negative (got %d)", r.MaxBodyBytes)
+	}
+	if c.ReplayFile != "" {
+		v.checkFile("replay_file", c.ReplayFile)
+		if r.File != "" && filepath.Clean(r.File) == filepath.Clean(c.ReplayFile) {
+			v.addf("record.file", "must differ from replay_file")
+		}
+	}
+}
+
+// checkHistory validates the history section.
+func (v *validator) checkHistory(h *HistoryConfig) {
+	if h.Size < 0 || h.Size > MaxHistorySize {
+		v.addf("history.size", "must be between 0 and %d (got %d)", MaxHistorySize, h.Size)
+	}
+	if h.MaxBodyBytes < 0 {
+		v.addf("history.max_body_bytes", "must not be negative (got %d)", h.MaxBodyBytes)
+	}
+	for i, name := range h.RedactHeaders {
+		if !isToken(name) {
+			v.addf(fmt.Sprintf("history.redact_headers[%d]", i), "%q is not a valid header name", name)
+		}
+	}
+}
+
+// builtinShapeProfiles are the profile names known to client.LookupShapeProfile.
+var builtinShapeProfiles = []string{"gprs", "2g", "3g", "4g", "dsl", "wifi"}
+
+// checkShaping validates the shaping section.
+func (v *validator) checkShaping(s *ShapingConfig) {
+	names := make([]string, 0, len(s.Custom))
+	for name := range s.Custom {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic error order
+	for _, name := range names {
+		p, path := s.Custom[name], fmt.Sprintf("shaping.custom[%q]", name)
+		if name == "" {
+			v.addf(path, "profile name must not be empty")
+		}
+		if p.DownloadKbps < 0 {
+			v.addf(path+".download_kbps", "must not be negative (got %d)", p.DownloadKbps)
+		}
+		if p.UploadKbps < 0 {
+			v.addf(path+".upload_kbps", "must not be negative (got %d)", p.UploadKbps)
+		}
+		if p.Latency < 0 {
+			v.addf(path+".latency", "must not be negative (got %s)", p.Latency)
+		}
+		if p.Jitter < 0 || p.Jitter > p.Latency {
+			v.addf(path+".jitter", "must be between 0 and latency (got %s)", p.Jitter)
+		}
+	}
+	for i, name := range s.Profiles {
+		if _, ok := s.Custom[name]; !ok && !slices.Contains(builtinShapeProfiles, name) {
+			v.addf(fmt.Sprintf("shaping.profiles[%d]", i), "unknown profile %q (built-in: %s)", name, strings.Join(builtinShapeProfiles, ", "))
+		}
+	}
+}
+
+// checkChaos validates the fault-injection rules.
+func (v *validator) checkChaos(c *ChaosConfig) {
+	names := make(map[string]struct{}, len(c.Rules))
+	for i, r := range c.Rules {
+		p := fmt.Sprintf("chaos.rules[%d]", i)
+		if r.Name == "" {
+			v.addf(p+".name", "is required")
+		} else if _, dup := names[r.Name]; dup {
+			v.addf(p+".name", "duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+		switch r.Fault {
+		case "reset", "timeout", "status", "truncate":
+		case "delay":
+			if r.Delay <= 0 {
+				v.addf(p+".delay", "must be positive for delay faults (got %s)", r.Delay)
+			}
+		default:
+			v.addf(p+".fault", "must be one of reset, timeout, delay, status, truncate (got %q)", r.Fault)
+		}
+		if pr := r.Probability; pr != nil && (*pr < 0 || *pr > 1) {
+			v.addf(p+".probability", "must be between 0 and 1 (got %g)", *pr)
+		}
+		if r.Every < 0 {
+			v.addf(p+".every", "must not be negative (got %d)", r.Every)
+		}
+		if r.Delay < 0 {
+			v.addf(p+".delay", "must not be negative (got %s)", r.Delay)
+		}
+		if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+			v.addf(p+".status", "must be a valid HTTP status code (got %d)", r.Status)
+		}
+		if r.TruncateAfter < 0 {
+			v.addf(p+".truncate_after", "must not be negative (got %d)", r.TruncateAfter)
+		}
+	}
+}
+
+// checkBreaker validates the circuit breaker settings.
+func (v *validator) checkBreaker(b *BreakerConfig) {
+	switch b.Key {
+	case "", "host", "target":
+	default:
+		v.addf("circuit_breaker.key", "must be host or target (got %q)", b.Key)
+	}
+	if b.Window < 0 {
+		v.addf("circuit_breaker.window", "must not be negative (got %s)", b.Window)
+	}
+	if b.MinRequests < 0 {
+		v.addf("circuit_breaker.min_requests", "must not be negative (got %d)", b.MinRequests)
+	}
+	if b.FailureRate < 0 || b.FailureRate > 1 {
+		v.addf("circuit_breaker.failure_rate", "must be between 0 and 1 (got %g)", b.FailureRate)
+	}
+	if b.CoolDown < 0 {
+		v.addf("circuit_breaker.cool_down", "must not be negative (got %s)", b.CoolDown)
+	}
+	if b.HalfOpenRequests < 0 {
+		v.addf("circuit_breaker.half_open_requests", "must not be negative (got %d)", b.HalfOpenRequests)
+	}
+}
+
+// checkResults validates the per-request result sinks.
+func (v *validator) checkResults(r *ResultsConfig) {
+	if r.BufferSize < 0 {
+		v.addf("results.buffer_size", "must not be negative (got %d)", r.BufferSize)
+	}
+	if r.FlushInterval < 0 {
+		v.addf("results.flush_interval", "must not be negative (got %s)", r.FlushInterval)
+	}
+	paths := make(map[string]struct{})
+	for i, s := range r.Sinks {
+		p := fmt.Sprintf("results.sinks[%d]", i)
+		switch s.Type {
+		case "jsonl", "csv":
+			if s.Path == "" {
+				v.addf(p+".path", "is required for %s sinks", s.Type)
+			} else {
+				clean := filepath.Clean(s.Path)
+				if _, dup := paths[clean]; dup {
+					v.addf(p+".path", "%q is already written by another sink", s.Path)
+				}
+				paths[clean] = struct{}{}
+			}
+			if s.MaxBytes < 0 {
+				v.addf(p+".max_bytes", "must not be negative (got %d)", s.MaxBytes)
+			}
+			if s.MaxAge < 0 {
+				v.addf(p+".max_age", "must not be negative (got %s)", s.MaxAge)
+			}
+			if s.MaxFiles < 0 {
+				v.addf(p+".max_files", "must not be negative (got %d)", s.MaxFiles)
+			}
+		case "webhook":
+			if s.URL == "" {
+				v.addf(p+".url", "is required for webhook sinks")
+			} else {
+				v.checkHTTPURL(p+".url", s.URL)
+			}
+			names := make([]string, 0, len(s.Headers))
+			for name := range s.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names) // deterministic error order
+			for _, name := range names {
+				if !isToken(name) {
+					v.addf(p+".headers", "%q is not a valid header name", name)
+				}
+			}
+			if s.Timeout < 0 {
+				v.addf(p+".timeout", "must not be negative (got %s)", s.Timeout)
+			}
+		default:
+			v.addf(p+".type", "must be one of jsonl, csv, webhook (got %q)", s.Type)
+		}
+	}
+}
+
+// checkFeeders validates the data feeders and the targets' references to
+// them.
+func (v *validator) checkFeeders(c *Config) {
+	names := make(map[string]struct{}, len(c.Feeders))
+	for i, f := range c.Feeders {
+		p := fmt.Sprintf("feeders[%d]", i)
+		if f.Name == "" {
+			v.addf(p+".name", "is required")
+		} else if _, dup := names[f.Name]; dup {
+			v.addf(p+".name", "duplicate feeder name %q", f.Name)
+		}
+		names[f.Name] = struct{}{}
+		if f.File == "" {
+			v.addf(p+".file", "is required")
+		} else {
+			v.checkFile(p+".file", f.File)
+		}
+		switch f.Format {
+		case "", "csv", "jsonl":
+		default:
+			v.addf(p+".format", "must be csv or jsonl (got %q)", f.Format)
+		}
+		switch f.Strategy {
+		case "", "sequential", "unique":
+		case "random", "circular":
+			if f.OnExhausted != "" {
+				v.addf(p+".on_exhausted", "has no effect with strategy %s", f.Strategy)
+			}
+		default:
+			v.addf(p+".strategy", "must be one of sequential, random, unique, circular (got %q)", f.Strategy)
+		}
+		switch f.OnExhausted {
+		case "", "stop", "recycle":
+		default:
+			v.addf(p+".on_exhausted", "must be stop or recycle (got %q)", f.OnExhausted)
+		}
+	}
+	for i, t := range c.Targets {
+		if t.Feeder == "" {
+			continue
+		}
+		if _, ok := names[t.Feeder]; !ok {
+			v.addf(fmt.Sprintf("targets[%d].feeder", i), "unknown feeder %q", t.Feeder)
+		}
+	}
+}
+
+// checkDNS validates the resolver fields.
+func (v *validator) checkDNS(c *Config) {
+	hosts := make([]string, 0, len(c.DNSHosts))
+	for host := range c.DNSHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts) // deterministic error order
+	for _, host := range hosts {
+		addrs := c.DNSHosts[host]
+		p := fmt.Sprintf("dns_hosts[%q]", host)
+		if host == "" {
+			v.addf(p, "host name must not be empty")
+		}
+		if len(addrs) == 0 {
+			v.addf(p, "must list at least one IP address")
+		}
+		for _, a := range addrs {
+			if net.ParseIP(a) == nil {
+				v.addf(p, "%q is not an IP address", a)
+			}
+		}
+	}
+	if c.DNSServer != "" {
+		host := c.DNSServer
+		if h, _, err := net.SplitHostPort(c.DNSServer); err == nil {
+			host = h
+		}
+		if host == "" {
+			v.addf("dns_server", "invalid address %q", c.DNSServer)
+		}
+	}
+	switch c.IPVersion {
+	case 0, 4, 6:
+	default:
+		v.addf("ip_version", "must be 0, 4 or 6 (got %d)", c.IPVersion)
+	}
+	if c.DNSCacheTTL < 0 {
+		v.addf("dns_cache_ttl", "must not be negative (got %s)", c.DNSCacheTTL)
+	}
+}
+
+// checkProtocol validates a protocol name and, when rawURL is set, that the
+// URL scheme suits it: h2 and h3 need TLS and h2c needs cleartext.
+func (v *validator) checkProtocol(path, proto, rawURL string) {
+	var scheme string
+	if u, err := url.Parse(rawURL); err == nil {
+		scheme = u.Scheme
+	}
+	switch proto {
+	case "", "auto", "h1":
+	case "h2":
+		if scheme == "http" {
+			v.addf(path, "h2 requires an https URL; use h2c for cleartext HTTP/2")
+		}
+	case "h2c":
+		if scheme == "https" {
+			v.addf(path, "h2c requires an http URL; use h2 over TLS")
+		}
+	case "h3", "h3-altsvc":
+		if scheme != "" && scheme != "https" {
+			v.addf(path, "%s requires an https URL", proto)
+		}
+	default:
+		v.addf(path, "must be one of auto, h1, h2, h2c, h3, h3-altsvc (got %q)", proto)
+	}
+}
+
+// checkHTTP3Proxy rejects HTTP/3 protocols when sessions are proxied: QUIC
+// cannot be tunnelled through an HTTP proxy.
+func (v *validator) checkHTTP3Proxy(c *Config) {
+	if isHTTP3(c.Protocol) {
+		v.addf("protocol", "%s cannot be used with proxy_file", c.Protocol)
+	}
+	for i, t := range c.Targets {
+		// Targets inheriting the session protocol are covered above.
+		if t.Protocol != "" && isHTTP3(t.Protocol) {
+			v.addf(fmt.Sprintf("targets[%d].protocol", i), "%s cannot be used with proxy_file", t.Protocol)
+		}
+	}
+}
+
+// isHTTP3 reports whether proto selects an HTTP/3 transport.
+func isHTTP3(proto string) bool {
+	return proto == "h3" || proto == "h3-altsvc"
+}
+
+// checkTLS validates the certificate files referenced by tc.
+func (v *validator) checkTLS(path string, tc *TLSConfig) {
+	for i, f := range tc.CAFiles {
+		v.checkFile(fmt.Sprintf("%s.ca_files[%d]", path, i), f)
+	}
+	for i, cc := range tc.ClientCerts {
+		p := fmt.Sprintf("%s.client_certs[%d]", path, i)
+		if cc.CertFile == "" {
+			v.addf(p+".cert_file", "must not be empty")
+		} else {
+			v.checkFile(p+".cert_file", cc.CertFile)
+		}
+		if cc.KeyFile == "" {
+			v.addf(p+".key_file", "must not be empty")
+		} else {
+			v.checkFile(p+".key_file", cc.KeyFile)
+		}
+	}
+}
+
+// checkTargets validates every entry of targets, using paths of the form
+// "targets[i].field".  sessionProto is the session-level protocol that
+// targets without their own inherit.
+func (v *validator) checkTargets(targets []Target, sessionProto string) {
+	names := make(map[string]int, len(targets))
+	for i, t := range targets {
+		proto := t.Protocol
+		if t.GRPC == nil {
+			proto = cmp.Or(t.Protocol, sessionProto)
+		}
+		p := fmt.Sprintf("targets[%d]", i)
+		switch {
+		case t.Name == "":
+			v.addf(p+".name", "must not be empty")
+		case names[t.Name] > 0:
+			v.addf(p+".name", "duplicate target name %q (also used by targets[%d])", t.Name, names[t.Name]-1)
+		default:
+			names[t.Name] = i + 1
+		}
+		if t.Method != "" && !isToken(t.Method) {
+			v.addf(p+".method", "invalid HTTP method %q", t.Method)
+		}
+		if t.URL == "" {
+			v.addf(p+".url", "must not be empty")
+		} else {
+			v.checkTargetURL(p+".url", t.URL)
+			if t.Protocol == "" && isHTTP3(proto) && strings.HasPrefix(t.URL, "unix:") {
+				// An explicit protocol is reported by checkProtocol below.
+				v.addf(p+".url", "a unix:// URL cannot be used with protocol %s", proto)
+			}
+		}
+		if t.Dial != "" {
+			switch {
+			case strings.HasPrefix(t.URL, "unix:"):
+				v.addf(p+".dial", "must not be set for a unix:// URL")
+			case isHTTP3(proto):
+				v.addf(p+".dial", "cannot be used with protocol %s", proto)
+			default:
+				v.checkDial(p+".dial", t.Dial)
+			}
+		}
+		if t.Redirects != nil {
+			v.checkRedirects(p+".redirects", t.Redirects)
+		}
+		if t.GRPC != nil {
+			v.checkGRPC(p, t)
+		}
+		if t.Body != "" && t.BodyFile != "" {
+			v.addf(p+".body_file", "body and body_file are mutually exclusive")
+		} else if t.BodyFile != "" {
+			v.checkFile(p+".body_file", t.BodyFile)
+		}
+		v.checkProtocol(p+".protocol", t.Protocol, t.URL)
+		if t.TLS != nil {
+			v.checkTLS(p+".tls", t.TLS)
+		}
+		for j, code := range t.ExpectStatus {
+			if code < 100 || code > 599 {
+				v.addf(fmt.Sprintf("%s.expect_status[%d]", p, j), "must be a status code between 100 and 599 (got %d)", code)
+			}
+		}
+		if t.Weight < 0 {
+			v.addf(p+".weight", "must not be negative (got %d)", t.Weight)
+		}
+		if len(t.Checks) > 0 {
+			if t.GRPC != nil {
+				v.addf(p+".checks", "checks do not apply to gRPC targets")
+			} else {
+				v.checkChecks(p+".checks", t.Checks)
+			}
+		}
+	}
+}
+
+// checkChecks validates a target's response checks.
+func (v *validator) checkChecks(p string, checks []Check) {
+	names := make(map[string]struct{}, len(checks))
+	for i, c := range checks {
+		cp := fmt.Sprintf("%s[%d]", p, i)
+		if c.Name == "" {
+			v.addf(cp+".name", "is required")
+		} else if _, dup := names[c.Name]; dup {
+			v.addf(cp+".name", "duplicate check name %q", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		set := 0
+		for _, on := range []bool{len(c.Status) > 0, c.Header != "", c.BodyMatch != "", c.JSONPath != "", c.MaxLatency != 0, c.MaxBodySize != 0} {
+			if on {
+				set++
+			}
+		}
+		if set != 1 {
+			v.addf(cp, "must set exactly one of status, header, body_match, json_path, max_latency, max_body_size (got %d)", set)
+		}
+		for j, code := range c.Status {
+			if code < 100 || code > 599 {
+				v.addf(fmt.Sprintf("%s.status[%d]", cp, j), "must be a status code between 100 and 599 (got %d)", code)
+			}
+		}
+		if c.Header != "" && !isToken(c.Header) {
+			v.addf(cp+".header", "%q is not a valid header name", c.Header)
+		}
+		if c.HeaderMatch != "" {
+			if c.Header == "" {
+				v.addf(cp+".header_match", "requires header")
+			}
+			v.checkRegexp(cp+".header_match", c.HeaderMatch)
+		}
+		if c.BodyMatch != "" {
+			v.checkRegexp(cp+".body_match", c.BodyMatch)
+		}
+		if c.JSONPath != "" && !strings.HasPrefix(c.JSONPath, "$") {
+			v.addf(cp+".json_path", "must start with $ (got %q)", c.JSONPath)
+		}
+		if len(c.Equals) > 0 {
+			if c.JSONPath == "" {
+				v.addf(cp+".equals", "requires json_path")
+			} else if !json.Valid(c.Equals) {
+				v.addf(cp+".equals", "is not valid JSON")
+			}
+		}
+		if c.MaxLatency < 0 {
+			v.addf(cp+".max_latency", "must not be negative (got %s)", c.MaxLatency)
+		}
+		if c.MaxBodySize < 0 {
+			v.addf(cp+".max_body_size", "must not be negative (got %d)", c.MaxBodySize)
+		}
+	}
+}
+
+// checkRegexp validates a regular expression.
+func (v *validator) checkRegexp(path, expr string) {
+	if _, err := regexp.Compile(expr); err != nil {
+		v.addf(path, "invalid regular expression: %v", err)
+	}
+}
+
+// isToken reports whether s is a valid RFC 7230 token (used for methods).
+func isToken(s string) bool {
+	for _, r := range s {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// checkRedirects validates a target's redirect policy.
+func (v *validator) checkRedirects(p string, r *RedirectConfig) {
+	switch r.Mode {
+	case "", "follow", "same-host":
+	case "none":
+		if r.MaxHops != 0 {
+			v.addf(p+".max_hops", "has no effect with mode none")
+		}
+	default:
+		v.addf(p+".mode", "must be one of follow, none, same-host (got %q)", r.Mode)
+	}
+	if r.MaxHops < 0 || r.MaxHops > MaxRedirectHops {
+		v.addf(p+".max_hops", "must be between 0 and %d (got %d)", MaxRedirectHops, r.MaxHops)
+	}
+}
+
+// checkGRPC validates the grpc block of target t at path p.
+func (v *validator) checkGRPC(p string, t Target) {
+	g := t.GRPC
+	service, method, ok := strings.Cut(strings.TrimPrefix(g.Method, "/"), "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		v.addf(p+".grpc.method", "must have the form package.Service/Method (got %q)", g.Method)
+	}
+	if g.DescriptorSet != "" {
+		v.checkFile(p+".grpc.descriptor_set", g.DescriptorSet)
+	}
+	if g.Request != "" && g.RequestFile != "" {
+		v.addf(p+".grpc.request_file", "request and request_file are mutually exclusive")
+	} else if g.RequestFile != "" {
+		v.checkFile(p+".grpc.request_file", g.RequestFile)
+	}
+	if t.Method != "" || t.Body != "" || t.BodyFile != "" || t.Protocol != "" {
+		v.addf(p+".grpc", "method, body, body_file and protocol do not apply to gRPC targets")
+	}
+	if strings.HasPrefix(t.URL, "unix:") {
+		v.addf(p+".url", "gRPC targets need an http or https URL; use dial for Unix sockets")
+	}
+}
+
+// checkTargetURL accepts an http(s) URL or a unix:// URL naming a Unix
+// socket, optionally followed by ":" and the request path.
+func (v *validator) checkTargetURL(path, raw string) {
+	if !strings.HasPrefix(raw, "unix:") {
+		v.checkHTTPURL(path, raw)
+		return
+	}
+	if u, err := url.Parse(raw); err != nil || u.Host != "" || u.Path == "" {
+		v.addf(path, "URL %q must have the form unix:///path.sock[:/request/path]", raw)
+	}
+}
+
+// checkDial validates a fixed dial address: unix:///path.sock or
+// tcp://host:port.
+func (v *validator) checkDial(path, raw string) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		v.addf(path, "invalid dial address %q: %v", raw, err)
+		return
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Host != "" || u.Path == "" {
+			v.addf(path, "dial address %q must have the form unix:///path.sock", raw)
+		}
+	case "tcp":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil || u.Path != "" {
+			v.addf(path, "dial address %q must have the form tcp://host:port", raw)
+		}
+	default:
+		v.addf(path, "dial address %q must use the unix or tcp scheme", raw)
+	}
+}
+
+// checkHTTPURL requires raw to be an absolute http or https URL with a host.
+func (v *validator) checkHTTPURL(path, raw string) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		v.addf(path, "invalid URL %q: %v", raw, err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		v.addf(path, "URL %q must use the http or https scheme", raw)
+		return
+	}
+	if u.Host == "" {
+		v.addf(path, "URL %q has no host", raw)
+	}
+}
+
+// checkFile requires name to exist and be a regular file.
+func (v *validator) checkFile(path, name string) {
+	info, err := os.Stat(name)
+	if err != nil {
+		v.addf(path, "cannot access %q: %v", name, err)
+		return
+	}
+	if info.IsDir() {
+		v.addf(path, "%q is a directory, not a file", name)
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
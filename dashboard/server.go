@@ -4,9 +4,10 @@
 //   - GET  /api/metrics/stream  – SSE stream of live metrics (100 ms ticks)
 //   - GET  /api/logs/stream     – SSE stream of log entries
 //   - GET  /api/config          – current engine configuration (JSON)
-//   - POST /api/config          – hot-reload selected config fields (JSON body)
+//   - POST /api/config          – hot-update selected config fields (JSON body)
 //   - GET  /api/nodes           – cluster node status snapshot (JSON)
 //   - POST /api/proxy           – upload a new proxy list (multipart file)
+//   - GET  /api/sessions/{id}/har – a session's recent requests as HAR 1.2
 //
 // All SSE endpoints set appropriate headers so browsers can use EventSource
 // without any additional libraries.  CORS is wide-open so the Next.js dev
@@ -15,16 +16,19 @@ package dashboard
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/firasghr/GoSessionEngine/client"
 	"github.com/firasghr/GoSessionEngine/config"
 	"github.com/firasghr/GoSessionEngine/metrics"
 )
@@ -40,6 +44,34 @@ type MetricsSnapshot struct {
 	RPS           float64 `json:"rps"`
 	Sessions      int64   `json:"sessions"`
 	CookieJarSize int64   `json:"cookie_jar_size"`
+
+	// Targets holds per-target counters keyed by target name.
+	Targets map[string]metrics.TargetSnapshot `json:"targets,omitempty"`
+
+	// Timings breaks average request latency down by phase.
+	Timings metrics.TimingSnapshot `json:"timings"`
+
+	// WebSockets summarises WebSocket connections and traffic.
+	WebSockets metrics.WebSocketSnapshot `json:"websockets"`
+
+	// SSE summarises Server-Sent Events subscriptions.
+	SSE metrics.SSESnapshot `json:"sse"`
+
+	// GRPC summarises calls to gRPC targets.
+	GRPC metrics.GRPCSnapshot `json:"grpc"`
+
+	// Redirects summarises followed and stopped redirects.
+	Redirects metrics.RedirectSnapshot `json:"redirects"`
+
+	// Shaping summarises the throughput of shaped sessions by profile.
+	Shaping metrics.ShapingSnapshot `json:"shaping"`
+
+	// Checks counts passed and failed response checks by target and name.
+	Checks metrics.ChecksSnapshot `json:"checks"`
+
+	// Breakers reports circuit breaker states and short-circuited
+	// requests by host or target.
+	Breakers metrics.BreakersSnapshot `json:"breakers"`
 }
 
 // NodeStatus represents one cluster node's health.
@@ -71,8 +103,7 @@ type ConfigPayload struct {
 // Server provides HTTP endpoints consumed by the Command Center frontend.
 type Server struct {
 	metrics *metrics.Metrics
-	cfg     *config.Config
-	cfgMu   sync.RWMutex
+	store   *config.Store
 
 	// Live counters updated by the engine.
 	activeSessions atomic.Int64
@@ -88,17 +119,28 @@ type Server struct {
 	metricsSubs  map[chan MetricsSnapshot]struct{}
 	metricsSubMu sync.Mutex
 
+	// harSource serves /api/sessions/{id}/har; see SetHARSource.
+	harSource atomic.Pointer[HARSource]
+
+	// chaos serves /api/chaos; see SetChaos.
+	chaos atomic.Pointer[client.Chaos]
+
+	// breakers serves /api/breakers; see SetBreakers.
+	breakers atomic.Pointer[client.Breakers]
+
 	mux *http.ServeMux
 }
 
 const maxLogs = 10_000
 
-// New creates a dashboard Server backed by the given metrics and config.
+// New creates a dashboard Server backed by the given metrics and config store.
+// Config changes made through the API are applied via store.Update, so they
+// are validated and propagated to every store subscriber.
 // Call ListenAndServe to start accepting connections.
-func New(m *metrics.Metrics, cfg *config.Config) *Server {
+func New(m *metrics.Metrics, store *config.Store) *Server {
 	s := &Server{
 		metrics:     m,
-		cfg:         cfg,
+		store:       store,
 		logs:        make([]LogEntry, 0, 512),
 		logSubs:     make(map[chan LogEntry]struct{}),
 		metricsSubs: make(map[chan MetricsSnapshot]struct{}),
@@ -108,6 +150,22 @@ func New(m *metrics.Metrics, cfg *config.Config) *Server {
 	return s
 }
 
+// HARSource returns the request history of session id as a HAR document,
+// or false if there is no such session.
+type HARSource func(id int) (*client.HAR, bool)
+
+// SetHARSource installs the lookup behind /api/sessions/{id}/har.  Until it
+// is called the endpoint answers 404.
+func (s *Server) SetHARSource(src HARSource) { s.harSource.Store(&src) }
+
+// SetChaos installs the fault injector controlled through /api/chaos.
+// Until it is called the endpoints answer 404.
+func (s *Server) SetChaos(c *client.Chaos) { s.chaos.Store(c) }
+
+// SetBreakers installs the circuit breakers reported by /api/breakers.
+// Until it is called the endpoint answers 404.
+func (s *Server) SetBreakers(b *client.Breakers) { s.breakers.Store(b) }
+
 // SetActiveSessions updates the live session count displayed on the dashboard.
 func (s *Server) SetActiveSessions(n int64) { s.activeSessions.Store(n) }
 
@@ -170,6 +228,10 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/api/config", s.withCORS(s.handleConfig))
 	s.mux.HandleFunc("/api/nodes", s.withCORS(s.handleNodes))
 	s.mux.HandleFunc("/api/proxy", s.withCORS(s.handleProxy))
+	s.mux.HandleFunc("/api/sessions/{id}/har", s.withCORS(s.handleSessionHAR))
+	s.mux.HandleFunc("/api/chaos", s.withCORS(s.handleChaos))
+	s.mux.HandleFunc("/api/chaos/rules/{name}", s.withCORS(s.handleChaosRule))
+	s.mux.HandleFunc("/api/breakers", s.withCORS(s.handleBreakers))
 }
 
 // ─── CORS middleware ──────────────────────────────────────────────────────────
@@ -215,6 +277,15 @@ func (s *Server) snapshot() MetricsSnapshot {
 		RPS:           s.metrics.RequestsPerSecond(),
 		Sessions:      s.activeSessions.Load(),
 		CookieJarSize: s.cookieJarSize.Load(),
+		Targets:       s.metrics.TargetSnapshots(),
+		Timings:       s.metrics.TimingSnapshot(),
+		WebSockets:    s.metrics.WebSocketSnapshot(),
+		SSE:           s.metrics.SSESnapshot(),
+		GRPC:          s.metrics.GRPCSnapshot(),
+		Redirects:     s.metrics.RedirectSnapshot(),
+		Shaping:       s.metrics.ShapingSnapshot(),
+		Checks:        s.metrics.ChecksSnapshot(),
+		Breakers:      s.metrics.BreakersSnapshot(),
 	}
 }
 
@@ -321,9 +392,7 @@ func sseWrite(w io.Writer, v any) error {
 func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		s.cfgMu.RLock()
-		cfg := *s.cfg
-		s.cfgMu.RUnlock()
+		cfg := s.store.Current()
 
 		payload := ConfigPayload{
 			TargetURL:        cfg.TargetURL,
@@ -341,17 +410,24 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid JSON", http.StatusBadRequest)
 			return
 		}
-		s.cfgMu.Lock()
-		if payload.TargetURL != "" {
-			s.cfg.TargetURL = payload.TargetURL
-		}
-		if payload.NumberOfSessions > 0 && payload.NumberOfSessions <= 2000 {
-			s.cfg.NumberOfSessions = payload.NumberOfSessions
-		}
-		if payload.MaxRetries > 0 && payload.MaxRetries <= 100 {
-			s.cfg.MaxRetries = payload.MaxRetries
+		// Zero values mean "leave unchanged"; everything else goes through
+		// the store so it is validated and broadcast to subscribers.
+		err := s.store.Update(func(c *config.Config) {
+			if payload.TargetURL != "" {
+				c.TargetURL = payload.TargetURL
+			}
+			if payload.NumberOfSessions != 0 {
+				c.NumberOfSessions = payload.NumberOfSessions
+			}
+			if payload.MaxRetries != 0 {
+				c.MaxRetries = payload.MaxRetries
+			}
+		})
+		if err != nil {
+			s.AddLog("ERROR", fmt.Sprintf("config update via dashboard rejected: %v", err))
+			writeConfigError(w, err)
+			return
 		}
-		s.cfgMu.Unlock()
 		s.AddLog("INFO", fmt.Sprintf("config updated via dashboard: target_url=%q sessions=%d retries=%d",
 			payload.TargetURL, payload.NumberOfSessions, payload.MaxRetries))
 		w.Header().Set("Content-Type", "application/json")
@@ -362,6 +438,21 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// writeConfigError reports a rejected config change as JSON.  Changes to
+// restart-only fields get 409 Conflict; validation failures get 400.
+func writeConfigError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var restartErr *config.RestartRequiredError
+	if errors.As(err, &restartErr) {
+		status = http.StatusConflict
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if encErr := json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": err.Error()}); encErr != nil {
+		log.Printf("dashboard: encode config error: %v", encErr)
+	}
+}
+
 // ─── /api/nodes ──────────────────────────────────────────────────────────────
 
 // handleNodes returns a synthetic cluster health snapshot.
@@ -444,9 +535,11 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.cfgMu.Lock()
-	s.cfg.ProxyFile = dest.Name()
-	s.cfgMu.Unlock()
+	if err := s.store.Update(func(c *config.Config) { c.ProxyFile = dest.Name() }); err != nil {
+		s.AddLog("ERROR", fmt.Sprintf("proxy list upload rejected: %v", err))
+		writeConfigError(w, err)
+		return
+	}
 
 	s.AddLog("INFO", fmt.Sprintf("proxy list uploaded: file=%q size=%d bytes original=%q",
 		dest.Name(), n, header.Filename))
@@ -454,3 +547,141 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	fmt.Fprintf(w, `{"ok":true,"path":%q,"bytes":%d}`, dest.Name(), n)
 }
+
+// ─── /api/sessions/{id}/har ──────────────────────────────────────────────────
+
+// handleSessionHAR exports one session's request history as HAR 1.2, as a
+// download named session-<id>.har.
+func (s *Server) handleSessionHAR(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid session id", http.StatusBadRequest)
+		return
+	}
+	src := s.harSource.Load()
+	if src == nil {
+		http.Error(w, "session history not available", http.StatusNotFound)
+		return
+	}
+	har, ok := (*src)(id)
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%d.har"`, id))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(har); err != nil {
+		log.Printf("dashboard: encode HAR: %v", err)
+	}
+}
+
+// ─── /api/chaos ──────────────────────────────────────────────────────────────
+
+// ChaosPayload is the body of GET /api/chaos.
+type ChaosPayload struct {
+	Enabled bool                     `json:"enabled"`
+	Rules   []client.FaultRuleStatus `json:"rules"`
+}
+
+// chaosToggle is the body of the POST endpoints under /api/chaos.
+type chaosToggle struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// decodeChaosToggle reads a chaosToggle, answering 400 if it is malformed.
+func decodeChaosToggle(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	var t chaosToggle
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil || t.Enabled == nil {
+		http.Error(w, `invalid JSON: want {"enabled": true|false}`, http.StatusBadRequest)
+		return false, false
+	}
+	return *t.Enabled, true
+}
+
+// handleChaos reports the fault-injection rules (GET) or switches all
+// injection on or off (POST {"enabled": bool}).
+func (s *Server) handleChaos(w http.ResponseWriter, r *http.Request) {
+	c := s.chaos.Load()
+	if c == nil {
+		http.Error(w, "fault injection not configured", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		on, ok := decodeChaosToggle(w, r)
+		if !ok {
+			return
+		}
+		c.SetEnabled(on)
+		s.AddLog("INFO", fmt.Sprintf("fault injection %s via dashboard", onOff(on)))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ChaosPayload{Enabled: c.Enabled(), Rules: c.Rules()}); err != nil {
+		log.Printf("dashboard: encode chaos: %v", err)
+	}
+}
+
+// handleChaosRule switches one rule on or off (POST {"enabled": bool}).
+func (s *Server) handleChaosRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	c := s.chaos.Load()
+	if c == nil {
+		http.Error(w, "fault injection not configured", http.StatusNotFound)
+		return
+	}
+	on, ok := decodeChaosToggle(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	if err := c.SetRuleEnabled(name, on); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.AddLog("INFO", fmt.Sprintf("fault rule %q %s via dashboard", name, onOff(on)))
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"ok":true}`)
+}
+
+// ─── /api/breakers ───────────────────────────────────────────────────────────
+
+// handleBreakers reports the live state of every circuit breaker (GET).
+func (s *Server) handleBreakers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	b := s.breakers.Load()
+	if b == nil {
+		http.Error(w, "circuit breakers not configured", http.StatusNotFound)
+		return
+	}
+	status := b.Status()
+	if status == nil {
+		status = []client.BreakerStatus{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("dashboard: encode breakers: %v", err)
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,212 @@
+// Package feeder loads rows of test data – user accounts, search terms, IDs
+// – from CSV or JSONL files and hands them out to sessions according to a
+// Strategy.  Targets render the rows into request URLs, headers and bodies
+// through templates; see target.TemplateData.
+//
+// # Thread safety
+//
+// A Feeder is safe for concurrent use.  Its rows are immutable after
+// loading; only the cursor changes.
+package feeder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/firasghr/GoSessionEngine/config"
+)
+
+// ErrExhausted is returned by Next once a feeder that stops at the end of
+// its data has handed out every row (or, for Unique, has no row left for
+// the session).
+var ErrExhausted = errors.New("feeder: out of data")
+
+// Strategy decides which row Next returns.
+type Strategy string
+
+const (
+	// Sequential hands out rows in file order, each row once, until the
+	// data runs out.
+	Sequential Strategy = "sequential"
+
+	// Random picks any row each time.  It never runs out.
+	Random Strategy = "random"
+
+	// Unique gives each session its own row, by session ID, for the
+	// session's whole lifetime – e.g. one account per session.
+	Unique Strategy = "unique"
+
+	// Circular hands out rows in file order and starts again at the top
+	// after the last one.  It never runs out.
+	Circular Strategy = "circular"
+)
+
+// ParseStrategy validates s.  The empty string means Sequential.
+func ParseStrategy(s string) (Strategy, error) {
+	switch st := Strategy(strings.ToLower(s)); st {
+	case "":
+		return Sequential, nil
+	case Sequential, Random, Unique, Circular:
+		return st, nil
+	}
+	return "", fmt.Errorf("feeder: unknown strategy %q (want sequential, random, unique or circular)", s)
+}
+
+// Row is one record of a feeder.  CSV values are strings keyed by the
+// header row; JSONL values keep their JSON types.
+type Row map[string]any
+
+// Feeder hands out rows.
+type Feeder struct {
+	name     string
+	rows     []Row
+	strategy Strategy
+	recycle  bool
+	next     atomic.Uint64
+}
+
+// New returns a feeder over rows.  recycle makes Sequential and Unique
+// feeders wrap around instead of returning ErrExhausted at the end of the
+// data; Random and Circular ignore it.
+func New(name string, rows []Row, strategy Strategy, recycle bool) (*Feeder, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("feeder %q: no rows", name)
+	}
+	st, err := ParseStrategy(string(strategy))
+	if err != nil {
+		return nil, fmt.Errorf("feeder %q: %w", name, err)
+	}
+	return &Feeder{name: name, rows: rows, strategy: st, recycle: recycle}, nil
+}
+
+// Open loads the feeder described by c.
+func Open(c config.Feeder) (*Feeder, error) {
+	rows, err := Load(c.File, c.Format)
+	if err != nil {
+		return nil, err
+	}
+	return New(c.Name, rows, Strategy(c.Strategy), c.OnExhausted == "recycle")
+}
+
+// Name returns the feeder's name.
+func (f *Feeder) Name() string { return f.name }
+
+// Len returns the number of rows.
+func (f *Feeder) Len() int { return len(f.rows) }
+
+// Exhausted reports whether Next will return ErrExhausted for every
+// session from now on: a Sequential feeder that stops at the end of its
+// data has handed out every row.  Unique feeders run out per session, so
+// they never report it.
+func (f *Feeder) Exhausted() bool {
+	return f.strategy == Sequential && !f.recycle && f.next.Load() >= uint64(len(f.rows))
+}
+
+// Next returns the row for the next request of session sessionID.  The
+// returned row is shared and must not be modified.
+func (f *Feeder) Next(sessionID int) (Row, error) {
+	n := uint64(len(f.rows))
+	switch f.strategy {
+	case Random:
+		return f.rows[rand.Uint64N(n)], nil
+	case Unique:
+		i := uint64(sessionID)
+		if sessionID < 0 || (i >= n && !f.recycle) {
+			return nil, fmt.Errorf("%w: feeder %q has %d rows, none left for session %d", ErrExhausted, f.name, n, sessionID)
+		}
+		return f.rows[i%n], nil
+	case Circular:
+		return f.rows[(f.next.Add(1)-1)%n], nil
+	}
+	i := f.next.Add(1) - 1
+	if i >= n && !f.recycle {
+		return nil, fmt.Errorf("%w: feeder %q handed out all %d rows", ErrExhausted, f.name, n)
+	}
+	return f.rows[i%n], nil
+}
+
+// Load reads the rows of a CSV or JSONL file.  An empty format is chosen
+// from the extension: ".csv" is CSV, anything else JSONL.
+func Load(path, format string) ([]Row, error) {
+	if format == "" {
+		format = "jsonl"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = "csv"
+		}
+	}
+	f, err := os.Open(path) // #nosec G304 – operator-supplied config path
+	if err != nil {
+		return nil, fmt.Errorf("feeder: open %q: %w", path, err)
+	}
+	defer f.Close()
+	var rows []Row
+	switch format {
+	case "csv":
+		rows, err = readCSV(f)
+	case "jsonl":
+		rows, err = readJSONL(f)
+	default:
+		return nil, fmt.Errorf("feeder: unknown format %q (want csv or jsonl)", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("feeder: read %q: %w", path, err)
+	}
+	return rows, nil
+}
+
+// readCSV reads a CSV file whose first row names the columns.
+func readCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rows []Row
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(Row, len(header))
+		for i, col := range header {
+			row[col] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readJSONL reads one JSON object per line, skipping blank lines.
+func readJSONL(r io.Reader) ([]Row, error) {
+	var rows []Row
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var row Row
+		if err := json.Unmarshal(b, &row); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
@@ -0,0 +1,168 @@
+package feeder_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/feeder"
+)
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rows(n int) []feeder.Row {
+	out := make([]feeder.Row, n)
+	for i := range out {
+		out[i] = feeder.Row{"i": i}
+	}
+	return out
+}
+
+// draw returns the "i" column of the next n rows for session id, stopping
+// at the first error.
+func draw(t *testing.T, f *feeder.Feeder, id, n int) ([]int, error) {
+	t.Helper()
+	var got []int
+	for range n {
+		row, err := f.Next(id)
+		if err != nil {
+			return got, err
+		}
+		got = append(got, row["i"].(int))
+	}
+	return got, nil
+}
+
+func TestLoad_CSVAndJSONL(t *testing.T) {
+	csvPath := writeFile(t, "users.csv", "username,password\nalice, s3cret\n\"bob, jr\",hunter2\n")
+	got, err := feeder.Load(csvPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0]["password"] != "s3cret" || got[1]["username"] != "bob, jr" {
+		t.Errorf("csv rows: got %v", got)
+	}
+
+	jsonPath := writeFile(t, "ids.data", "{\"id\": 7, \"tags\": [\"a\"]}\n\n{\"id\": 8}\n")
+	got, err = feeder.Load(jsonPath, "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0]["id"] != float64(7) || got[1]["id"] != float64(8) {
+		t.Errorf("jsonl rows: got %v", got)
+	}
+
+	bad := writeFile(t, "bad.jsonl", "{\"id\": 1}\nnot json\n")
+	if _, err := feeder.Load(bad, ""); err == nil {
+		t.Error("expected an error for a malformed JSONL line")
+	}
+}
+
+func TestNext_Sequential(t *testing.T) {
+	f, err := feeder.New("seq", rows(3), feeder.Sequential, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := draw(t, f, 0, 4)
+	if !errors.Is(err, feeder.ErrExhausted) {
+		t.Fatalf("err: got %v, want ErrExhausted", err)
+	}
+	if len(got) != 3 || got[0] != 0 || got[2] != 2 {
+		t.Errorf("rows: got %v, want [0 1 2]", got)
+	}
+	if !f.Exhausted() {
+		t.Error("Exhausted: got false after every row was handed out")
+	}
+
+	f, _ = feeder.New("seq", rows(3), feeder.Sequential, true)
+	got, err = draw(t, f, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[3] != 0 || got[4] != 1 {
+		t.Errorf("recycled rows: got %v, want [0 1 2 0 1]", got)
+	}
+	if f.Exhausted() {
+		t.Error("Exhausted: got true for a recycling feeder")
+	}
+}
+
+func TestNext_Circular(t *testing.T) {
+	f, _ := feeder.New("c", rows(2), feeder.Circular, false)
+	got, err := draw(t, f, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if v != i%2 {
+			t.Fatalf("rows: got %v, want alternating 0 and 1", got)
+		}
+	}
+}
+
+func TestNext_Unique(t *testing.T) {
+	f, _ := feeder.New("u", rows(2), feeder.Unique, false)
+	for id := range 2 {
+		got, err := draw(t, f, id, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range got {
+			if v != id {
+				t.Fatalf("session %d: got rows %v, want only %d", id, got, id)
+			}
+		}
+	}
+	if _, err := f.Next(2); !errors.Is(err, feeder.ErrExhausted) {
+		t.Errorf("session 2: got %v, want ErrExhausted", err)
+	}
+
+	f, _ = feeder.New("u", rows(2), feeder.Unique, true)
+	row, err := f.Next(3)
+	if err != nil || row["i"] != 1 {
+		t.Errorf("recycled session 3: got %v, %v; want row 1", row, err)
+	}
+}
+
+func TestNext_Random(t *testing.T) {
+	f, _ := feeder.New("r", rows(3), feeder.Random, false)
+	seen := make(map[int]bool)
+	got, err := draw(t, f, 0, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range got {
+		seen[v] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("300 random draws hit %d of 3 rows", len(seen))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := writeFile(t, "terms.csv", "term\ngo\n")
+	f, err := feeder.Open(config.Feeder{Name: "terms", File: path, OnExhausted: "recycle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if row, err := f.Next(0); err != nil || row["term"] != "go" {
+			t.Fatalf("got %v, %v", row, err)
+		}
+	}
+	if _, err := feeder.Open(config.Feeder{Name: "empty", File: writeFile(t, "empty.csv", "term\n")}); err == nil {
+		t.Error("expected an error for a feeder without rows")
+	}
+	if _, err := feeder.New("x", rows(1), "shuffle", false); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 )
 
@@ -21,6 +22,21 @@ const (
 	LevelError
 )
 
+// ParseLevel converts a level name ("debug", "info", "error") to a Level.
+// An empty string maps to LevelInfo.  Matching is case-insensitive.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("logger: unknown level %q", name)
+	}
+}
+
 // Logger is a structured, levelled logger.
 //
 // Thread-safety: log.Logger (from the standard library) serialises writes to
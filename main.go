@@ -1,39 +1,54 @@
 // GoSessionEngine is a high-concurrency HTTP session automation engine.
 //
 // Startup sequence:
-//  1. Load configuration (JSON file or defaults).
+//  1. Load configuration: defaults, then a JSON/YAML file, then GSE_*
+//     environment variables, then per-field CLI flags (later layers win).
 //  2. Load proxy list (optional).
 //  3. Initialise metrics and logger.
 //  4. Create the session manager and instantiate all sessions concurrently.
 //  5. Start the worker pool.
 //  6. Start the scheduler, which fans work out to sessions continuously.
 //  7. Monitor metrics in a background goroutine.
-//  8. Block until OS signals SIGINT or SIGTERM, then perform a clean shutdown.
+//  8. Watch the config file and SIGHUP for hot reloads.
+//  9. Block until OS signals SIGINT or SIGTERM, then perform a clean shutdown.
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"net/http"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/firasghr/GoSessionEngine/check"
+	"github.com/firasghr/GoSessionEngine/client"
 	"github.com/firasghr/GoSessionEngine/config"
 	"github.com/firasghr/GoSessionEngine/dashboard"
+	"github.com/firasghr/GoSessionEngine/feeder"
 	"github.com/firasghr/GoSessionEngine/logger"
 	"github.com/firasghr/GoSessionEngine/metrics"
 	"github.com/firasghr/GoSessionEngine/proxy"
+	"github.com/firasghr/GoSessionEngine/results"
 	"github.com/firasghr/GoSessionEngine/scheduler"
 	"github.com/firasghr/GoSessionEngine/session"
+	"github.com/firasghr/GoSessionEngine/target"
 	"github.com/firasghr/GoSessionEngine/worker"
 )
 
 func main() {
 	// ── Flags ──────────────────────────────────────────────────────────────
-	configFile := flag.String("config", "", "Path to JSON config file (optional; uses defaults if omitted)")
+	configFile := flag.String("config", "", "Path to JSON or YAML config file (optional; uses defaults if omitted)")
 	dashboardAddr := flag.String("dashboard", ":8080", "Address for the real-time dashboard HTTP server (e.g. :8080)")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration and the source of each value, then exit")
+	overrides := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	// ── Logger ─────────────────────────────────────────────────────────────
@@ -41,20 +56,42 @@ func main() {
 	log.Info("GoSessionEngine starting up")
 
 	// ── Configuration ──────────────────────────────────────────────────────
-	var cfg *config.Config
-	if *configFile != "" {
-		var err error
-		cfg, err = config.LoadConfig(*configFile)
-		if err != nil {
-			log.Errorf("failed to load config from %q: %v", *configFile, err)
+	cfg, sources, err := config.Load(config.Options{File: *configFile, Flags: overrides})
+	if err != nil {
+		log.Errorf("failed to load configuration: %v", err)
+		os.Exit(1)
+	}
+	validationErr := cfg.Validate()
+	if *printConfig {
+		if err := config.WriteEffective(os.Stdout, cfg, sources); err != nil {
+			log.Errorf("failed to print configuration: %v", err)
 			os.Exit(1)
 		}
-		log.Infof("configuration loaded from %q", *configFile)
+		if validationErr != nil {
+			log.Errorf("%v", validationErr)
+			os.Exit(1)
+		}
+		return
+	}
+	if validationErr != nil {
+		// Refuse to start rather than run with a config that would fail at
+		// request time (or silently do nothing).
+		log.Errorf("refusing to start: %v", validationErr)
+		os.Exit(1)
+	}
+	if *configFile != "" {
+		log.Infof("configuration loaded from %q with env/flag overrides", *configFile)
 	} else {
-		cfg = config.DefaultConfig()
-		log.Info("using default configuration")
+		log.Info("using default configuration with env/flag overrides")
+	}
+	if level, err := logger.ParseLevel(cfg.LogLevel); err == nil {
+		log.SetLevel(level)
 	}
 
+	// The store owns the live configuration from here on; components read
+	// store.Current() or subscribe to changes instead of holding cfg.
+	store := config.NewStore(cfg, config.Options{File: *configFile, Flags: overrides})
+
 	// ── Proxy manager ──────────────────────────────────────────────────────
 	pm := &proxy.ProxyManager{}
 	if cfg.ProxyFile != "" {
@@ -71,7 +108,7 @@ func main() {
 	m := metrics.NewMetrics()
 
 	// ── Dashboard server ───────────────────────────────────────────────────
-	dash := dashboard.New(m, cfg)
+	dash := dashboard.New(m, store)
 	go func() {
 		if err := dash.ListenAndServe(*dashboardAddr); err != nil {
 			log.Errorf("dashboard server error: %v", err)
@@ -81,17 +118,103 @@ func main() {
 
 	// ── Session manager ────────────────────────────────────────────────────
 	sm := session.NewSessionManager(cfg)
+
+	// Record and replay share one cassette across all sessions; shaped
+	// connections report their throughput to the metrics.
+	traffic := session.Traffic{ShapeObserver: m}
+	if cfg.Record.File != "" {
+		rec, err := client.NewRecorderWithOptions(cfg.Record.File, client.RecorderOptions{
+			Format:       client.CassetteFormat(cfg.Record.Format),
+			MaxBodyBytes: cfg.Record.MaxBodyBytes,
+		})
+		if err != nil {
+			log.Errorf("failed to open cassette: %v", err)
+			os.Exit(1)
+		}
+		traffic.Recorder = rec
+		log.Infof("recording session traffic to %q", cfg.Record.File)
+	}
+	if cfg.ReplayFile != "" {
+		rp, err := client.LoadReplayer(cfg.ReplayFile)
+		if err != nil {
+			log.Errorf("failed to load cassette: %v", err)
+			os.Exit(1)
+		}
+		traffic.Replay = rp
+		log.Infof("replaying responses from %q; targets will not be contacted", cfg.ReplayFile)
+	}
+	chaos, err := session.NewChaos(cfg.Chaos)
+	if err != nil {
+		log.Errorf("failed to build fault injection rules: %v", err)
+		os.Exit(1)
+	}
+	if chaos != nil {
+		traffic.Chaos = chaos
+		dash.SetChaos(chaos)
+		log.Infof("%d fault injection rule(s) loaded (enabled=%t)", len(cfg.Chaos.Rules), chaos.Enabled())
+	}
+	breakers := session.NewBreakers(cfg.CircuitBreaker, breakerMetrics{m}, func(key string, from, to client.BreakerState) {
+		msg := fmt.Sprintf("circuit breaker %q %s -> %s", key, from, to)
+		log.Info(msg)
+		dash.AddLog("INFO", msg)
+	})
+	if breakers != nil {
+		traffic.Breakers = breakers
+		dash.SetBreakers(breakers)
+		log.Infof("circuit breakers enabled per %s", cmp.Or(cfg.CircuitBreaker.Key, "host"))
+	}
+	if len(cfg.Shaping.Profiles) > 0 {
+		log.Infof("shaping sessions with profiles %v", cfg.Shaping.Profiles)
+	}
+	sm.SetTraffic(traffic, cfg.Record.Sessions)
+
 	log.Infof("creating %d sessions…", cfg.NumberOfSessions)
 	if err := sm.CreateSessions(cfg.NumberOfSessions, pm); err != nil {
 		log.Errorf("session creation failed: %v", err)
 		os.Exit(1)
 	}
 	log.Infof("%d sessions created", sm.Count())
+	dash.SetHARSource(func(id int) (*client.HAR, bool) {
+		sess, ok := sm.GetSession(id)
+		if !ok {
+			return nil, false
+		}
+		return sess.HAR(), true
+	})
+
+	// ── Targets ────────────────────────────────────────────────────────────
+	// The resolved target set is swapped atomically on reload so jobs never
+	// see a half-built set.
+	// Feeders are loaded once and shared by every rebuilt set, so their
+	// cursors survive reloads.
+	feeders := make(map[string]*feeder.Feeder, len(cfg.Feeders))
+	for _, fc := range cfg.Feeders {
+		f, err := feeder.Open(fc)
+		if err != nil {
+			log.Errorf("failed to load feeder: %v", err)
+			os.Exit(1)
+		}
+		feeders[fc.Name] = f
+		log.Infof("feeder %q: %d row(s) from %q", fc.Name, f.Len(), fc.File)
+	}
+	var targets atomic.Pointer[target.Set]
+	initialTargets, err := target.NewSetWithFeeders(cfg.EffectiveTargets(), feeders)
+	if err != nil {
+		log.Errorf("failed to build targets: %v", err)
+		os.Exit(1)
+	}
+	targets.Store(initialTargets)
+	if initialTargets.Len() == 0 {
+		log.Info("no targets configured; the default job is a no-op")
+	} else {
+		log.Infof("%d target(s) configured", initialTargets.Len())
+	}
 
 	// ── Worker pool ────────────────────────────────────────────────────────
 	// Use the same number of OS threads as sessions (capped at 2 000) to
 	// maximise I/O parallelism.  In CPU-bound scenarios this should be tuned
-	// down to runtime.NumCPU().
+	// down to runtime.NumCPU().  The pool is sized once at startup; sessions
+	// added by a later reload share the existing workers.
 	workerCount := cfg.NumberOfSessions
 	if workerCount < 1 {
 		workerCount = 1
@@ -102,32 +225,242 @@ func main() {
 
 	// ── Scheduler ──────────────────────────────────────────────────────────
 	sc := scheduler.NewScheduler(sm, wp)
+	sc.SetRateLimit(cfg.RateLimit)
+
+	// ── Result sinks ───────────────────────────────────────────────────────
+	// Per-request records are queued and written in the background, so a
+	// slow disk or webhook never holds up a worker.
+	// Each sink has its own queue, so a stalled webhook does not hold up
+	// the files.
+	resultSink, err := openResults(cfg.Results, func(err error) {
+		log.Errorf("result sink: %v", err)
+	})
+	if err != nil {
+		log.Errorf("failed to open result sinks: %v", err)
+		os.Exit(1)
+	}
+	emit := resultSink.Record
+	if len(resultSink) > 0 {
+		log.Infof("writing per-request results to %d sink(s)", len(cfg.Results.Sinks))
+	}
 
 	// jobFn is the work each session performs on each iteration.
 	// Replace this closure with your application-specific logic.
+	// The default job picks one of the configured targets (weighted) and
+	// records the outcome both globally and per target.
+	//
+	// A target whose feeder has run out is no longer picked; a request
+	// that finds the feeder empty is skipped without being counted, and
+	// the first skip per target is logged.  Once every target has run out
+	// the run ends.  A target whose circuit breaker is open is also
+	// skipped, before it draws test data: short-circuited requests are
+	// counted by the breaker metrics only.
+	var exhausted sync.Map // target name -> struct{}
+	drained := make(chan struct{})
+	var drainOnce sync.Once
 	jobFn := func(s *session.Session) {
-		if cfg.TargetURL == "" {
+		set := targets.Load()
+		t := set.Pick()
+		if t == nil {
+			if set.Drained() {
+				drainOnce.Do(func() { close(drained) })
+			}
+			return
+		}
+		if breakers != nil && !t.IsGRPC() {
+			if key := breakers.KeyFor(t.Name, t.URL); key != "" {
+				if err := breakers.Check(key); err != nil {
+					rec := results.Record{Time: time.Now(), Session: s.ID, Target: t.Name, URL: t.URL}
+					setError(&rec, err)
+					emit(rec)
+					return
+				}
+			}
+		}
+		data, err := t.Data(s.ID)
+		if err != nil {
+			if _, seen := exhausted.LoadOrStore(t.Name, struct{}{}); !seen {
+				log.Infof("%v; target %q stops sending", err, t.Name)
+				dash.AddLog("INFO", fmt.Sprintf("target %q: feeder out of data", t.Name))
+			}
 			return
 		}
 		m.IncrementTotal()
-		resp, err := s.ExecuteRequest(http.MethodGet, cfg.TargetURL, nil)
+		tm := m.Target(t.Name)
+		rec := results.Record{Time: time.Now(), Session: s.ID, Target: t.Name, URL: t.URL}
+
+		if t.IsGRPC() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.RequestTimeout))
+			start := time.Now()
+			res, err := t.InvokeWith(ctx, s, data)
+			cancel()
+			elapsed := time.Since(start)
+			m.RecordGRPC(res.Code.String(), res.Messages, elapsed)
+			tm.Record(err == nil, elapsed)
+			rec.GRPCCode, rec.OK, rec.TotalMs = res.Code.String(), err == nil, float64(elapsed)/float64(time.Millisecond)
+			setError(&rec, err)
+			emit(rec)
+			if err != nil {
+				m.IncrementFailed()
+				log.Debugf("session %d: %v", s.ID, err)
+				return
+			}
+			m.IncrementSuccess()
+			return
+		}
+
+		req, err := t.NewRequestWith(data)
+		if err != nil {
+			m.IncrementFailed()
+			tm.Record(false, 0)
+			setError(&rec, err)
+			emit(rec)
+			log.Debugf("session %d: %v", s.ID, err)
+			return
+		}
+		rec.Method, rec.URL, rec.BytesSent = req.Method, req.URL.String(), max(req.ContentLength, 0)
+		// A breaker that opened after the check above still short-circuits
+		// the request; it was already counted, so it counts as failed.
+		resp, trace, err := s.DoTimed(req)
 		if err != nil {
+			if errors.Is(err, client.ErrTooManyRedirects) {
+				m.RecordRedirectLimit()
+			}
+			timings := trace.Timings()
 			m.IncrementFailed()
+			m.RecordTimings(metricsTimings(timings))
+			tm.Record(false, timings.Total)
+			setTimings(&rec, timings)
+			setError(&rec, err)
+			emit(rec)
 			log.Debugf("session %d request error: %v", s.ID, err)
 			return
 		}
+		// Drain the body so the connection can be reused and the latency
+		// covers the full response.  Checks that inspect the body get its
+		// first check.MaxBody bytes.
+		checks := t.Checks()
+		body, n, readErr := checks.Drain(resp.Body)
 		resp.Body.Close()
-		if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+
+		timings := trace.Timings()
+		m.RecordTimings(metricsTimings(timings))
+		m.RecordRedirects(len(client.RedirectChain(resp)),
+			resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "")
+		expected := t.Expected(resp.StatusCode)
+		ok := expected
+		var checkErr error
+		if len(checks) > 0 && readErr == nil {
+			var outcomes []check.Result
+			outcomes, checkErr = checks.Run(&check.Response{
+				Status:  resp.StatusCode,
+				Header:  resp.Header,
+				Body:    body,
+				Size:    n,
+				Latency: timings.Total,
+			})
+			for _, o := range outcomes {
+				m.RecordCheck(t.Name, o.Name, o.Err)
+			}
+			if checkErr != nil {
+				ok = false
+				log.Debugf("session %d: target %q: %v", s.ID, t.Name, checkErr)
+			}
+		}
+		tm.Record(ok, timings.Total)
+		if ok {
 			m.IncrementSuccess()
 		} else {
 			m.IncrementFailed()
 		}
+		rec.Status, rec.OK, rec.BytesReceived = resp.StatusCode, ok, n
+		setTimings(&rec, timings)
+		setError(&rec, readErr)
+		switch {
+		case readErr != nil:
+		case !expected:
+			rec.ErrorClass = results.ClassStatus
+		case checkErr != nil:
+			rec.ErrorClass, rec.Error = results.ClassCheck, checkErr.Error()
+		}
+		emit(rec)
 	}
 
 	sm.StartAll()
 	sc.Start(jobFn)
 	log.Info("scheduler started; sessions are now active")
 
+	// ── Hot reload ─────────────────────────────────────────────────────────
+	// Subscribers receive the previous and new snapshot after every
+	// successful reload; restart-only fields never reach them because the
+	// store rejects such changes up front.
+	store.Subscribe(func(prev, next *config.Config) {
+		if prev.LogLevel != next.LogLevel {
+			if level, err := logger.ParseLevel(next.LogLevel); err == nil {
+				log.SetLevel(level)
+			}
+		}
+	})
+	store.Subscribe(func(prev, next *config.Config) {
+		if prev.ProxyFile == next.ProxyFile || next.ProxyFile == "" {
+			return
+		}
+		if err := pm.LoadProxies(next.ProxyFile); err != nil {
+			log.Errorf("reload proxies from %q: %v", next.ProxyFile, err)
+			return
+		}
+		log.Infof("loaded %d proxies from %q for new sessions", pm.Count(), next.ProxyFile)
+	})
+	store.Subscribe(func(prev, next *config.Config) {
+		sm.SetConfig(next)
+		if prev.NumberOfSessions == next.NumberOfSessions {
+			return
+		}
+		if err := sm.Resize(next.NumberOfSessions, pm); err != nil {
+			log.Errorf("resize sessions to %d: %v", next.NumberOfSessions, err)
+		}
+		sm.StartAll()
+		dash.SetActiveSessions(int64(sm.Count()))
+		log.Infof("session pool resized from %d to %d", prev.NumberOfSessions, sm.Count())
+	})
+	store.Subscribe(func(prev, next *config.Config) {
+		set, err := target.NewSetWithFeeders(next.EffectiveTargets(), feeders)
+		if err != nil {
+			log.Errorf("rebuild targets: %v", err)
+			return
+		}
+		targets.Store(set)
+		// Per-target clients built for settings no target uses any more
+		// would otherwise stay open until the session closes.
+		sm.PruneVariants(set.OverrideKeys())
+	})
+	store.Subscribe(func(prev, next *config.Config) {
+		if prev.RateLimit != next.RateLimit {
+			sc.SetRateLimit(next.RateLimit)
+			log.Infof("rate limit changed from %g to %g jobs/s", prev.RateLimit, next.RateLimit)
+		}
+	})
+
+	reportReload := func(trigger string, err error) {
+		if err != nil {
+			log.Errorf("config reload (%s) rejected: %v", trigger, err)
+			dash.AddLog("ERROR", fmt.Sprintf("config reload (%s) rejected: %v", trigger, err))
+			return
+		}
+		log.Infof("config reloaded (%s)", trigger)
+		dash.AddLog("INFO", fmt.Sprintf("config reloaded (%s)", trigger))
+	}
+	stopWatch := make(chan struct{})
+	go store.Watch(stopWatch, 2*time.Second, func(err error) { reportReload("file change", err) })
+
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			reportReload("SIGHUP", store.Reload())
+		}
+	}()
+
 	// ── Metrics monitor ────────────────────────────────────────────────────
 	// Print a summary line every 10 seconds and keep dashboard counters fresh.
 	go func() {
@@ -139,19 +472,39 @@ func main() {
 			count := sm.Count()
 			log.Infof("metrics – total: %d | success: %d | failed: %d | rps: %.1f | sessions: %d",
 				total, success, failed, rps, count)
+			for name, ts := range m.TargetSnapshots() {
+				log.Infof("target %q – total: %d | success: %d | failed: %d | avg latency: %.1f ms",
+					name, ts.Total, ts.Success, ts.Failed, ts.AvgLatencyMs)
+			}
+			if tt := m.TimingSnapshot(); tt.Requests > 0 {
+				log.Infof("timings – dns: %.1f ms | connect: %.1f ms | tls: %.1f ms | ttfb: %.1f ms | body: %.1f ms | reused: %d/%d",
+					tt.AvgDNSMs, tt.AvgConnectMs, tt.AvgTLSMs, tt.AvgTTFBMs, tt.AvgBodyReadMs, tt.ReusedConns, tt.Requests)
+				for proto, n := range tt.Protocols {
+					log.Infof("protocol %s – responses: %d", proto, n)
+				}
+			}
 			dash.SetActiveSessions(int64(count))
 		}
 	}()
 
 	// ── Graceful shutdown ──────────────────────────────────────────────────
+	// The run ends on SIGINT or SIGTERM, or once every target's feeder
+	// has run out of data.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigCh
-	fmt.Println() // newline after ^C
-	log.Infof("received signal %s; shutting down", sig)
-	dash.AddLog("INFO", fmt.Sprintf("received signal %s; shutting down", sig))
+	select {
+	case sig := <-sigCh:
+		fmt.Println() // newline after ^C
+		log.Infof("received signal %s; shutting down", sig)
+		dash.AddLog("INFO", fmt.Sprintf("received signal %s; shutting down", sig))
+	case <-drained:
+		log.Info("every target is out of data; shutting down")
+		dash.AddLog("INFO", "every target is out of data; shutting down")
+	}
 
-	// Stop dispatching new jobs.
+	// Stop reacting to config changes and dispatching new jobs.
+	signal.Stop(hupCh)
+	close(stopWatch)
 	sc.Stop()
 
 	// Wait for in-flight jobs to finish, then shut down workers.
@@ -159,9 +512,123 @@ func main() {
 
 	// Close all sessions and release transport resources.
 	sm.StopAll()
+	if traffic.Recorder != nil {
+		if err := traffic.Recorder.Close(); err != nil {
+			log.Errorf("cassette: %v", err)
+		} else {
+			log.Infof("recorded %d interactions to %q", traffic.Recorder.Len(), cfg.Record.File)
+		}
+	}
+
+	if len(resultSink) > 0 {
+		if err := resultSink.Close(); err != nil {
+			log.Errorf("result sink: %v", err)
+		}
+		for i, st := range resultSink.Stats() {
+			sink := cfg.Results.Sinks[i]
+			log.Infof("results to %s %s: %d written, %d dropped, %d failed writes",
+				sink.Type, cmp.Or(sink.Path, sink.URL), st.Written, st.Dropped, st.Errors)
+		}
+	}
 
 	total, success, failed := m.Snapshot()
 	log.Infof("final metrics – total: %d | success: %d | failed: %d | rps: %.1f",
 		total, success, failed, m.RequestsPerSecond())
+	if cs := m.ChecksSnapshot(); cs.Passed+cs.Failed > 0 {
+		log.Infof("checks – passed: %d | failed: %d", cs.Passed, cs.Failed)
+		for _, tname := range slices.Sorted(maps.Keys(cs.Targets)) {
+			for _, cname := range slices.Sorted(maps.Keys(cs.Targets[tname])) {
+				c := cs.Targets[tname][cname]
+				line := fmt.Sprintf("check %q of target %q – passed: %d | failed: %d", cname, tname, c.Passed, c.Failed)
+				if c.LastFailure != "" {
+					line += " | last failure: " + c.LastFailure
+				}
+				log.Info(line)
+			}
+		}
+	}
+	if bs := m.BreakersSnapshot(); bs.Trips+bs.Rejected > 0 {
+		log.Infof("circuit breakers – trips: %d | short-circuited: %d", bs.Trips, bs.Rejected)
+		for _, key := range slices.Sorted(maps.Keys(bs.Keys)) {
+			b := bs.Keys[key]
+			log.Infof("breaker %q – %s | trips: %d | short-circuited: %d", key, b.State, b.Trips, b.Rejected)
+		}
+	}
 	log.Info("GoSessionEngine shut down cleanly")
 }
+
+// metricsTimings converts a request's traced phases for the metrics
+// package, which does not depend on the client.
+func metricsTimings(t client.Timings) metrics.Timings {
+	return metrics.Timings{
+		DNS:          t.DNS,
+		Connect:      t.Connect,
+		TLSHandshake: t.TLSHandshake,
+		TTFB:         t.TTFB,
+		BodyRead:     t.BodyRead,
+		Total:        t.Total,
+		Reused:       t.Reused,
+		Proto:        t.Proto,
+	}
+}
+
+// breakerMetrics feeds circuit breaker activity to the metrics package,
+// which does not depend on the client.
+type breakerMetrics struct{ m *metrics.Metrics }
+
+func (b breakerMetrics) BreakerStateChanged(key string, _, to client.BreakerState) {
+	b.m.RecordBreakerState(key, to.String())
+}
+
+func (b breakerMetrics) BreakerRejected(key string) { b.m.RecordBreakerRejected(key) }
+
+// openResults builds the sinks described by c, each behind its own queue.
+// It returns an empty Fanout when c lists no sinks.  onError may be nil.
+func openResults(c config.ResultsConfig, onError func(error)) (results.Fanout, error) {
+	var sinks []results.Sink
+	for _, sc := range c.Sinks {
+		switch sc.Type {
+		case "webhook":
+			sinks = append(sinks, results.NewWebhookSink(sc.URL, results.WebhookOptions{Headers: sc.Headers, Timeout: sc.Timeout.Std()}))
+		default:
+			fs, err := results.NewFileSink(sc.Path, results.Format(sc.Type), results.Rotation{
+				MaxBytes: sc.MaxBytes,
+				MaxAge:   sc.MaxAge.Std(),
+				MaxFiles: sc.MaxFiles,
+			})
+			if err != nil {
+				for _, s := range sinks {
+					s.Close()
+				}
+				return nil, err
+			}
+			sinks = append(sinks, fs)
+		}
+	}
+	return results.NewFanout(sinks, results.AsyncOptions{
+		BufferSize:    c.BufferSize,
+		FlushInterval: c.FlushInterval.Std(),
+		OnError:       onError,
+	}), nil
+}
+
+// setTimings copies the traced phases of a request into rec.
+func setTimings(rec *results.Record, t client.Timings) {
+	rec.SetTimings(t.DNS, t.Connect, t.TLSHandshake, t.TTFB, t.BodyRead, t.Total)
+	rec.Reused, rec.Proto = t.Reused, t.Proto
+}
+
+// setError records err in rec, with the classes of the client's own
+// errors, which the results package does not know.
+func setError(rec *results.Record, err error) {
+	rec.SetError(err)
+	if rec.ErrorClass != results.ClassOther {
+		return
+	}
+	switch {
+	case errors.Is(err, client.ErrTooManyRedirects):
+		rec.ErrorClass = results.ClassTooManyRedirects
+	case errors.Is(err, client.ErrCircuitOpen):
+		rec.ErrorClass = results.ClassCircuitOpen
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/client"
+	"github.com/firasghr/GoSessionEngine/results"
+)
+
+func TestSetError_ClassifiesClientErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: stopped after 10", client.ErrTooManyRedirects), results.ClassTooManyRedirects},
+		{&url.Error{Op: "Get", URL: "http://x", Err: &client.CircuitOpenError{Key: "x"}}, results.ClassCircuitOpen},
+		{errors.New("boom"), results.ClassOther},
+	} {
+		var rec results.Record
+		setError(&rec, tc.err)
+		if rec.ErrorClass != tc.want || rec.Error != tc.err.Error() {
+			t.Errorf("setError(%v): got class %q, error %q; want %q", tc.err, rec.ErrorClass, rec.Error, tc.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// breakerStats tracks circuit breakers by key; see RecordBreakerState.
+type breakerStats struct {
+	breakers sync.Map // key -> *breakerCounters
+}
+
+type breakerCounters struct {
+	state    atomic.Pointer[string]
+	trips    uint64
+	rejected uint64
+}
+
+func (s *breakerStats) breaker(key string) *breakerCounters {
+	v, ok := s.breakers.Load(key)
+	if !ok {
+		v, _ = s.breakers.LoadOrStore(key, new(breakerCounters))
+	}
+	return v.(*breakerCounters)
+}
+
+// BreakerSnapshot summarises one circuit breaker.
+type BreakerSnapshot struct {
+	State    string `json:"state"`
+	Trips    uint64 `json:"trips"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// BreakersSnapshot is a point-in-time, JSON-friendly summary of the circuit
+// breakers.  Rejected requests were short-circuited without being sent and
+// are not counted as failed requests.
+type BreakersSnapshot struct {
+	// Open is the number of breakers currently open or half-open.
+	Open     int    `json:"open"`
+	Trips    uint64 `json:"trips"`
+	Rejected uint64 `json:"rejected"`
+
+	// Keys maps hosts (or target names) to their breakers.
+	Keys map[string]BreakerSnapshot `json:"keys"`
+}
+
+// Breaker states passed to RecordBreakerState; they match the names of
+// client.BreakerState.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// RecordBreakerState records that key's breaker entered state.  Entering
+// BreakerOpen counts a trip.
+func (m *Metrics) RecordBreakerState(key, state string) {
+	b := m.breakers.breaker(key)
+	b.state.Store(&state)
+	if state == BreakerOpen {
+		atomic.AddUint64(&b.trips, 1)
+	}
+}
+
+// RecordBreakerRejected counts a request short-circuited by key's breaker.
+func (m *Metrics) RecordBreakerRejected(key string) {
+	atomic.AddUint64(&m.breakers.breaker(key).rejected, 1)
+}
+
+// BreakersSnapshot returns the state of every breaker seen so far.
+// Breakers that have never changed state or rejected a request are not
+// listed.
+func (m *Metrics) BreakersSnapshot() BreakersSnapshot {
+	out := BreakersSnapshot{Keys: make(map[string]BreakerSnapshot)}
+	m.breakers.breakers.Range(func(k, v any) bool {
+		b := v.(*breakerCounters)
+		state := BreakerClosed
+		if p := b.state.Load(); p != nil {
+			state = *p
+		}
+		snap := BreakerSnapshot{
+			State:    state,
+			Trips:    atomic.LoadUint64(&b.trips),
+			Rejected: atomic.LoadUint64(&b.rejected),
+		}
+		out.Keys[k.(string)] = snap
+		if state != BreakerClosed {
+			out.Open++
+		}
+		out.Trips += snap.Trips
+		out.Rejected += snap.Rejected
+		return true
+	})
+	return out
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// checkStats accumulates response check outcomes per target and check
+// name; see RecordCheck.
+type checkStats struct {
+	checks sync.Map // checkKey -> *checkCounters
+}
+
+type checkKey struct{ target, name string }
+
+type checkCounters struct {
+	passed, failed uint64
+	lastFailure    atomic.Pointer[string]
+}
+
+func (s *checkStats) check(target, name string) *checkCounters {
+	k := checkKey{target, name}
+	v, ok := s.checks.Load(k)
+	if !ok {
+		v, _ = s.checks.LoadOrStore(k, new(checkCounters))
+	}
+	return v.(*checkCounters)
+}
+
+// CheckSnapshot summarises one response check.
+type CheckSnapshot struct {
+	Passed uint64 `json:"passed"`
+	Failed uint64 `json:"failed"`
+
+	// LastFailure describes the most recent failure, if any.
+	LastFailure string `json:"last_failure,omitempty"`
+}
+
+// ChecksSnapshot is a point-in-time, JSON-friendly summary of response
+// checks.  Checks are counted separately from transport errors: only
+// requests that received a response run them.
+type ChecksSnapshot struct {
+	Passed uint64 `json:"passed"`
+	Failed uint64 `json:"failed"`
+
+	// Targets maps target names to their checks, keyed by check name.
+	Targets map[string]map[string]CheckSnapshot `json:"targets"`
+}
+
+// RecordCheck counts one evaluation of the named check of target.  A nil
+// err is a pass; otherwise err describes the failure.
+func (m *Metrics) RecordCheck(target, name string, err error) {
+	c := m.checks.check(target, name)
+	if err == nil {
+		atomic.AddUint64(&c.passed, 1)
+		return
+	}
+	atomic.AddUint64(&c.failed, 1)
+	msg := err.Error()
+	c.lastFailure.Store(&msg)
+}
+
+// ChecksSnapshot returns the outcomes of every check seen so far.
+func (m *Metrics) ChecksSnapshot() ChecksSnapshot {
+	out := ChecksSnapshot{Targets: make(map[string]map[string]CheckSnapshot)}
+	m.checks.checks.Range(func(k, v any) bool {
+		key, c := k.(checkKey), v.(*checkCounters)
+		snap := CheckSnapshot{
+			Passed: atomic.LoadUint64(&c.passed),
+			Failed: atomic.LoadUint64(&c.failed),
+		}
+		if p := c.lastFailure.Load(); p != nil {
+			snap.LastFailure = *p
+		}
+		if out.Targets[key.target] == nil {
+			out.Targets[key.target] = make(map[string]CheckSnapshot)
+		}
+		out.Targets[key.target][key.name] = snap
+		out.Passed += snap.Passed
+		out.Failed += snap.Failed
+		return true
+	})
+	return out
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// grpcStats accumulates gRPC target calls recorded with RecordGRPC.
+type grpcStats struct {
+	calls    uint64
+	messages uint64
+	latency  phaseStat
+	codes    sync.Map // status code name -> *uint64
+}
+
+// GRPCSnapshot is a point-in-time, JSON-friendly summary of gRPC calls.
+type GRPCSnapshot struct {
+	Calls uint64 `json:"calls"`
+
+	// Messages counts response messages, including every message of a
+	// server stream.
+	Messages     uint64  `json:"messages"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+
+	// Codes counts calls by status code name ("OK", "Unavailable", ...).
+	Codes map[string]uint64 `json:"codes"`
+}
+
+// RecordGRPC counts one gRPC call that finished with the named status code
+// after receiving messages responses.
+func (m *Metrics) RecordGRPC(code string, messages int, d time.Duration) {
+	s := &m.grpc
+	atomic.AddUint64(&s.calls, 1)
+	atomic.AddUint64(&s.messages, uint64(messages))
+	s.latency.add(d)
+	v, ok := s.codes.Load(code)
+	if !ok {
+		v, _ = s.codes.LoadOrStore(code, new(uint64))
+	}
+	atomic.AddUint64(v.(*uint64), 1)
+}
+
+// GRPCSnapshot returns the aggregated gRPC call statistics.
+func (m *Metrics) GRPCSnapshot() GRPCSnapshot {
+	s := &m.grpc
+	snap := GRPCSnapshot{
+		Calls:        atomic.LoadUint64(&s.calls),
+		Messages:     atomic.LoadUint64(&s.messages),
+		AvgLatencyMs: s.latency.avgMs(),
+		Codes:        make(map[string]uint64),
+	}
+	s.codes.Range(func(k, v any) bool {
+		snap.Codes[k.(string)] = atomic.LoadUint64(v.(*uint64))
+		return true
+	})
+	return snap
+}
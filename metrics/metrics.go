@@ -25,13 +25,41 @@ type Metrics struct {
 	// Success is the number of requests that received a non-error response.
 	Success uint64
 
-	// Failed is the number of requests that resulted in a transport error or
-	// a non-2xx/3xx response (application-level definition of failure).
+	// Failed is the number of requests that resulted in a transport error,
+	// an unexpected status or a failed response check (application-level
+	// definition of failure).
 	Failed uint64
 
 	// startTime records when the metrics instance was created so that
 	// RequestsPerSecond can compute a meaningful rate.
 	startTime time.Time
+
+	// targets holds per-target counters; see Target.
+	targets targetRegistry
+
+	// timings aggregates request phase timings; see RecordTimings.
+	timings timingStats
+
+	// websockets aggregates WebSocket activity; see WebSocketOpened.
+	websockets wsStats
+
+	// sse aggregates Server-Sent Events activity; see SSEEvent.
+	sse sseStats
+
+	// grpc aggregates gRPC target calls; see RecordGRPC.
+	grpc grpcStats
+
+	// redirects aggregates redirect chains; see RecordRedirects.
+	redirects redirectStats
+
+	// shaping aggregates shaped connection traffic; see ShapedIO.
+	shaping shapingStats
+
+	// checks aggregates response check outcomes; see RecordCheck.
+	checks checkStats
+
+	// breakers tracks circuit breaker states; see BreakerStateChanged.
+	breakers breakerStats
 }
 
 // NewMetrics creates a Metrics instance with the start time set to now.
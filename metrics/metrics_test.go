@@ -1,8 +1,10 @@
 package metrics_test
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/firasghr/GoSessionEngine/metrics"
 )
@@ -48,3 +50,185 @@ func TestConcurrentIncrements(t *testing.T) {
 		t.Errorf("Success: got %d, want %d", success, goroutines)
 	}
 }
+
+func TestTargetStats(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.Target("a").Record(true, 10*time.Millisecond)
+	m.Target("a").Record(false, 30*time.Millisecond)
+	m.Target("b").Record(true, 0)
+
+	if m.Target("a") != m.Target("a") {
+		t.Error("Target should return a stable pointer per name")
+	}
+
+	snaps := m.TargetSnapshots()
+	a := snaps["a"]
+	if a.Total != 2 || a.Success != 1 || a.Failed != 1 {
+		t.Errorf("target a: got %+v", a)
+	}
+	if a.AvgLatencyMs != 20 {
+		t.Errorf("target a AvgLatencyMs: got %v, want 20", a.AvgLatencyMs)
+	}
+	if snaps["b"].Total != 1 {
+		t.Errorf("target b: got %+v", snaps["b"])
+	}
+}
+
+func TestRecordTimings(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.RecordTimings(metrics.Timings{
+		DNS: 2 * time.Millisecond, Connect: 4 * time.Millisecond, TLSHandshake: 10 * time.Millisecond,
+		TTFB: 30 * time.Millisecond, BodyRead: 5 * time.Millisecond, Total: 35 * time.Millisecond,
+	})
+	m.RecordTimings(metrics.Timings{
+		Reused: true, Proto: "HTTP/2.0", TTFB: 10 * time.Millisecond, BodyRead: 5 * time.Millisecond, Total: 15 * time.Millisecond,
+	})
+
+	snap := m.TimingSnapshot()
+	if snap.Requests != 2 || snap.ReusedConns != 1 {
+		t.Errorf("counts: got %+v", snap)
+	}
+	// Connection phases average only over requests that opened a connection.
+	if snap.AvgDNSMs != 2 || snap.AvgConnectMs != 4 || snap.AvgTLSMs != 10 {
+		t.Errorf("connection phases: got %+v", snap)
+	}
+	if snap.AvgTTFBMs != 20 || snap.AvgBodyReadMs != 5 || snap.AvgTotalMs != 25 {
+		t.Errorf("request phases: got %+v", snap)
+	}
+	if len(snap.Protocols) != 1 || snap.Protocols["HTTP/2.0"] != 1 {
+		t.Errorf("protocols: got %v, want map[HTTP/2.0:1]", snap.Protocols)
+	}
+}
+
+func TestWebSocketSnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.WebSocketOpened()
+	m.WebSocketOpened()
+	m.WebSocketMessage(true, 10)
+	m.WebSocketMessage(false, 4)
+	m.WebSocketMessage(false, 6)
+	m.WebSocketClosed(2 * time.Second)
+
+	snap := m.WebSocketSnapshot()
+	want := metrics.WebSocketSnapshot{
+		Open: 1, Opened: 2, MessagesSent: 1, MessagesReceived: 2,
+		BytesSent: 10, BytesReceived: 10, AvgLifetimeMs: 2000,
+	}
+	if snap != want {
+		t.Errorf("got %+v, want %+v", snap, want)
+	}
+}
+
+func TestSSESnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.SSEConnected()
+	m.SSEEvent(0, 0)
+	m.SSEEvent(10*time.Millisecond, 0)
+	m.SSEEvent(30*time.Millisecond, 2)
+
+	snap := m.SSESnapshot()
+	if snap.Connects != 1 || snap.Events != 3 || snap.MissedEvents != 2 {
+		t.Errorf("counts: %+v", snap)
+	}
+	if snap.AvgGapMs != 20 || snap.MaxGapMs != 30 {
+		t.Errorf("gaps: got avg %v max %v, want 20 and 30", snap.AvgGapMs, snap.MaxGapMs)
+	}
+	if snap.EventsPerSecond <= 0 {
+		t.Errorf("EventsPerSecond: got %v", snap.EventsPerSecond)
+	}
+}
+
+func TestGRPCSnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.RecordGRPC("OK", 1, 10*time.Millisecond)
+	m.RecordGRPC("OK", 3, 20*time.Millisecond)
+	m.RecordGRPC("Unavailable", 0, 30*time.Millisecond)
+
+	snap := m.GRPCSnapshot()
+	if snap.Calls != 3 || snap.Messages != 4 || snap.AvgLatencyMs != 20 {
+		t.Errorf("got %+v", snap)
+	}
+	if snap.Codes["OK"] != 2 || snap.Codes["Unavailable"] != 1 || len(snap.Codes) != 2 {
+		t.Errorf("Codes: got %v", snap.Codes)
+	}
+}
+
+func TestRedirectSnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.RecordRedirects(0, false)
+	m.RecordRedirects(2, false)
+	m.RecordRedirects(3, true)
+	m.RecordRedirects(0, true)
+	m.RecordRedirectLimit()
+
+	want := metrics.RedirectSnapshot{Redirected: 2, Hops: 5, MaxChain: 3, Stopped: 2, LimitExceeded: 1}
+	if got := m.RedirectSnapshot(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestShapingSnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.ShapedIO("3g", false, 1000, 10*time.Millisecond)
+	m.ShapedIO("3g", false, 1000, 10*time.Millisecond)
+	m.ShapedIO("3g", true, 500, 40*time.Millisecond)
+	m.ShapedDelay("3g", 100*time.Millisecond)
+	m.ShapedDelay("3g", 300*time.Millisecond)
+
+	want := metrics.ShapeProfileSnapshot{
+		BytesDown:    2000,
+		BytesUp:      500,
+		DownloadKbps: 800, // 16 000 bits in 20 ms
+		UploadKbps:   100, // 4 000 bits in 40 ms
+		Delays:       2,
+		AvgDelayMs:   200,
+	}
+	snap := m.ShapingSnapshot()
+	if got := snap.Profiles["3g"]; got != want || len(snap.Profiles) != 1 {
+		t.Errorf("got %+v, want %+v", snap.Profiles, want)
+	}
+}
+
+func TestChecksSnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.RecordCheck("login", "status", nil)
+	m.RecordCheck("login", "status", errors.New("status 500 not in [200]"))
+	m.RecordCheck("login", "token", nil)
+	m.RecordCheck("search", "fast", errors.New("latency 1.2s exceeds 1s"))
+
+	snap := m.ChecksSnapshot()
+	if snap.Passed != 2 || snap.Failed != 2 || len(snap.Targets) != 2 {
+		t.Fatalf("totals: got %+v", snap)
+	}
+	want := metrics.CheckSnapshot{Passed: 1, Failed: 1, LastFailure: "status 500 not in [200]"}
+	if got := snap.Targets["login"]["status"]; got != want {
+		t.Errorf("login/status: got %+v, want %+v", got, want)
+	}
+	if got := snap.Targets["login"]["token"]; got != (metrics.CheckSnapshot{Passed: 1}) {
+		t.Errorf("login/token: got %+v", got)
+	}
+	if got := snap.Targets["search"]["fast"]; got.Failed != 1 || got.LastFailure == "" {
+		t.Errorf("search/fast: got %+v", got)
+	}
+}
+
+func TestBreakersSnapshot(t *testing.T) {
+	m := metrics.NewMetrics()
+	m.RecordBreakerState("api:443", metrics.BreakerOpen)
+	m.RecordBreakerRejected("api:443")
+	m.RecordBreakerRejected("api:443")
+	m.RecordBreakerState("cdn:443", metrics.BreakerOpen)
+	m.RecordBreakerState("cdn:443", metrics.BreakerHalfOpen)
+	m.RecordBreakerState("cdn:443", metrics.BreakerClosed)
+
+	snap := m.BreakersSnapshot()
+	if snap.Open != 1 || snap.Trips != 2 || snap.Rejected != 2 {
+		t.Fatalf("totals: got %+v", snap)
+	}
+	if got := snap.Keys["api:443"]; got != (metrics.BreakerSnapshot{State: "open", Trips: 1, Rejected: 2}) {
+		t.Errorf("api:443: got %+v", got)
+	}
+	if got := snap.Keys["cdn:443"]; got != (metrics.BreakerSnapshot{State: "closed", Trips: 1}) {
+		t.Errorf("cdn:443: got %+v", got)
+	}
+}
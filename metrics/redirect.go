@@ -0,0 +1,69 @@
+package metrics
+
+import "sync/atomic"
+
+// redirectStats accumulates redirect chains recorded with RecordRedirects.
+type redirectStats struct {
+	redirected uint64 // responses reached through at least one redirect
+	hops       uint64
+	maxChain   uint64
+	stopped    uint64 // redirect responses returned unfollowed
+	limit      uint64 // requests that hit the hop limit
+}
+
+// RedirectSnapshot is a point-in-time, JSON-friendly summary of redirects.
+type RedirectSnapshot struct {
+	// Redirected counts responses reached after one or more redirects.
+	Redirected uint64 `json:"redirected"`
+
+	// Hops is the total number of redirects followed; MaxChain is the
+	// longest chain seen.
+	Hops     uint64 `json:"hops"`
+	MaxChain uint64 `json:"max_chain"`
+
+	// Stopped counts redirect responses the policy did not follow.
+	Stopped uint64 `json:"stopped"`
+
+	// LimitExceeded counts requests that failed on the hop limit.
+	LimitExceeded uint64 `json:"limit_exceeded"`
+}
+
+// RecordRedirects counts a response reached through hops redirects.
+// stopped reports that the response is itself a redirect the policy did not
+// follow.
+func (m *Metrics) RecordRedirects(hops int, stopped bool) {
+	s := &m.redirects
+	if stopped {
+		atomic.AddUint64(&s.stopped, 1)
+	}
+	if hops <= 0 {
+		return
+	}
+	n := uint64(hops)
+	atomic.AddUint64(&s.redirected, 1)
+	atomic.AddUint64(&s.hops, n)
+	for {
+		cur := atomic.LoadUint64(&s.maxChain)
+		if n <= cur || atomic.CompareAndSwapUint64(&s.maxChain, cur, n) {
+			break
+		}
+	}
+}
+
+// RecordRedirectLimit counts a request that failed because it was
+// redirected more often than its policy allows.
+func (m *Metrics) RecordRedirectLimit() {
+	atomic.AddUint64(&m.redirects.limit, 1)
+}
+
+// RedirectSnapshot returns the aggregated redirect counters.
+func (m *Metrics) RedirectSnapshot() RedirectSnapshot {
+	s := &m.redirects
+	return RedirectSnapshot{
+		Redirected:    atomic.LoadUint64(&s.redirected),
+		Hops:          atomic.LoadUint64(&s.hops),
+		MaxChain:      atomic.LoadUint64(&s.maxChain),
+		Stopped:       atomic.LoadUint64(&s.stopped),
+		LimitExceeded: atomic.LoadUint64(&s.limit),
+	}
+}
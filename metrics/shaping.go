@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shapingStats accumulates the activity of shaped connections reported
+// through the client.ShapeObserver methods below, per profile.
+type shapingStats struct {
+	profiles sync.Map // profile name -> *shapeProfileStats
+}
+
+type shapeProfileStats struct {
+	down, up           uint64 // bytes
+	downNanos, upNanos uint64 // time spent in reads and writes
+	delay              phaseStat
+}
+
+func (s *shapingStats) profile(name string) *shapeProfileStats {
+	v, ok := s.profiles.Load(name)
+	if !ok {
+		v, _ = s.profiles.LoadOrStore(name, new(shapeProfileStats))
+	}
+	return v.(*shapeProfileStats)
+}
+
+// ShapeProfileSnapshot summarises the connections of one shaping profile.
+type ShapeProfileSnapshot struct {
+	BytesDown uint64 `json:"bytes_down"`
+	BytesUp   uint64 `json:"bytes_up"`
+
+	// DownloadKbps and UploadKbps are the effective throughput in kilobits
+	// per second: bytes moved divided by the time spent reading or
+	// writing them.  Read time includes waiting for the server, so the
+	// download figure is a lower bound on the shaped link rate.
+	DownloadKbps float64 `json:"download_kbps"`
+	UploadKbps   float64 `json:"upload_kbps"`
+
+	// Delays counts injected latencies; AvgDelayMs is their mean.
+	Delays     uint64  `json:"delays"`
+	AvgDelayMs float64 `json:"avg_delay_ms"`
+}
+
+// ShapingSnapshot is a point-in-time, JSON-friendly summary of shaped
+// traffic, keyed by profile name.
+type ShapingSnapshot struct {
+	Profiles map[string]ShapeProfileSnapshot `json:"profiles"`
+}
+
+// ShapedIO counts n bytes read (upload false) or written on a connection
+// shaped with the named profile, taking d.
+func (m *Metrics) ShapedIO(profile string, upload bool, n int, d time.Duration) {
+	p := m.shaping.profile(profile)
+	if upload {
+		atomic.AddUint64(&p.up, uint64(n))
+		atomic.AddUint64(&p.upNanos, uint64(max(d, 0)))
+		return
+	}
+	atomic.AddUint64(&p.down, uint64(n))
+	atomic.AddUint64(&p.downNanos, uint64(max(d, 0)))
+}
+
+// ShapedDelay counts one latency injected by the named profile.
+func (m *Metrics) ShapedDelay(profile string, d time.Duration) {
+	m.shaping.profile(profile).delay.add(d)
+}
+
+// ShapingSnapshot returns the aggregated shaped traffic.
+func (m *Metrics) ShapingSnapshot() ShapingSnapshot {
+	snap := ShapingSnapshot{Profiles: make(map[string]ShapeProfileSnapshot)}
+	m.shaping.profiles.Range(func(k, v any) bool {
+		p := v.(*shapeProfileStats)
+		down, up := atomic.LoadUint64(&p.down), atomic.LoadUint64(&p.up)
+		snap.Profiles[k.(string)] = ShapeProfileSnapshot{
+			BytesDown:    down,
+			BytesUp:      up,
+			DownloadKbps: kbps(down, atomic.LoadUint64(&p.downNanos)),
+			UploadKbps:   kbps(up, atomic.LoadUint64(&p.upNanos)),
+			Delays:       atomic.LoadUint64(&p.delay.count),
+			AvgDelayMs:   p.delay.avgMs(),
+		}
+		return true
+	})
+	return snap
+}
+
+// kbps converts bytes moved in nanos nanoseconds to kilobits per second.
+func kbps(bytes, nanos uint64) float64 {
+	if nanos == 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / 1000 / (float64(nanos) / float64(time.Second))
+}
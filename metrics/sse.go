@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// sseStats accumulates Server-Sent Events activity reported through the
+// session.SSEObserver methods below.
+type sseStats struct {
+	connects uint64
+	events   uint64
+	missed   uint64
+	gap      phaseStat
+	maxGap   uint64 // nanoseconds
+}
+
+// SSESnapshot is a point-in-time, JSON-friendly summary of event-stream
+// activity across all subscriptions.
+type SSESnapshot struct {
+	Connects        uint64  `json:"connects"`
+	Events          uint64  `json:"events"`
+	EventsPerSecond float64 `json:"events_per_second"`
+
+	// MissedEvents counts events skipped according to numeric event IDs.
+	MissedEvents uint64 `json:"missed_events"`
+
+	// AvgGapMs and MaxGapMs describe the time between consecutive events
+	// of a subscription.
+	AvgGapMs float64 `json:"avg_gap_ms"`
+	MaxGapMs float64 `json:"max_gap_ms"`
+}
+
+// SSEConnected counts a successful event-stream connection, including
+// reconnects.
+func (m *Metrics) SSEConnected() {
+	atomic.AddUint64(&m.sse.connects, 1)
+}
+
+// SSEEvent counts one received event, the time since the subscription's
+// previous event and the number of events missed before it.
+func (m *Metrics) SSEEvent(gap time.Duration, missed uint64) {
+	s := &m.sse
+	atomic.AddUint64(&s.events, 1)
+	if missed > 0 {
+		atomic.AddUint64(&s.missed, missed)
+	}
+	s.gap.add(gap)
+	for {
+		cur := atomic.LoadUint64(&s.maxGap)
+		if uint64(gap) <= cur || atomic.CompareAndSwapUint64(&s.maxGap, cur, uint64(gap)) {
+			break
+		}
+	}
+}
+
+// SSESnapshot returns the aggregated event-stream activity.  The event rate
+// is averaged since the Metrics instance was created.
+func (m *Metrics) SSESnapshot() SSESnapshot {
+	s := &m.sse
+	events := atomic.LoadUint64(&s.events)
+	var rate float64
+	if elapsed := time.Since(m.startTime).Seconds(); elapsed > 0 {
+		rate = float64(events) / elapsed
+	}
+	return SSESnapshot{
+		Connects:        atomic.LoadUint64(&s.connects),
+		Events:          events,
+		EventsPerSecond: rate,
+		MissedEvents:    atomic.LoadUint64(&s.missed),
+		AvgGapMs:        s.gap.avgMs(),
+		MaxGapMs:        float64(atomic.LoadUint64(&s.maxGap)) / float64(time.Millisecond),
+	}
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TargetStats holds the counters for a single named target.  Like Metrics,
+// every field is updated atomically so workers never contend on a lock.
+type TargetStats struct {
+	// Total is the number of requests sent to the target.
+	Total uint64
+
+	// Success is the number of responses whose status matched the target's
+	// expectations and that passed its checks.
+	Success uint64
+
+	// Failed is the number of transport errors plus unexpected statuses and
+	// failed checks.
+	Failed uint64
+
+	// LatencyNanos is the summed request latency, used to derive the mean.
+	LatencyNanos uint64
+}
+
+// Record counts one completed request to the target.
+func (t *TargetStats) Record(success bool, latency time.Duration) {
+	atomic.AddUint64(&t.Total, 1)
+	if success {
+		atomic.AddUint64(&t.Success, 1)
+	} else {
+		atomic.AddUint64(&t.Failed, 1)
+	}
+	if latency > 0 {
+		atomic.AddUint64(&t.LatencyNanos, uint64(latency))
+	}
+}
+
+// TargetSnapshot is a point-in-time, JSON-friendly copy of TargetStats.
+type TargetSnapshot struct {
+	Total        uint64  `json:"total"`
+	Success      uint64  `json:"success"`
+	Failed       uint64  `json:"failed"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// targetRegistry maps target names to *TargetStats.  sync.Map suits the
+// access pattern: a handful of keys written once, then read on every request.
+type targetRegistry struct {
+	m sync.Map // string -> *TargetStats
+}
+
+// Target returns the stats for name, creating them on first use.  Safe for
+// concurrent use; the returned pointer is stable for the life of m.
+func (m *Metrics) Target(name string) *TargetStats {
+	if v, ok := m.targets.m.Load(name); ok {
+		return v.(*TargetStats)
+	}
+	v, _ := m.targets.m.LoadOrStore(name, &TargetStats{})
+	return v.(*TargetStats)
+}
+
+// TargetSnapshots returns a snapshot of every target seen so far, keyed by
+// target name.
+func (m *Metrics) TargetSnapshots() map[string]TargetSnapshot {
+	out := make(map[string]TargetSnapshot)
+	m.targets.m.Range(func(k, v any) bool {
+		t := v.(*TargetStats)
+		snap := TargetSnapshot{
+			Total:   atomic.LoadUint64(&t.Total),
+			Success: atomic.LoadUint64(&t.Success),
+			Failed:  atomic.LoadUint64(&t.Failed),
+		}
+		if snap.Total > 0 {
+			snap.AvgLatencyMs = float64(atomic.LoadUint64(&t.LatencyNanos)) / float64(snap.Total) / float64(time.Millisecond)
+		}
+		out[k.(string)] = snap
+		return true
+	})
+	return out
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// timingStats accumulates request phase timings.  Each phase keeps its own
+// sample count because DNS, connect and TLS only occur on new connections.
+type timingStats struct {
+	requests  uint64
+	reused    uint64
+	dns       phaseStat
+	connect   phaseStat
+	tls       phaseStat
+	ttfb      phaseStat
+	bodyRead  phaseStat
+	totalTime phaseStat
+	protocols sync.Map // response protocol -> *uint64
+}
+
+type phaseStat struct {
+	count uint64
+	nanos uint64
+}
+
+func (p *phaseStat) add(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	atomic.AddUint64(&p.count, 1)
+	atomic.AddUint64(&p.nanos, uint64(d))
+}
+
+func (p *phaseStat) avgMs() float64 {
+	n := atomic.LoadUint64(&p.count)
+	if n == 0 {
+		return 0
+	}
+	return float64(atomic.LoadUint64(&p.nanos)) / float64(n) / float64(time.Millisecond)
+}
+
+// TimingSnapshot is a point-in-time, JSON-friendly summary of the recorded
+// request timings.  Each average is taken over the requests in which that
+// phase occurred.
+type TimingSnapshot struct {
+	Requests      uint64  `json:"requests"`
+	ReusedConns   uint64  `json:"reused_conns"`
+	AvgDNSMs      float64 `json:"avg_dns_ms"`
+	AvgConnectMs  float64 `json:"avg_connect_ms"`
+	AvgTLSMs      float64 `json:"avg_tls_ms"`
+	AvgTTFBMs     float64 `json:"avg_ttfb_ms"`
+	AvgBodyReadMs float64 `json:"avg_body_read_ms"`
+	AvgTotalMs    float64 `json:"avg_total_ms"`
+
+	// Protocols counts responses by negotiated protocol ("HTTP/1.1",
+	// "HTTP/2.0", …).
+	Protocols map[string]uint64 `json:"protocols,omitempty"`
+}
+
+// Timings is one request's phase breakdown, as measured by client.Trace.
+// Phases that did not occur are zero.
+type Timings struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	TTFB         time.Duration
+	BodyRead     time.Duration
+	Total        time.Duration
+
+	// Reused reports whether the request was sent on a pooled connection.
+	Reused bool
+
+	// Proto is the response protocol, e.g. "HTTP/2.0".
+	Proto string
+}
+
+// RecordTimings adds one request's phase breakdown to the aggregate.
+func (m *Metrics) RecordTimings(t Timings) {
+	ts := &m.timings
+	atomic.AddUint64(&ts.requests, 1)
+	if t.Reused {
+		atomic.AddUint64(&ts.reused, 1)
+	}
+	ts.dns.add(t.DNS)
+	ts.connect.add(t.Connect)
+	ts.tls.add(t.TLSHandshake)
+	ts.ttfb.add(t.TTFB)
+	ts.bodyRead.add(t.BodyRead)
+	ts.totalTime.add(t.Total)
+	if t.Proto != "" {
+		n, ok := ts.protocols.Load(t.Proto)
+		if !ok {
+			n, _ = ts.protocols.LoadOrStore(t.Proto, new(uint64))
+		}
+		atomic.AddUint64(n.(*uint64), 1)
+	}
+}
+
+// TimingSnapshot returns the aggregated request timings.
+func (m *Metrics) TimingSnapshot() TimingSnapshot {
+	ts := &m.timings
+	var protocols map[string]uint64
+	ts.protocols.Range(func(k, v any) bool {
+		if protocols == nil {
+			protocols = make(map[string]uint64)
+		}
+		protocols[k.(string)] = atomic.LoadUint64(v.(*uint64))
+		return true
+	})
+	return TimingSnapshot{
+		Requests:      atomic.LoadUint64(&ts.requests),
+		ReusedConns:   atomic.LoadUint64(&ts.reused),
+		AvgDNSMs:      ts.dns.avgMs(),
+		AvgConnectMs:  ts.connect.avgMs(),
+		AvgTLSMs:      ts.tls.avgMs(),
+		AvgTTFBMs:     ts.ttfb.avgMs(),
+		AvgBodyReadMs: ts.bodyRead.avgMs(),
+		AvgTotalMs:    ts.totalTime.avgMs(),
+		Protocols:     protocols,
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// wsStats accumulates WebSocket activity reported through the
+// session.WebSocketObserver methods below.
+type wsStats struct {
+	opened        uint64
+	closed        uint64
+	sent          uint64
+	received      uint64
+	bytesSent     uint64
+	bytesReceived uint64
+	lifetime      phaseStat
+}
+
+// WebSocketSnapshot is a point-in-time, JSON-friendly summary of WebSocket
+// activity.
+type WebSocketSnapshot struct {
+	// Open is the number of connections currently open.
+	Open             int64   `json:"open"`
+	Opened           uint64  `json:"opened"`
+	MessagesSent     uint64  `json:"messages_sent"`
+	MessagesReceived uint64  `json:"messages_received"`
+	BytesSent        uint64  `json:"bytes_sent"`
+	BytesReceived    uint64  `json:"bytes_received"`
+	AvgLifetimeMs    float64 `json:"avg_lifetime_ms"`
+}
+
+// WebSocketOpened counts a completed WebSocket handshake, including
+// reconnects.
+func (m *Metrics) WebSocketOpened() {
+	atomic.AddUint64(&m.websockets.opened, 1)
+}
+
+// WebSocketMessage counts one WebSocket message of n payload bytes.
+func (m *Metrics) WebSocketMessage(sent bool, n int) {
+	ws := &m.websockets
+	if sent {
+		atomic.AddUint64(&ws.sent, 1)
+		atomic.AddUint64(&ws.bytesSent, uint64(n))
+		return
+	}
+	atomic.AddUint64(&ws.received, 1)
+	atomic.AddUint64(&ws.bytesReceived, uint64(n))
+}
+
+// WebSocketClosed counts the end of a WebSocket connection that was open for
+// lifetime.
+func (m *Metrics) WebSocketClosed(lifetime time.Duration) {
+	atomic.AddUint64(&m.websockets.closed, 1)
+	m.websockets.lifetime.add(lifetime)
+}
+
+// WebSocketSnapshot returns the aggregated WebSocket activity.
+func (m *Metrics) WebSocketSnapshot() WebSocketSnapshot {
+	ws := &m.websockets
+	closed := atomic.LoadUint64(&ws.closed)
+	opened := atomic.LoadUint64(&ws.opened)
+	return WebSocketSnapshot{
+		Open:             int64(opened) - int64(closed),
+		Opened:           opened,
+		MessagesSent:     atomic.LoadUint64(&ws.sent),
+		MessagesReceived: atomic.LoadUint64(&ws.received),
+		BytesSent:        atomic.LoadUint64(&ws.bytesSent),
+		BytesReceived:    atomic.LoadUint64(&ws.bytesReceived),
+		AvgLifetimeMs:    ws.lifetime.avgMs(),
+	}
+}
@@ -0,0 +1,222 @@
+package results
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format selects the encoding of a FileSink.
+type Format string
+
+const (
+	// FormatJSONL writes one JSON object per line.
+	FormatJSONL Format = "jsonl"
+
+	// FormatCSV writes a header row followed by one row per record.
+	FormatCSV Format = "csv"
+)
+
+// csvHeader names the CSV columns, in the order written by csvRow.
+var csvHeader = []string{
+	"time", "session", "target", "method", "url", "status", "grpc_code", "ok",
+	"dns_ms", "connect_ms", "tls_ms", "ttfb_ms", "body_read_ms", "total_ms",
+	"reused", "proto", "bytes_sent", "bytes_received", "error_class", "error",
+}
+
+func csvRow(r Record) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	return []string{
+		r.Time.UTC().Format(time.RFC3339Nano),
+		strconv.Itoa(r.Session),
+		r.Target,
+		r.Method,
+		r.URL,
+		strconv.Itoa(r.Status),
+		r.GRPCCode,
+		strconv.FormatBool(r.OK),
+		f(r.DNSMs), f(r.ConnectMs), f(r.TLSMs), f(r.TTFBMs), f(r.BodyReadMs), f(r.TotalMs),
+		strconv.FormatBool(r.Reused),
+		r.Proto,
+		strconv.FormatInt(r.BytesSent, 10),
+		strconv.FormatInt(r.BytesReceived, 10),
+		r.ErrorClass,
+		r.Error,
+	}
+}
+
+// Rotation decides when a FileSink starts a new file.  The zero value never
+// rotates.
+type Rotation struct {
+	// MaxBytes rotates once the current file reaches this size.
+	MaxBytes int64
+
+	// MaxAge rotates files older than this.
+	MaxAge time.Duration
+
+	// MaxFiles caps the rotated files kept; older ones are deleted.  Zero
+	// keeps them all.
+	MaxFiles int
+}
+
+// FileSink writes records to a file in JSONL or CSV, buffering writes and
+// rotating the file according to a Rotation.  Rotated files are renamed
+// with a timestamp before the extension, e.g. results-20240102T150405.000.jsonl.
+type FileSink struct {
+	path   string
+	format Format
+	rot    Rotation
+
+	f       *os.File
+	w       *bufio.Writer
+	csv     *csv.Writer
+	size    int64
+	opened  time.Time
+	rotated []string // rotated files, oldest first
+}
+
+// FormatFor returns FormatCSV for a .csv path and FormatJSONL otherwise.
+func FormatFor(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSONL
+}
+
+// NewFileSink creates (truncating) path and returns a sink writing format
+// to it.  An empty format is chosen from the extension; see FormatFor.
+func NewFileSink(path string, format Format, rot Rotation) (*FileSink, error) {
+	if format == "" {
+		format = FormatFor(path)
+	}
+	if format != FormatJSONL && format != FormatCSV {
+		return nil, fmt.Errorf("results: unknown format %q (want jsonl or csv)", format)
+	}
+	s := &FileSink{path: path, format: format, rot: rot}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.Create(s.path)
+	if err != nil {
+		return fmt.Errorf("results: create %q: %w", s.path, err)
+	}
+	s.f, s.size, s.opened = f, 0, time.Now()
+	s.w = bufio.NewWriterSize(countingWriter{s}, 64<<10)
+	if s.format == FormatCSV {
+		s.csv = csv.NewWriter(s.w)
+		if err := s.csv.Write(csvHeader); err != nil {
+			return fmt.Errorf("results: write %q: %w", s.path, err)
+		}
+	}
+	return nil
+}
+
+// countingWriter writes to the sink's file and tracks its size.
+type countingWriter struct{ s *FileSink }
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.s.f.Write(p)
+	c.s.size += int64(n)
+	return n, err
+}
+
+// Write appends recs and flushes them to the file, rotating first if the
+// current file is due.
+func (s *FileSink) Write(recs []Record) error {
+	if s.due() {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range recs {
+		if err := s.encode(r); err != nil {
+			return fmt.Errorf("results: write %q: %w", s.path, err)
+		}
+	}
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return fmt.Errorf("results: write %q: %w", s.path, err)
+		}
+	}
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("results: write %q: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *FileSink) encode(r Record) error {
+	if s.csv != nil {
+		return s.csv.Write(csvRow(r))
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// due reports whether the current file should be rotated before writing.
+func (s *FileSink) due() bool {
+	return (s.rot.MaxBytes > 0 && s.size >= s.rot.MaxBytes) ||
+		(s.rot.MaxAge > 0 && time.Since(s.opened) >= s.rot.MaxAge)
+}
+
+// rotate renames the current file aside, opens a fresh one and prunes old
+// rotated files.
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("results: close %q: %w", s.path, err)
+	}
+	ext := filepath.Ext(s.path)
+	stamp := time.Now().UTC().Format("20060102T150405.000")
+	name := strings.TrimSuffix(s.path, ext) + "-" + stamp + ext
+	for i := 1; fileExists(name); i++ {
+		name = fmt.Sprintf("%s-%s-%d%s", strings.TrimSuffix(s.path, ext), stamp, i, ext)
+	}
+	if err := os.Rename(s.path, name); err != nil {
+		return fmt.Errorf("results: rotate %q: %w", s.path, err)
+	}
+	s.rotated = append(s.rotated, name)
+	if n := s.rot.MaxFiles; n > 0 && len(s.rotated) > n {
+		for _, old := range s.rotated[:len(s.rotated)-n] {
+			os.Remove(old)
+		}
+		s.rotated = append([]string(nil), s.rotated[len(s.rotated)-n:]...)
+	}
+	return s.open()
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// Rotated returns the rotated files still on disk, oldest first.
+func (s *FileSink) Rotated() []string { return append([]string(nil), s.rotated...) }
+
+// Close flushes and closes the current file.
+func (s *FileSink) Close() error {
+	if s.csv != nil {
+		s.csv.Flush()
+	}
+	werr := s.w.Flush()
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("results: close %q: %w", s.path, err)
+	}
+	if werr != nil {
+		return fmt.Errorf("results: write %q: %w", s.path, werr)
+	}
+	return nil
+}
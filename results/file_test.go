@@ -0,0 +1,100 @@
+package results_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/results"
+)
+
+func sampleRecord(session int) results.Record {
+	return results.Record{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Session: session,
+		Target:  "api",
+		Method:  "GET",
+		URL:     "https://example.com/a,b",
+		Status:  200,
+		OK:      true,
+		TotalMs: 12.5,
+		Error:   `quote " and, comma`,
+	}
+}
+
+func TestFileSink_JSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := results.NewFileSink(path, "", results.Rotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write([]results.Record{sampleRecord(1), sampleRecord(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []results.Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r results.Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != 2 || got[1] != sampleRecord(2) {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestFileSink_CSVRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.csv")
+	s, err := results.NewFileSink(path, "", results.Rotation{MaxBytes: 1, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every batch after the first finds the file over MaxBytes and rotates.
+	for i := range 4 {
+		if err := s.Write([]results.Record{sampleRecord(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := s.Rotated()
+	if len(rotated) != 2 {
+		t.Fatalf("got %d rotated files, want 2 (MaxFiles)", len(rotated))
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "results*.csv"))
+	if len(matches) != 3 {
+		t.Errorf("got files %v, want the current file and 2 rotated ones", matches)
+	}
+	// Each file has its own header; the newest rotated file holds session 2.
+	for file, session := range map[string]string{rotated[1]: "2", path: "3"} {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[0][0] != "time" || rows[1][1] != session || rows[1][4] != "https://example.com/a,b" {
+			t.Errorf("%s: got rows %q", filepath.Base(file), rows)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Package results streams one record per request to pluggable sinks –
+// rotated JSONL or CSV files and HTTP webhooks – for offline analysis.
+//
+// Workers hand records to an Async dispatcher, which never blocks: records
+// are queued and written in batches by a background goroutine, and dropped
+// (and counted) if the queue is full.  A Fanout gives each of several sinks
+// its own Async, so a slow sink cannot hold up the others.
+//
+// The package depends on neither the client nor the config; the caller
+// builds sinks from its configuration and fills records from its traces.
+package results
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Record describes the outcome of one request.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Session int       `json:"session"`
+	Target  string    `json:"target"`
+	Method  string    `json:"method,omitempty"`
+	URL     string    `json:"url,omitempty"`
+
+	// Status is the HTTP status code, or zero when no response arrived.
+	// gRPC calls report their status code name in GRPCCode instead.
+	Status   int    `json:"status"`
+	GRPCCode string `json:"grpc_code,omitempty"`
+
+	// OK reports whether the target's expectations were met.
+	OK bool `json:"ok"`
+
+	// Phase timings in milliseconds; see SetTimings.
+	DNSMs      float64 `json:"dns_ms"`
+	ConnectMs  float64 `json:"connect_ms"`
+	TLSMs      float64 `json:"tls_ms"`
+	TTFBMs     float64 `json:"ttfb_ms"`
+	BodyReadMs float64 `json:"body_read_ms"`
+	TotalMs    float64 `json:"total_ms"`
+	Reused     bool    `json:"reused"`
+	Proto      string  `json:"proto,omitempty"`
+
+	// BytesSent is the request body size; BytesReceived is the number of
+	// response body bytes read, after decompression.
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+
+	// ErrorClass groups failures for analysis; see Classify.  Error holds
+	// the error text.
+	ErrorClass string `json:"error_class,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SetTimings sets the phase timings of r, given as durations.  Phases
+// that did not occur are zero.
+func (r *Record) SetTimings(dns, connect, tls, ttfb, bodyRead, total time.Duration) {
+	r.DNSMs = ms(dns)
+	r.ConnectMs = ms(connect)
+	r.TLSMs = ms(tls)
+	r.TTFBMs = ms(ttfb)
+	r.BodyReadMs = ms(bodyRead)
+	r.TotalMs = ms(total)
+}
+
+// SetError records err and its class.  A nil err clears both.
+func (r *Record) SetError(err error) {
+	r.ErrorClass, r.Error = Classify(err), ""
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+// Error classes returned by Classify.
+const (
+	ClassTimeout   = "timeout"
+	ClassCanceled  = "canceled"
+	ClassDNS       = "dns"
+	ClassRefused   = "connection_refused"
+	ClassReset     = "connection_reset"
+	ClassTLS       = "tls"
+	ClassTruncated = "truncated"
+	ClassOther     = "other"
+
+	// ClassTooManyRedirects and ClassCircuitOpen mark errors of the
+	// client's redirect limit and circuit breakers.  Classify returns
+	// ClassOther for them; callers that know the client set them.
+	ClassTooManyRedirects = "too_many_redirects"
+	ClassCircuitOpen      = "circuit_open"
+
+	// ClassStatus marks a response whose status the target did not
+	// expect.  Classify never returns it; callers set it.
+	ClassStatus = "unexpected_status"
+
+	// ClassCheck marks a response that failed one of the target's checks.
+	// Classify never returns it; callers set it.
+	ClassCheck = "check_failed"
+)
+
+// Classify returns a short, stable class for err, or "" for nil.
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+	var (
+		dnsErr  *net.DNSError
+		netErr  net.Error
+		certErr *tls.CertificateVerificationError
+		recErr  tls.RecordHeaderError
+		alert   tls.AlertError
+	)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ClassTimeout
+	case errors.As(err, &dnsErr):
+		return ClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ClassRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ClassReset
+	case errors.As(err, &certErr), errors.As(err, &recErr), errors.As(err, &alert):
+		return ClassTLS
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return ClassTruncated
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Timeout() {
+		return ClassTimeout
+	}
+	return ClassOther
+}
+
+// Sink receives batches of records.  Write and Close are called from a
+// single goroutine by Async, so implementations need no locking of their
+// own.
+type Sink interface {
+	// Write stores recs.  An error is reported but the batch is not
+	// retried.  recs is reused after Write returns.
+	Write(recs []Record) error
+
+	// Close flushes buffered records and releases resources.
+	Close() error
+}
+
+// AsyncOptions tunes NewAsync.  Start from DefaultAsyncOptions.
+type AsyncOptions struct {
+	// BufferSize is the number of records queued before new ones are
+	// dropped.
+	BufferSize int
+
+	// BatchSize caps the records passed to one Sink.Write.
+	BatchSize int
+
+	// FlushInterval bounds how long a record waits before it is written.
+	FlushInterval time.Duration
+
+	// OnError, if non-nil, is called from the writer goroutine with every
+	// error returned by the sink.
+	OnError func(error)
+}
+
+// DefaultAsyncOptions queues up to 10 000 records and writes them at least
+// once a second, in batches of up to 500.
+func DefaultAsyncOptions() AsyncOptions {
+	return AsyncOptions{BufferSize: 10_000, BatchSize: 500, FlushInterval: time.Second}
+}
+
+// Async queues records for a Sink and writes them on a background
+// goroutine.  Record never blocks.
+type Async struct {
+	sink Sink
+	opts AsyncOptions
+	ch   chan Record
+	done chan struct{}
+
+	mu     sync.RWMutex // guards closed against concurrent Record
+	closed bool
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	errors  atomic.Uint64
+}
+
+// NewAsync starts the writer goroutine for sink.  Zero fields of opts take
+// their DefaultAsyncOptions values.
+func NewAsync(sink Sink, opts AsyncOptions) *Async {
+	def := DefaultAsyncOptions()
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = def.BufferSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = def.BatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = def.FlushInterval
+	}
+	a := &Async{
+		sink: sink,
+		opts: opts,
+		ch:   make(chan Record, opts.BufferSize),
+		done: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Record queues r.  If the queue is full, or a is closed, r is dropped.
+func (a *Async) Record(r Record) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+	select {
+	case a.ch <- r:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Close writes the queued records, closes the sink and returns its error.
+func (a *Async) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.ch)
+	a.mu.Unlock()
+	<-a.done
+	return a.sink.Close()
+}
+
+// AsyncStats counts what happened to recorded results.
+type AsyncStats struct {
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Errors  uint64 `json:"errors"`
+}
+
+// Stats returns the records written and dropped so far, and the number of
+// failed sink writes.
+func (a *Async) Stats() AsyncStats {
+	return AsyncStats{Written: a.written.Load(), Dropped: a.dropped.Load(), Errors: a.errors.Load()}
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	t := time.NewTicker(a.opts.FlushInterval)
+	defer t.Stop()
+	batch := make([]Record, 0, a.opts.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := a.sink.Write(batch); err != nil {
+			a.errors.Add(1)
+			if a.opts.OnError != nil {
+				a.opts.OnError(err)
+			}
+		} else {
+			a.written.Add(uint64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case r, ok := <-a.ch:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, r)
+			if len(batch) >= a.opts.BatchSize {
+				flush()
+			}
+		case <-t.C:
+			flush()
+		}
+	}
+}
+
+// Fanout queues every record for several sinks, each through its own
+// Async.  A sink that stalls, such as a webhook waiting out its timeout,
+// only fills and drops from its own queue; the others keep writing.
+type Fanout []*Async
+
+// NewFanout starts one Async per sink with opts.
+func NewFanout(sinks []Sink, opts AsyncOptions) Fanout {
+	f := make(Fanout, len(sinks))
+	for i, s := range sinks {
+		f[i] = NewAsync(s, opts)
+	}
+	return f
+}
+
+// Record queues r for every sink.  It never blocks.
+func (f Fanout) Record(r Record) {
+	for _, a := range f {
+		a.Record(r)
+	}
+}
+
+// Close closes every Async, concurrently so one slow sink does not delay
+// the others, and returns their joined errors.
+func (f Fanout) Close() error {
+	errs := make([]error, len(f))
+	var wg sync.WaitGroup
+	for i, a := range f {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = a.Close()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Stats returns the stats of every sink, in order.
+func (f Fanout) Stats() []AsyncStats {
+	st := make([]AsyncStats, len(f))
+	for i, a := range f {
+		st[i] = a.Stats()
+	}
+	return st
+}
@@ -0,0 +1,166 @@
+package results_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+	"github.com/firasghr/GoSessionEngine/results"
+)
+
+// memSink keeps every record written to it.  block, if set, holds Write
+// until it is closed.
+type memSink struct {
+	mu     sync.Mutex
+	recs   []results.Record
+	writes int
+	closed bool
+	block  chan struct{}
+	err    error
+}
+
+func (s *memSink) Write(recs []results.Record) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recs = append(s.recs, recs...)
+	s.writes++
+	return s.err
+}
+
+func (s *memSink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func TestAsync_BatchesAndFlushesOnClose(t *testing.T) {
+	sink := &memSink{}
+	a := results.NewAsync(sink, results.AsyncOptions{BatchSize: 4, FlushInterval: time.Hour})
+	for i := range 10 {
+		a.Record(results.Record{Session: i})
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.recs) != 10 || !sink.closed {
+		t.Fatalf("got %d records, closed=%v", len(sink.recs), sink.closed)
+	}
+	for i, r := range sink.recs {
+		if r.Session != i {
+			t.Fatalf("record %d: got session %d", i, r.Session)
+		}
+	}
+	if sink.writes != 3 {
+		t.Errorf("got %d writes, want 3 batches", sink.writes)
+	}
+	if st := a.Stats(); st.Written != 10 || st.Dropped != 0 {
+		t.Errorf("stats: %+v", st)
+	}
+}
+
+func TestAsync_FlushesOnInterval(t *testing.T) {
+	sink := &memSink{}
+	a := results.NewAsync(sink, results.AsyncOptions{FlushInterval: 10 * time.Millisecond})
+	defer a.Close()
+	a.Record(results.Record{Target: "t"})
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if a.Stats().Written == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("record was not flushed within a second")
+}
+
+func TestAsync_DropsInsteadOfBlocking(t *testing.T) {
+	sink := &memSink{block: make(chan struct{})}
+	var errs []error
+	sink.err = errors.New("disk full")
+	a := results.NewAsync(sink, results.AsyncOptions{BufferSize: 2, BatchSize: 1, OnError: func(err error) { errs = append(errs, err) }})
+
+	done := make(chan struct{})
+	go func() {
+		for range 100 {
+			a.Record(results.Record{})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a stalled sink")
+	}
+	close(sink.block)
+	a.Close()
+
+	st := a.Stats()
+	if st.Dropped == 0 || st.Dropped+uint64(len(sink.recs)) != 100 {
+		t.Errorf("stats %+v with %d records written", st, len(sink.recs))
+	}
+	if st.Written != 0 || int(st.Errors) != len(errs) || len(errs) == 0 {
+		t.Errorf("failed writes: stats %+v, OnError called %d times", st, len(errs))
+	}
+}
+
+func TestFanout_SlowSinkDoesNotStallOthers(t *testing.T) {
+	slow := &memSink{block: make(chan struct{})}
+	fast := &memSink{}
+	f := results.NewFanout([]results.Sink{slow, fast}, results.AsyncOptions{BufferSize: 4, BatchSize: 1, FlushInterval: time.Millisecond})
+	for i := range 20 {
+		f.Record(results.Record{Session: i})
+		time.Sleep(time.Millisecond)
+	}
+	deadline := time.Now().Add(time.Second)
+	for f.Stats()[1].Written < 20 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(slow.block)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st := f.Stats()
+	if st[1].Written != 20 || st[1].Dropped != 0 {
+		t.Errorf("fast sink: %+v, want all 20 records written", st[1])
+	}
+	if st[0].Dropped == 0 || st[0].Written+st[0].Dropped != 20 {
+		t.Errorf("slow sink: %+v, want drops from its own queue only", st[0])
+	}
+	if !slow.closed || !fast.closed {
+		t.Error("Close did not close every sink")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{context.DeadlineExceeded, results.ClassTimeout},
+		{fmt.Errorf("get: %w", context.Canceled), results.ClassCanceled},
+		{&net.DNSError{Err: "no such host", Name: "x.invalid"}, results.ClassDNS},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, results.ClassRefused},
+		{&client.FaultError{Fault: client.FaultReset, Err: syscall.ECONNRESET}, results.ClassReset},
+		{&client.FaultError{Fault: client.FaultTimeout, Err: os.ErrDeadlineExceeded}, results.ClassTimeout},
+		{io.ErrUnexpectedEOF, results.ClassTruncated},
+		{errors.New("boom"), results.ClassOther},
+	} {
+		if got := results.Classify(tc.err); got != tc.want {
+			t.Errorf("Classify(%v): got %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookOptions tunes NewWebhookSink.
+type WebhookOptions struct {
+	// Headers are added to every POST, e.g. an Authorization token.
+	Headers map[string]string
+
+	// Timeout bounds each POST.  Zero means 10 seconds.
+	Timeout time.Duration
+
+	// Client sends the POSTs.  Nil uses a client with Timeout.
+	Client *http.Client
+}
+
+// WebhookSink POSTs each batch of records to a URL as a JSON array.  Any
+// status outside 2xx is reported as an error.
+type WebhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	buf     bytes.Buffer
+}
+
+// NewWebhookSink returns a sink posting to url.
+func NewWebhookSink(url string, opts WebhookOptions) *WebhookSink {
+	c := opts.Client
+	if c == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		c = &http.Client{Timeout: timeout}
+	}
+	return &WebhookSink{url: url, headers: opts.Headers, client: c}
+}
+
+// Write POSTs recs.
+func (s *WebhookSink) Write(recs []Record) error {
+	s.buf.Reset()
+	if err := json.NewEncoder(&s.buf).Encode(recs); err != nil {
+		return fmt.Errorf("results: encode webhook batch: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(s.buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("results: webhook %q: %w", s.url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("results: webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("results: webhook %q answered %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// Close releases idle connections.
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,41 @@
+package results_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/results"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var got []results.Record
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	s := results.NewWebhookSink(srv.URL, results.WebhookOptions{Headers: map[string]string{"Authorization": "Bearer t"}})
+	if err := s.Write([]results.Record{sampleRecord(1), sampleRecord(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != sampleRecord(1) || auth != "Bearer t" {
+		t.Errorf("got %+v with Authorization %q", got, auth)
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := results.NewWebhookSink(srv.URL, results.WebhookOptions{}).Write([]results.Record{{}}); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+}
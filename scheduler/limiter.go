@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// maxLimiterSleep bounds how long Wait sleeps in one step so that a rate
+// change made through SetRate (e.g. by a config reload) takes effect quickly
+// even when the previous rate was very low.
+const maxLimiterSleep = 100 * time.Millisecond
+
+// RateLimiter is a token-bucket limiter whose rate can be changed at runtime.
+//
+// The bucket holds at most one second's worth of tokens (minimum one), so
+// short bursts are absorbed without letting an idle period build up an
+// unbounded backlog.  A rate <= 0 disables limiting entirely.
+//
+// Thread-safety: a sync.Mutex guards all fields; Wait releases it while
+// sleeping so SetRate never blocks behind a waiting dispatcher.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second; <= 0 means unlimited
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows rps events per second.
+// Pass 0 for an unlimited limiter.
+func NewRateLimiter(rps float64) *RateLimiter {
+	return &RateLimiter{rate: rps, tokens: burstFor(rps), last: time.Now()}
+}
+
+// SetRate changes the limit to rps events per second.  Safe for concurrent
+// use with Wait.
+func (l *RateLimiter) SetRate(rps float64) {
+	l.mu.Lock()
+	l.refill(time.Now())
+	l.rate = rps
+	if b := burstFor(rps); l.tokens > b {
+		l.tokens = b
+	}
+	l.mu.Unlock()
+}
+
+// Rate returns the current limit in events per second (0 = unlimited).
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Wait blocks until one event is permitted and returns true, or returns false
+// as soon as stop is closed.
+func (l *RateLimiter) Wait(stop <-chan struct{}) bool {
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return true
+		}
+		now := time.Now()
+		l.refill(now)
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return true
+		}
+		sleep := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if sleep > maxLimiterSleep {
+			sleep = maxLimiterSleep
+		}
+		timer := time.NewTimer(sleep)
+		select {
+		case <-stop:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// refill adds the tokens accrued since the last call.  l.mu must be held.
+func (l *RateLimiter) refill(now time.Time) {
+	if l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if b := burstFor(l.rate); l.tokens > b {
+			l.tokens = b
+		}
+	}
+	l.last = now
+}
+
+// burstFor returns the bucket capacity for rate rps.
+func burstFor(rps float64) float64 {
+	if rps < 1 {
+		return 1
+	}
+	return rps
+}
@@ -0,0 +1,65 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/scheduler"
+)
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	l := scheduler.NewRateLimiter(0)
+	stop := make(chan struct{})
+	start := time.Now()
+	for i := 0; i < 10000; i++ {
+		if !l.Wait(stop) {
+			t.Fatal("Wait returned false without stop")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("unlimited limiter took %v for 10000 waits", elapsed)
+	}
+}
+
+func TestRateLimiter_Paces(t *testing.T) {
+	l := scheduler.NewRateLimiter(50)
+	stop := make(chan struct{})
+
+	// Drain the initial burst, then time the next events.
+	for i := 0; i < 50; i++ {
+		l.Wait(stop)
+	}
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		l.Wait(stop)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("10 events at 50/s took %v, want >= ~200ms", elapsed)
+	}
+}
+
+func TestRateLimiter_StopAndSetRate(t *testing.T) {
+	l := scheduler.NewRateLimiter(0.1)
+	stop := make(chan struct{})
+	l.Wait(stop) // consume the single burst token
+
+	done := make(chan bool)
+	go func() { done <- l.Wait(stop) }()
+	close(stop)
+	select {
+	case ok := <-done:
+		if ok {
+			t.Error("Wait should return false after stop")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not observe stop")
+	}
+
+	l.SetRate(0)
+	if l.Rate() != 0 {
+		t.Errorf("Rate: got %v, want 0", l.Rate())
+	}
+	if !l.Wait(make(chan struct{})) {
+		t.Error("Wait should succeed once unlimited")
+	}
+}
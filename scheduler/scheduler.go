@@ -18,11 +18,14 @@ import (
 //   - A stop channel allows clean shutdown: calling Stop closes the channel,
 //     which causes the control goroutine to exit after the current iteration
 //     completes.
+//   - A RateLimiter paces submissions engine-wide.  It is unlimited by
+//     default and can be retuned at runtime via SetRateLimit.
 //   - The design is intentionally decoupled: Scheduler does not know what the
 //     job does; it only knows how to fan work out to sessions efficiently.
 type Scheduler struct {
 	sessionManager *session.SessionManager
 	workerPool     *worker.WorkerPool
+	limiter        *RateLimiter
 	stopCh         chan struct{}
 	once           sync.Once
 }
@@ -33,10 +36,17 @@ func NewScheduler(sm *session.SessionManager, wp *worker.WorkerPool) *Scheduler
 	return &Scheduler{
 		sessionManager: sm,
 		workerPool:     wp,
+		limiter:        NewRateLimiter(0),
 		stopCh:         make(chan struct{}),
 	}
 }
 
+// SetRateLimit caps job dispatch at rps jobs per second across all sessions.
+// Pass 0 to remove the cap.  Safe to call while the Scheduler is running.
+func (sc *Scheduler) SetRateLimit(rps float64) {
+	sc.limiter.SetRate(rps)
+}
+
 // Start begins continuous job assignment.  For every active session the
 // Scheduler submits a job to the WorkerPool via jobFn(session).  The loop
 // runs until Stop is called.
@@ -67,6 +77,9 @@ func (sc *Scheduler) dispatchJobs(jobFn func(s *session.Session)) {
 		if !ok {
 			continue
 		}
+		if !sc.limiter.Wait(sc.stopCh) {
+			return
+		}
 		// Capture s in the closure to avoid the classic loop-variable trap.
 		captured := s
 		sc.workerPool.Submit(func() {
@@ -0,0 +1,248 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/firasghr/GoSessionEngine/client"
+	"github.com/firasghr/GoSessionEngine/config"
+)
+
+// DefaultRedactedHeaders are always redacted in the request history, on
+// both requests and responses.
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+// redactedValue replaces the value of a redacted header.
+const redactedValue = "[REDACTED]"
+
+// HistoryOptions configures the bounded request history a session keeps for
+// debugging; see Session.History.
+type HistoryOptions struct {
+	// Size is the number of exchanges kept.  Zero disables the history.
+	Size int
+
+	// MaxBodyBytes caps the request and response body bytes kept per
+	// exchange.  Zero keeps no bodies.
+	MaxBodyBytes int
+
+	// RedactHeaders are redacted in addition to DefaultRedactedHeaders.
+	RedactHeaders []string
+}
+
+// historyOptions converts the history section of the configuration.
+func historyOptions(c config.HistoryConfig) HistoryOptions {
+	return HistoryOptions{Size: c.Size, MaxBodyBytes: c.MaxBodyBytes, RedactHeaders: c.RedactHeaders}
+}
+
+// history is a ring buffer of HAR entries.
+type history struct {
+	maxBody int
+	redact  map[string]struct{} // canonical header names
+
+	mu      sync.Mutex
+	entries []client.HAREntry
+	next    int // slot written next
+	full    bool
+}
+
+// newHistory returns a history for o, or nil when o.Size is zero.
+func newHistory(o HistoryOptions) *history {
+	if o.Size <= 0 {
+		return nil
+	}
+	h := &history{
+		maxBody: o.MaxBodyBytes,
+		redact:  make(map[string]struct{}),
+		entries: make([]client.HAREntry, o.Size),
+	}
+	for _, name := range append(append([]string(nil), DefaultRedactedHeaders...), o.RedactHeaders...) {
+		h.redact[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	return h
+}
+
+func (h *history) add(e client.HAREntry) {
+	h.mu.Lock()
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.mu.Unlock()
+}
+
+// snapshot returns the entries oldest first.
+func (h *history) snapshot() []client.HAREntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.full {
+		return append([]client.HAREntry(nil), h.entries[:h.next]...)
+	}
+	out := make([]client.HAREntry, 0, len(h.entries))
+	out = append(out, h.entries[h.next:]...)
+	return append(out, h.entries[:h.next]...)
+}
+
+// headers converts hdr to HAR form with sensitive values redacted.
+func (h *history) headers(hdr http.Header) []client.HARNameValue {
+	out := []client.HARNameValue{}
+	for _, nv := range client.HARHeaders(hdr) {
+		if _, ok := h.redact[http.CanonicalHeaderKey(nv.Name)]; ok {
+			nv.Value = redactedValue
+		}
+		out = append(out, nv)
+	}
+	return out
+}
+
+// body returns the kept part of b as HAR text, its encoding and a comment
+// noting truncation.
+func (h *history) body(b []byte, total int) (text, encoding, comment string) {
+	if len(b) > h.maxBody {
+		b = b[:h.maxBody]
+	}
+	if total > len(b) {
+		comment = fmt.Sprintf("truncated to %d of %d bytes", len(b), total)
+	}
+	if utf8.Valid(b) {
+		return string(b), "", comment
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64", comment
+}
+
+// begin starts the entry for req.  The request body is copied only when it
+// can be replayed through GetBody, so sending is never disturbed.
+func (h *history) begin(req *http.Request) *client.HAREntry {
+	e := &client.HAREntry{
+		StartedDateTime: time.Now(),
+		Request: client.HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     h.headers(req.Header),
+			QueryString: client.HARQuery(req.URL.String()),
+			Cookies:     []client.HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    int(req.ContentLength),
+		},
+	}
+	if req.GetBody != nil && req.ContentLength != 0 {
+		if rc, err := req.GetBody(); err == nil {
+			b, _ := io.ReadAll(io.LimitReader(rc, int64(h.maxBody)))
+			rc.Close()
+			pd := &client.HARPostData{MimeType: req.Header.Get("Content-Type")}
+			pd.Text, pd.Encoding, pd.Comment = h.body(b, int(req.ContentLength))
+			e.Request.PostData = pd
+		}
+	}
+	return e
+}
+
+// fail completes e for a request that got no response.
+func (h *history) fail(e *client.HAREntry, trace *client.Trace, err error) {
+	t := trace.Timings()
+	e.Time = float64(t.Total) / float64(time.Millisecond)
+	e.Timings = client.NewHARTimings(t)
+	e.Response = client.HARResponse{Headers: []client.HARNameValue{}, Cookies: []client.HARNameValue{}, HeadersSize: -1, BodySize: -1}
+	e.Error = err.Error()
+	h.add(*e)
+}
+
+// respond fills in the response half of e and wraps resp.Body so the entry
+// is stored, with the kept part of the body, once the body is consumed.
+func (h *history) respond(e *client.HAREntry, trace *client.Trace, resp *http.Response) {
+	e.Request.HTTPVersion = resp.Proto
+	e.Response = client.HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Headers:     h.headers(resp.Header),
+		Cookies:     []client.HARNameValue{},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+	}
+	e.Response.Content.MimeType = resp.Header.Get("Content-Type")
+	resp.Body = &historyBody{ReadCloser: resp.Body, h: h, e: e, trace: trace}
+}
+
+// historyBody keeps the first maxBody bytes of a response body and stores
+// the entry at EOF, on a read error or on Close.
+type historyBody struct {
+	io.ReadCloser
+	h     *history
+	e     *client.HAREntry
+	trace *client.Trace
+	buf   bytes.Buffer
+	n     int
+	once  sync.Once
+}
+
+func (b *historyBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += n
+	if keep := b.h.maxBody - b.buf.Len(); keep > 0 {
+		b.buf.Write(p[:min(n, keep)])
+	}
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *historyBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *historyBody) finish(err error) {
+	b.once.Do(func() {
+		e := b.e
+		t := b.trace.Timings()
+		e.Time = float64(t.Total) / float64(time.Millisecond)
+		e.Timings = client.NewHARTimings(t)
+		if host, _, splitErr := net.SplitHostPort(t.RemoteAddr); splitErr == nil {
+			e.ServerIPAddress = host
+		}
+		c := &e.Response.Content
+		c.Size = b.n
+		c.Text, c.Encoding, c.Comment = b.h.body(b.buf.Bytes(), b.n)
+		e.Response.BodySize = b.n
+		if err != nil && !errors.Is(err, io.EOF) {
+			e.Error = err.Error()
+		}
+		b.h.add(*e)
+	})
+}
+
+// History returns the session's most recent HTTP exchanges, oldest first,
+// as HAR entries.  Sensitive headers are redacted and bodies truncated
+// according to the session's HistoryOptions.  An exchange appears once its
+// response body has been read or closed.  History returns nil when the
+// history is disabled.
+func (s *Session) History() []client.HAREntry {
+	if s.history == nil {
+		return nil
+	}
+	return s.history.snapshot()
+}
+
+// HAR returns History as an HTTP Archive (HAR 1.2) document.
+func (s *Session) HAR() *client.HAR {
+	return client.NewHAR(s.History())
+}
@@ -0,0 +1,106 @@
+package session_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/session"
+)
+
+func TestHistory_RingRedactionAndTruncation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret"})
+		w.Header().Set("X-Trace", "abc")
+		fmt.Fprintf(w, "response body for %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.History = config.HistoryConfig{Size: 3, MaxBodyBytes: 8, RedactHeaders: []string{"x-trace"}}
+	s, err := session.NewSession(1, "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Headers["Authorization"] = "Bearer token"
+
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("%s/p%d?q=%d", srv.URL, i, i), strings.NewReader("request-body"))
+		resp, err := s.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	entries := s.History()
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want the 3 most recent", len(entries))
+	}
+	for i, e := range entries {
+		if want := fmt.Sprintf("%s/p%d?q=%d", srv.URL, i+2, i+2); e.Request.URL != want {
+			t.Errorf("entry %d: URL %q, want %q", i, e.Request.URL, want)
+		}
+	}
+	e := entries[2]
+	for _, h := range e.Request.Headers {
+		if h.Name == "Authorization" && h.Value != "[REDACTED]" {
+			t.Errorf("Authorization not redacted: %q", h.Value)
+		}
+	}
+	for _, h := range e.Response.Headers {
+		if (h.Name == "Set-Cookie" || h.Name == "X-Trace") && h.Value != "[REDACTED]" {
+			t.Errorf("%s not redacted: %q", h.Name, h.Value)
+		}
+	}
+	if e.Request.PostData == nil || e.Request.PostData.Text != "request-" || e.Request.PostData.Comment == "" {
+		t.Errorf("request body should be truncated to 8 bytes: %+v", e.Request.PostData)
+	}
+	if c := e.Response.Content; c.Text != "response" || c.Size != len("response body for /p4") || c.Comment == "" {
+		t.Errorf("response content: %+v", c)
+	}
+	if e.Response.Status != 200 || e.Time <= 0 || e.Timings.Wait < 0 {
+		t.Errorf("status/timings: %d %v %+v", e.Response.Status, e.Time, e.Timings)
+	}
+
+	data, err := json.Marshal(s.HAR())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":"1.2"`) || strings.Contains(string(data), "Bearer token") {
+		t.Errorf("HAR document: %s", data)
+	}
+}
+
+func TestHistory_RecordsFailuresAndCanBeDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	s, err := session.NewSession(1, "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.ExecuteRequest("GET", "http://127.0.0.1:1/", nil); err == nil {
+		t.Fatal("expected a connection error")
+	}
+	if h := s.History(); len(h) != 1 || h[0].Error == "" {
+		t.Errorf("failed request should be kept with its error, got %+v", h)
+	}
+
+	cfg.History.Size = 0
+	off, err := session.NewSession(2, "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer off.Close()
+	off.ExecuteRequest("GET", "http://127.0.0.1:1/", nil)
+	if off.History() != nil || len(off.HAR().Log.Entries) != 0 {
+		t.Error("history should be disabled when size is 0")
+	}
+}
@@ -25,6 +25,9 @@ type SessionManager struct {
 	sessions map[int]*Session
 	mutex    sync.RWMutex
 	config   *config.Config
+
+	traffic   Traffic          // guarded by mutex; see SetTraffic
+	recordIDs map[int]struct{} // sessions to record; empty means all
 }
 
 // NewSessionManager creates an empty SessionManager backed by cfg.
@@ -44,16 +47,32 @@ func NewSessionManager(cfg *config.Config) *SessionManager {
 // returns.  If any session fails to initialise, an aggregated error is
 // returned and the successfully-created sessions remain registered.
 func (sm *SessionManager) CreateSessions(count int, pm *proxy.ProxyManager) error {
+	ids := make([]int, count)
+	for i := range ids {
+		ids[i] = i
+	}
+	return sm.create(ids, pm)
+}
+
+// create creates sessions with the given IDs concurrently and registers
+// the ones that succeed.  An ID registered in the meantime keeps its
+// existing session; the new one is closed.
+func (sm *SessionManager) create(ids []int, pm *proxy.ProxyManager) error {
 	type result struct {
 		s   *Session
 		err error
 		id  int
 	}
 
-	results := make(chan result, count)
+	sm.mutex.RLock()
+	cfg := sm.config
+	traffic, recordIDs := sm.traffic, sm.recordIDs
+	sm.mutex.RUnlock()
+
+	results := make(chan result, len(ids))
 	var wg sync.WaitGroup
 
-	for i := 0; i < count; i++ {
+	for _, i := range ids {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
@@ -61,7 +80,11 @@ func (sm *SessionManager) CreateSessions(count int, pm *proxy.ProxyManager) erro
 			if pm != nil {
 				p = pm.GetNextProxy()
 			}
-			s, err := NewSession(id, p, sm.config)
+			tr := traffic
+			if _, ok := recordIDs[id]; len(recordIDs) > 0 && !ok {
+				tr.Recorder = nil
+			}
+			s, err := NewSessionWithTraffic(id, p, cfg, tr)
 			results <- result{s: s, err: err, id: id}
 		}(i)
 	}
@@ -79,6 +102,10 @@ func (sm *SessionManager) CreateSessions(count int, pm *proxy.ProxyManager) erro
 			errs = append(errs, r.err)
 			continue
 		}
+		if _, exists := sm.sessions[r.s.ID]; exists {
+			r.s.Close()
+			continue
+		}
 		sm.sessions[r.s.ID] = r.s
 	}
 	sm.mutex.Unlock()
@@ -89,6 +116,64 @@ func (sm *SessionManager) CreateSessions(count int, pm *proxy.ProxyManager) erro
 	return nil
 }
 
+// SetConfig replaces the configuration used for sessions created from now
+// on (by CreateSessions or Resize).  Existing sessions are not touched.
+func (sm *SessionManager) SetConfig(cfg *config.Config) {
+	sm.mutex.Lock()
+	sm.config = cfg
+	sm.mutex.Unlock()
+}
+
+// SetTraffic makes sessions created from now on record to tr.Recorder and
+// replay from tr.Replay.  When recordSessions is non-empty only the
+// sessions with those IDs are recorded.  Call it before CreateSessions.
+func (sm *SessionManager) SetTraffic(tr Traffic, recordSessions []int) {
+	ids := make(map[int]struct{}, len(recordSessions))
+	for _, id := range recordSessions {
+		ids[id] = struct{}{}
+	}
+	sm.mutex.Lock()
+	sm.traffic, sm.recordIDs = tr, ids
+	sm.mutex.Unlock()
+}
+
+// Resize makes the pool hold the sessions with IDs 0..n-1, so IDs stay
+// contiguous from 0 – which is what the Scheduler relies on.  Sessions with
+// IDs n and above are closed and removed.  Every missing ID below n, whether
+// beyond the current pool or left by an earlier failed creation, gets a new
+// session in the "idle" state using the current config and the next
+// proxies from pm; call StartAll to activate them.
+func (sm *SessionManager) Resize(n int, pm *proxy.ProxyManager) error {
+	var missing []int
+	sm.mutex.Lock()
+	for id, s := range sm.sessions {
+		if id >= n {
+			s.Close()
+			delete(sm.sessions, id)
+		}
+	}
+	for id := 0; id < n; id++ {
+		if _, ok := sm.sessions[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	sm.mutex.Unlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+	return sm.create(missing, pm)
+}
+
+// PruneVariants calls Session.PruneVariants with keep on every session.
+func (sm *SessionManager) PruneVariants(keep map[string]struct{}) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	for _, s := range sm.sessions {
+		s.PruneVariants(keep)
+	}
+}
+
 // GetSession returns the session with the given id and true, or nil and false
 // if no such session exists.  Safe for concurrent use.
 func (sm *SessionManager) GetSession(id int) (*Session, bool) {
@@ -1,8 +1,14 @@
 package session_test
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/firasghr/GoSessionEngine/client"
 	"github.com/firasghr/GoSessionEngine/config"
 	"github.com/firasghr/GoSessionEngine/proxy"
 	"github.com/firasghr/GoSessionEngine/session"
@@ -71,3 +77,129 @@ func TestCreateSessions_WithProxies(t *testing.T) {
 		t.Errorf("expected 2 sessions, got %d", sm.Count())
 	}
 }
+
+func TestResize(t *testing.T) {
+	cfg := config.DefaultConfig()
+	sm := session.NewSessionManager(cfg)
+	if err := sm.CreateSessions(3, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sm.Resize(5, nil); err != nil {
+		t.Fatalf("Resize up: %v", err)
+	}
+	if sm.Count() != 5 {
+		t.Fatalf("expected 5 sessions after growing, got %d", sm.Count())
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := sm.GetSession(i); !ok {
+			t.Errorf("session %d missing after growing", i)
+		}
+	}
+
+	if err := sm.Resize(2, nil); err != nil {
+		t.Fatalf("Resize down: %v", err)
+	}
+	if sm.Count() != 2 {
+		t.Fatalf("expected 2 sessions after shrinking, got %d", sm.Count())
+	}
+	if _, ok := sm.GetSession(2); ok {
+		t.Error("session 2 should have been removed")
+	}
+}
+
+func TestResize_FillsMissingIDs(t *testing.T) {
+	// Every other proxy is malformed, so CreateSessions leaves gaps.
+	path := filepath.Join(t.TempDir(), "proxies.txt")
+	if err := os.WriteFile(path, []byte("http://127.0.0.1:1\n%zz\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pm := &proxy.ProxyManager{}
+	if err := pm.LoadProxies(path); err != nil {
+		t.Fatal(err)
+	}
+	sm := session.NewSessionManager(config.DefaultConfig())
+	if err := sm.CreateSessions(4, pm); err == nil {
+		t.Fatal("expected an error for the malformed proxies")
+	}
+	if sm.Count() != 2 {
+		t.Fatalf("expected 2 sessions after a partial failure, got %d", sm.Count())
+	}
+	kept := make(map[int]*session.Session)
+	for i := 0; i < 4; i++ {
+		if s, ok := sm.GetSession(i); ok {
+			kept[i] = s
+		}
+	}
+
+	if err := sm.Resize(4, nil); err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	if sm.Count() != 4 {
+		t.Fatalf("expected 4 sessions, got %d", sm.Count())
+	}
+	for i := 0; i < 4; i++ {
+		s, ok := sm.GetSession(i)
+		if !ok {
+			t.Errorf("session %d missing after Resize", i)
+		} else if old, was := kept[i]; was && s != old {
+			t.Errorf("session %d was replaced", i)
+		}
+	}
+}
+
+func TestSetTraffic_RecordsSelectedSessions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "tape.jsonl")
+	rec, err := client.NewRecorder(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := session.NewSessionManager(config.DefaultConfig())
+	sm.SetTraffic(session.Traffic{Recorder: rec}, []int{1})
+	if err := sm.CreateSessions(3, nil); err != nil {
+		t.Fatal(err)
+	}
+	for id := 0; id < 3; id++ {
+		s, _ := sm.GetSession(id)
+		resp, err := s.ExecuteRequest("GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	sm.StopAll()
+	rec.Close()
+
+	ins, err := client.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ins) != 1 || ins[0].Tag != "1" {
+		t.Errorf("want one interaction from session 1, got %+v", ins)
+	}
+
+	// Replaying the cassette serves the response without the server.
+	srv.Close()
+	sm = session.NewSessionManager(config.DefaultConfig())
+	sm.SetTraffic(session.Traffic{Replay: client.NewReplayer(ins)}, nil)
+	if err := sm.CreateSessions(1, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer sm.StopAll()
+	s, _ := sm.GetSession(0)
+	resp, err := s.ExecuteRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("replayed body: got %q", body)
+	}
+}
@@ -5,12 +5,17 @@
 package session
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"google.golang.org/grpc"
+
 	"github.com/firasghr/GoSessionEngine/client"
 	"github.com/firasghr/GoSessionEngine/config"
 )
@@ -60,6 +65,22 @@ type Session struct {
 	LastActivity time.Time
 
 	mu sync.RWMutex // guards Headers, State, LastActivity
+
+	// opts and timeout are the settings Client was built with; per-request
+	// overrides (see client.Override) start from them.
+	opts    client.TransportOptions
+	timeout time.Duration
+
+	variantsMu sync.Mutex
+	variants   map[string]*http.Client // override key -> client sharing CookieJar
+
+	grpcConns map[grpcKey]*grpc.ClientConn // guarded by variantsMu
+
+	history *history // recent exchanges for HAR export; nil when disabled
+
+	streamsMu sync.Mutex
+	sockets   map[*WebSocket]struct{}    // open WebSockets, closed with the session
+	subs      map[*Subscription]struct{} // open SSE subscriptions, likewise
 }
 
 // NewSession constructs a Session with a dedicated HTTP client configured
@@ -68,11 +89,57 @@ type Session struct {
 // Returns an error if the HTTP client cannot be constructed (e.g. invalid
 // proxy URL).
 func NewSession(id int, proxy string, cfg *config.Config) (*Session, error) {
+	return NewSessionWithTraffic(id, proxy, cfg, Traffic{})
+}
+
+// Traffic routes a session's HTTP traffic through a cassette recorder, a
+// replayer, or both, and receives the activity of shaped connections.  One
+// Recorder or Replayer is usually shared by many sessions; see
+// SessionManager.SetTraffic.
+type Traffic struct {
+	// Recorder, when set, records every request and response of the
+	// session, tagged with the session ID.
+	Recorder *client.Recorder
+
+	// Replay, when set, answers requests from a cassette instead of the
+	// network.
+	Replay *client.Replayer
+
+	// ShapeObserver, when set, receives the throughput and injected
+	// latency of connections shaped according to the shaping section of
+	// the configuration.
+	ShapeObserver client.ShapeObserver
+
+	// Chaos, when set, injects faults into the session's requests.
+	Chaos *client.Chaos
+
+	// Breakers, when set, short-circuits requests to failing hosts or
+	// targets.  See NewBreakers.
+	Breakers *client.Breakers
+}
+
+// NewSessionWithTraffic is NewSession with recording or replay enabled
+// according to tr.  The settings carry over to per-target client variants.
+func NewSessionWithTraffic(id int, proxy string, cfg *config.Config, tr Traffic) (*Session, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("session %d: config must not be nil", id)
 	}
 
-	c, err := client.NewHTTPClient(proxy, cfg.RequestTimeout)
+	opts := transportOptions(cfg)
+	opts.Replay = tr.Replay
+	opts.Chaos = tr.Chaos
+	opts.Breakers = tr.Breakers
+	if tr.Recorder != nil {
+		opts.Recorder, opts.RecordTag = tr.Recorder, strconv.Itoa(id)
+	}
+	tlsOpts, err := sessionTLS(cfg.TLS, id)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: %w", id, err)
+	}
+	opts.TLS = tlsOpts
+	opts.Shaper = sessionShaper(cfg.Shaping, id, tr.ShapeObserver)
+
+	c, err := client.NewHTTPClientWithOptions(proxy, cfg.RequestTimeout.Std(), opts)
 	if err != nil {
 		return nil, fmt.Errorf("session %d: create HTTP client: %w", id, err)
 	}
@@ -87,9 +154,129 @@ func NewSession(id int, proxy string, cfg *config.Config) (*Session, error) {
 		State:        "idle",
 		CreatedAt:    now,
 		LastActivity: now,
+		opts:         opts,
+		timeout:      cfg.RequestTimeout.Std(),
+		history:      newHistory(historyOptions(cfg.History)),
 	}, nil
 }
 
+// sessionTLS builds the TLS options for session id from the session-level
+// TLS settings.  Client certificates are assigned round-robin by session ID.
+func sessionTLS(c config.TLSConfig, id int) (client.TLSOptions, error) {
+	opts := client.TLSOptions{InsecureSkipVerify: c.InsecureSkipVerify}
+	if len(c.CAFiles) > 0 {
+		pool, err := client.LoadCertPool(c.CAFiles...)
+		if err != nil {
+			return opts, err
+		}
+		opts.RootCAs = pool
+	}
+	if n := len(c.ClientCerts); n > 0 {
+		cc := c.ClientCerts[id%n]
+		cert, err := client.LoadClientCertificate(cc.CertFile, cc.KeyFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Certificates = []tls.Certificate{cert}
+	}
+	return opts, nil
+}
+
+// sessionShaper returns the shaper for session id, or nil when shaping is
+// disabled.  Profiles are assigned round-robin by session ID; custom
+// profiles take precedence over built-in ones of the same name.  The
+// configuration has been validated, so unknown names are skipped.
+func sessionShaper(c config.ShapingConfig, id int, o client.ShapeObserver) *client.Shaper {
+	n := len(c.Profiles)
+	if n == 0 {
+		return nil
+	}
+	name := c.Profiles[id%n]
+	if p, ok := c.Custom[name]; ok {
+		return client.NewShaper(client.ShapeProfile{
+			Name:         name,
+			DownloadKbps: p.DownloadKbps,
+			UploadKbps:   p.UploadKbps,
+			Latency:      p.Latency.Std(),
+			Jitter:       p.Jitter.Std(),
+		}, o)
+	}
+	if p, ok := client.LookupShapeProfile(name); ok {
+		return client.NewShaper(p, o)
+	}
+	return nil
+}
+
+// NewChaos builds the fault injector described by c, or returns nil when c
+// has no rules.  Injection starts switched on only if c.Enabled is set.
+func NewChaos(c config.ChaosConfig) (*client.Chaos, error) {
+	if len(c.Rules) == 0 {
+		return nil, nil
+	}
+	rules := make([]client.FaultRule, len(c.Rules))
+	for i, r := range c.Rules {
+		rules[i] = client.FaultRule{
+			Name:          r.Name,
+			URL:           r.URL,
+			Fault:         client.FaultKind(r.Fault),
+			Probability:   r.Probability,
+			Every:         r.Every,
+			Delay:         r.Delay.Std(),
+			Status:        r.Status,
+			TruncateAfter: r.TruncateAfter,
+			Disabled:      r.Disabled,
+		}
+	}
+	ch, err := client.NewChaos(rules)
+	if err != nil {
+		return nil, fmt.Errorf("session: build chaos rules: %w", err)
+	}
+	ch.SetEnabled(c.Enabled)
+	return ch, nil
+}
+
+// NewBreakers builds the circuit breakers described by c, or returns nil
+// when c is not enabled.  o and onChange, when non-nil, receive the
+// breakers' activity.
+func NewBreakers(c config.BreakerConfig, o client.BreakerObserver, onChange func(key string, from, to client.BreakerState)) *client.Breakers {
+	if !c.Enabled {
+		return nil
+	}
+	return client.NewBreakers(client.BreakerOptions{
+		Key:              client.BreakerKey(c.Key),
+		Window:           c.Window.Std(),
+		MinRequests:      c.MinRequests,
+		FailureRate:      c.FailureRate,
+		CoolDown:         c.CoolDown.Std(),
+		HalfOpenRequests: c.HalfOpenRequests,
+		Observer:         o,
+		OnStateChange:    onChange,
+	})
+}
+
+// transportOptions maps the transport-tuning fields of cfg onto
+// client.TransportOptions.
+func transportOptions(cfg *config.Config) client.TransportOptions {
+	return client.TransportOptions{
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout.Std(),
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout.Std(),
+		DialTimeout:         cfg.DialTimeout.Std(),
+		KeepAlive:           cfg.KeepAlive.Std(),
+		EnableHTTP2:         cfg.EnableHTTP2,
+		MaxDecodedBodySize:  cfg.MaxDecodedBodySize,
+		Protocol:            client.Protocol(cfg.Protocol),
+		Resolver: client.ResolverOptions{
+			Hosts:     cfg.DNSHosts,
+			Server:    cfg.DNSServer,
+			IPVersion: cfg.IPVersion,
+			CacheTTL:  cfg.DNSCacheTTL.Std(),
+		},
+	}
+}
+
 // ExecuteRequest sends an HTTP request and returns the response.
 //
 // The method is safe for concurrent use: it acquires a read-lock to snapshot
@@ -103,22 +290,182 @@ func (s *Session) ExecuteRequest(method, targetURL string, body io.Reader) (*htt
 	if err != nil {
 		return nil, fmt.Errorf("session %d: build request: %w", s.ID, err)
 	}
+	return s.Do(req)
+}
+
+// ExecuteBody is ExecuteRequest for a body built with the client package's
+// body builders (client.FormBody, client.JSONBody, client.FileBody,
+// client.Multipart and friends).  The body's Content-Type is set, and the
+// request can be replayed on redirects and retries because the body is.
+// A nil body sends none.
+func (s *Session) ExecuteBody(method, targetURL string, body *client.Body) (*http.Response, error) {
+	req, err := body.NewRequest(context.Background(), method, targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: build request: %w", s.ID, err)
+	}
+	return s.Do(req)
+}
+
+// Do sends a caller-built request through the session's client.  Session
+// headers are added to req first; headers already set on req take precedence
+// over session headers with the same name.
+//
+// Like ExecuteRequest, Do is safe for concurrent use and the caller must close
+// the returned response body.  Use DoTimed to also obtain the request's
+// timing breakdown.
+func (s *Session) Do(req *http.Request) (*http.Response, error) {
+	resp, _, err := s.DoTimed(req)
+	return resp, err
+}
+
+// DoTimed is Do with net/http/httptrace instrumentation.  The returned trace
+// is non-nil even when err is not, so failed requests can still be attributed
+// to DNS, connect or TLS overhead.  Its BodyRead and Total timings are final
+// once the response body has been read to EOF or closed, which is also when
+// the exchange is added to the session history (see History).
+func (s *Session) DoTimed(req *http.Request) (*http.Response, *client.Trace, error) {
+	s.applyHeaders(req)
+
+	var entry *client.HAREntry
+	if s.history != nil {
+		entry = s.history.begin(req)
+	}
+	req, trace := client.TraceRequest(req)
+	fail := func(err error) (*http.Response, *client.Trace, error) {
+		trace.Finish(nil, err)
+		if entry != nil {
+			s.history.fail(entry, trace, err)
+		}
+		return nil, trace, err
+	}
+
+	c, err := s.clientFor(client.OverrideFrom(req.Context()))
+	if err != nil {
+		return fail(fmt.Errorf("session %d: %w", s.ID, err))
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fail(fmt.Errorf("session %d: execute %s %s: %w", s.ID, req.Method, req.URL, err))
+	}
+	trace.Finish(resp, nil)
+	if entry != nil {
+		s.history.respond(entry, trace, resp)
+	}
 
+	s.UpdateLastActivity()
+	return resp, trace, nil
+}
+
+// applyHeaders copies the session headers onto req, keeping any header
+// already set on req.
+func (s *Session) applyHeaders(req *http.Request) {
 	// Snapshot headers under a read-lock so we don't race with concurrent
 	// header updates.
 	s.mu.RLock()
 	for k, v := range s.Headers {
-		req.Header.Set(k, v)
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
 	}
 	s.mu.RUnlock()
+}
 
-	resp, err := s.Client.Do(req)
+// clientFor returns the client for requests carrying ov: Client itself when
+// ov is nil, otherwise a lazily built client whose transport options are
+// Client's with ov applied.  All variants share the session's cookie jar.
+func (s *Session) clientFor(ov *client.Override) (*http.Client, error) {
+	if ov == nil {
+		return s.Client, nil
+	}
+	s.variantsMu.Lock()
+	defer s.variantsMu.Unlock()
+	if c, ok := s.variants[ov.Key]; ok {
+		return c, nil
+	}
+	opts := s.opts
+	if ov.Apply != nil {
+		ov.Apply(&opts, s.ID)
+	}
+	c, err := client.NewHTTPClientWithOptions(s.Proxy, s.timeout, opts)
 	if err != nil {
-		return nil, fmt.Errorf("session %d: execute %s %s: %w", s.ID, method, targetURL, err)
+		return nil, fmt.Errorf("create HTTP client for %q: %w", ov.Key, err)
+	}
+	c.Jar = s.CookieJar
+	if s.variants == nil {
+		s.variants = make(map[string]*http.Client)
 	}
+	s.variants[ov.Key] = c
+	return c, nil
+}
 
-	s.UpdateLastActivity()
-	return resp, nil
+// GRPCConn returns the session's gRPC connection to addr (host:port),
+// creating it on first use.  Like the HTTP client, it uses the session's
+// proxy and transport settings, adjusted by any client.Override in ctx, so
+// gRPC traffic stays isolated per session.  secure selects TLS.  The
+// connection is closed by Close.
+func (s *Session) GRPCConn(ctx context.Context, addr string, secure bool) (*grpc.ClientConn, error) {
+	ov := client.OverrideFrom(ctx)
+	key := grpcKey{addr: addr, secure: secure}
+	if ov != nil {
+		key.override = ov.Key
+	}
+
+	s.variantsMu.Lock()
+	defer s.variantsMu.Unlock()
+	if conn, ok := s.grpcConns[key]; ok {
+		return conn, nil
+	}
+	opts := s.opts
+	if ov != nil && ov.Apply != nil {
+		ov.Apply(&opts, s.ID)
+	}
+	conn, err := client.NewGRPCConn(addr, secure, s.Proxy, opts)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: %w", s.ID, err)
+	}
+	if s.grpcConns == nil {
+		s.grpcConns = make(map[grpcKey]*grpc.ClientConn)
+	}
+	s.grpcConns[key] = conn
+	return conn, nil
+}
+
+// grpcKey identifies a gRPC connection of a session.
+type grpcKey struct {
+	addr     string
+	secure   bool
+	override string // client.Override key, or "" for none
+}
+
+// PruneVariants drops the per-target clients and gRPC connections built for
+// overrides whose key is not in keep, closing their idle connections.  Call
+// it after the target set changes, so clients for settings no target uses
+// any more do not pile up across reloads.  Requests in flight on a dropped
+// client complete normally.
+func (s *Session) PruneVariants(keep map[string]struct{}) {
+	var drop []*http.Client
+	var conns []*grpc.ClientConn
+	s.variantsMu.Lock()
+	for key, c := range s.variants {
+		if _, ok := keep[key]; !ok {
+			drop = append(drop, c)
+			delete(s.variants, key)
+		}
+	}
+	for key, conn := range s.grpcConns {
+		if _, ok := keep[key.override]; key.override != "" && !ok {
+			conns = append(conns, conn)
+			delete(s.grpcConns, key)
+		}
+	}
+	s.variantsMu.Unlock()
+
+	for _, c := range drop {
+		c.CloseIdleConnections()
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
 }
 
 // UpdateLastActivity records the current time as the session's last activity
@@ -130,16 +477,49 @@ func (s *Session) UpdateLastActivity() {
 	s.mu.Unlock()
 }
 
+// Streams returns the number of the session's WebSockets and SSE
+// subscriptions that are still running.
+func (s *Session) Streams() (websockets, subscriptions int) {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	return len(s.sockets), len(s.subs)
+}
+
 // Close transitions the session to the "closed" state and releases transport
-// resources by closing all idle connections.  After Close returns the session
-// must not be used.
+// resources by closing all idle connections, open WebSockets and SSE
+// subscriptions.  After Close returns the session must not be used.
 func (s *Session) Close() {
 	s.mu.Lock()
 	s.State = "closed"
 	s.mu.Unlock()
 
 	// Drain the idle-connection pool so the OS can reclaim sockets promptly.
-	if t, ok := s.Client.Transport.(*http.Transport); ok {
-		t.CloseIdleConnections()
+	// http.Client forwards this through any wrapping round trippers.
+	s.Client.CloseIdleConnections()
+	s.variantsMu.Lock()
+	for _, c := range s.variants {
+		c.CloseIdleConnections()
+	}
+	for _, conn := range s.grpcConns {
+		_ = conn.Close()
+	}
+	s.grpcConns = nil
+	s.variantsMu.Unlock()
+
+	s.streamsMu.Lock()
+	sockets := make([]*WebSocket, 0, len(s.sockets))
+	for ws := range s.sockets {
+		sockets = append(sockets, ws)
+	}
+	subs := make([]*Subscription, 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	s.streamsMu.Unlock()
+	for _, ws := range sockets {
+		_ = ws.Close()
+	}
+	for _, sub := range subs {
+		sub.Close()
 	}
 }
@@ -1,17 +1,26 @@
 package session_test
 
 import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/firasghr/GoSessionEngine/client"
 	"github.com/firasghr/GoSessionEngine/config"
 	"github.com/firasghr/GoSessionEngine/session"
+	"github.com/firasghr/GoSessionEngine/target"
 )
 
 func testConfig() *config.Config {
 	return &config.Config{
-		RequestTimeout:      5 * time.Second,
+		RequestTimeout:      config.Duration(5 * time.Second),
 		MaxIdleConns:        10,
 		MaxIdleConnsPerHost: 5,
 		MaxConnsPerHost:     10,
@@ -91,10 +100,258 @@ func TestClose_SetsState(t *testing.T) {
 
 func TestExecuteRequest_UnreachableHost(t *testing.T) {
 	cfg := testConfig()
-	cfg.RequestTimeout = 100 * time.Millisecond
+	cfg.RequestTimeout = config.Duration(100 * time.Millisecond)
 	s, _ := session.NewSession(1, "", cfg)
 	_, err := s.ExecuteRequest("GET", "http://192.0.2.1/test", strings.NewReader(""))
 	if err == nil {
 		t.Error("expected error for unreachable host")
 	}
 }
+
+func TestDoTimed_ReturnsTimings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	s, err := session.NewSession(1, "", testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, trace, err := s.DoTimed(req)
+	if err != nil {
+		t.Fatalf("DoTimed: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	tm := trace.Timings()
+	if tm.Connect <= 0 || tm.TTFB <= 0 || tm.Total <= 0 {
+		t.Errorf("expected connect, TTFB and total to be recorded: %+v", tm)
+	}
+	if tm.Proto != "HTTP/1.1" {
+		t.Errorf("Proto: got %q, want HTTP/1.1", tm.Proto)
+	}
+}
+
+func TestDo_OverrideUsesVariantClientWithSharedJar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/set" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "42"})
+			return
+		}
+		if c, err := r.Cookie("sid"); err != nil || c.Value != "42" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := session.NewSession(3, "", testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := 0
+	ov := &client.Override{
+		Key: "variant",
+		Apply: func(opts *client.TransportOptions, slot int) {
+			applied++
+			if slot != 3 {
+				t.Errorf("slot: got %d, want the session ID 3", slot)
+			}
+		},
+	}
+
+	resp, err := s.ExecuteRequest(http.MethodGet, srv.URL+"/set", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(client.WithOverride(context.Background(), ov), http.MethodGet, srv.URL+"/check", nil)
+		resp, err := s.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("variant client should send the session's cookies, got status %d", resp.StatusCode)
+		}
+	}
+	if applied != 1 {
+		t.Errorf("override applied %d times, want once (variant client reused)", applied)
+	}
+}
+
+func TestDoTimed_ReturnsTraceWhenVariantFails(t *testing.T) {
+	cfg := testConfig()
+	cfg.History.Size = 4
+	s, err := session.NewSession(1, "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// HTTP/3 cannot use a fixed dial address, so the variant client cannot
+	// be built.
+	ov := &client.Override{Key: "h3-dial", Apply: func(opts *client.TransportOptions, _ int) {
+		opts.Protocol, opts.Dial = client.ProtocolHTTP3, "unix:///tmp/x.sock"
+	}}
+	req, _ := http.NewRequestWithContext(client.WithOverride(context.Background(), ov), http.MethodGet, "http://localhost/ping", nil)
+	resp, trace, err := s.DoTimed(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected an error building the variant client")
+	}
+	if trace == nil {
+		t.Fatal("DoTimed returned a nil trace with its error")
+	}
+	if trace.Timings().Total <= 0 {
+		t.Error("the failed request's trace was not finished")
+	}
+	if h := s.History(); len(h) != 1 || h[0].Error == "" {
+		t.Errorf("history: got %+v, want the failed request", h)
+	}
+}
+
+func TestPruneVariants_DropsUnusedOverrides(t *testing.T) {
+	var closed atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, st http.ConnState) {
+		if st == http.StateClosed {
+			closed.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	s, err := session.NewSession(1, "", testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	built := 0
+	ov := &client.Override{Key: "tls-a", Apply: func(*client.TransportOptions, int) { built++ }}
+	send := func() {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(client.WithOverride(context.Background(), ov), http.MethodGet, srv.URL, nil)
+		resp, err := s.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	send()
+	s.PruneVariants(map[string]struct{}{"tls-a": {}})
+	send()
+	if built != 1 {
+		t.Fatalf("variant built %d times, want 1 while its key is kept", built)
+	}
+
+	s.PruneVariants(map[string]struct{}{"tls-b": {}})
+	deadline := time.Now().Add(time.Second)
+	for closed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if closed.Load() == 0 {
+		t.Error("idle connection of the dropped variant was not closed")
+	}
+	send()
+	if built != 2 {
+		t.Errorf("variant built %d times, want a fresh one after pruning", built)
+	}
+}
+
+func TestNewSession_MissingClientCert(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLS.ClientCerts = []config.ClientCert{{CertFile: "/nonexistent/cert.pem", KeyFile: "/nonexistent/key.pem"}}
+	if _, err := session.NewSession(1, "", cfg); err == nil {
+		t.Error("expected an error for an unreadable client certificate")
+	}
+}
+
+func TestDo_UnixSocketTargetKeepsSessionsIsolated(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("sid"); err == nil {
+			_, _ = io.WriteString(w, c.Value)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: r.URL.Query().Get("id")})
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	set, err := target.NewSet([]config.Target{{Name: "sidecar", URL: "unix://" + sock + ":/login?id=a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tgt := set.Targets()[0]
+
+	get := func(s *session.Session) string {
+		t.Helper()
+		req, err := tgt.NewRequest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, trace, err := s.DoTimed(req)
+		if err != nil {
+			t.Fatalf("session %d: %v", s.ID, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if trace.Timings().Proto != "HTTP/1.1" {
+			t.Errorf("session %d: Proto = %q, want HTTP/1.1", s.ID, trace.Timings().Proto)
+		}
+		return string(body)
+	}
+
+	a, _ := session.NewSession(1, "", testConfig())
+	b, _ := session.NewSession(2, "", testConfig())
+	if got := get(a); got != "" {
+		t.Fatalf("first request should set the cookie, got body %q", got)
+	}
+	if got := get(a); got != "a" {
+		t.Errorf("session 1 should send its cookie over the socket, got %q", got)
+	}
+	if got := get(b); got != "" {
+		t.Errorf("session 2 must not see session 1's cookie, got %q", got)
+	}
+}
+
+func TestExecuteBody_SetsContentTypeAndReplays(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusTemporaryRedirect)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer srv.Close()
+
+	s, err := session.NewSession(1, "", testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := client.JSONBody(map[string]string{"q": "shoes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.ExecuteBody(http.MethodPost, srv.URL+"/old", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+	if string(got) != `application/json {"q":"shoes"}` {
+		t.Errorf("got %q", got)
+	}
+}
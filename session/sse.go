@@ -0,0 +1,446 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// ErrSubscriptionClosed is returned by Subscription.Err after Close, or after
+// the server ended the stream with 204 No Content.
+var ErrSubscriptionClosed = errors.New("session: subscription closed")
+
+// Event is one Server-Sent Event.
+type Event struct {
+	// ID is the last event ID seen on the stream, which is also what a
+	// reconnect sends as Last-Event-ID.
+	ID string
+
+	// Type is the event field, or "message" when the event had none.
+	Type string
+
+	// Data is the event's data lines joined with "\n".
+	Data string
+
+	// Retry is the reconnection delay the server set with this event, if
+	// any.
+	Retry time.Duration
+}
+
+// EventHandler processes one event.  Handlers run on the subscription's
+// reader goroutine, so the stream is not read while a handler runs.
+type EventHandler func(Event)
+
+// SSEObserver receives stream activity for aggregation.  *metrics.Metrics
+// implements it.  Methods are called concurrently.
+type SSEObserver interface {
+	// SSEConnected is called after every successful connection, including
+	// reconnects.
+	SSEConnected()
+
+	// SSEEvent is called for every dispatched event with the time since
+	// the previous event on the same subscription (zero for the first) and
+	// the number of events missed, judged by a jump in numeric event IDs.
+	SSEEvent(gap time.Duration, missed uint64)
+}
+
+// SSEOptions tunes SubscribeWithOptions.  Start from DefaultSSEOptions and
+// override individual fields.
+type SSEOptions struct {
+	// Header is sent with every connection attempt in addition to the
+	// session headers, taking precedence over them.
+	Header http.Header
+
+	// RetryDelay is the reconnection delay until the server sets one with a
+	// retry field.  Consecutive failed attempts double it, up to
+	// MaxRetryDelay.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+
+	// MaxReconnects caps consecutive failed reconnect attempts before the
+	// subscription gives up.  Zero means no limit.
+	MaxReconnects int
+
+	// MaxEventSize caps the length of a single line of the stream.
+	MaxEventSize int
+
+	// Observer, if non-nil, receives connection and event notifications.
+	Observer SSEObserver
+}
+
+// DefaultSSEOptions returns the options used by Subscribe: a 3 s initial
+// retry delay (the value browsers use), backing off to 1 min, unlimited
+// reconnects and a 1 MiB line limit.
+func DefaultSSEOptions() SSEOptions {
+	return SSEOptions{
+		RetryDelay:    3 * time.Second,
+		MaxRetryDelay: time.Minute,
+		MaxEventSize:  1 << 20,
+	}
+}
+
+// SubscriptionStats is a snapshot of a subscription's counters.
+type SubscriptionStats struct {
+	Events       uint64
+	Reconnects   uint64
+	MissedEvents uint64
+	LastEventID  string
+	Connected    bool
+}
+
+// Subscription is a Server-Sent Events stream held open by a session.  It
+// reconnects after drops, resuming with Last-Event-ID.
+type Subscription struct {
+	s       *Session
+	url     string
+	handler EventHandler
+	opts    SSEOptions
+	ov      *client.Override
+	c       *http.Client // session client without the request timeout
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // valid after done is closed
+
+	mu          sync.Mutex
+	lastEventID string
+	retry       time.Duration
+	connected   bool
+	lastEvent   time.Time
+	lastNumID   uint64
+	hasNumID    bool
+
+	events, reconnects, missed atomic.Uint64
+}
+
+// Subscribe opens an SSE stream at rawURL with DefaultSSEOptions and calls
+// handler for every event.  See SubscribeWithOptions.
+func (s *Session) Subscribe(rawURL string, handler EventHandler) (*Subscription, error) {
+	return s.SubscribeWithOptions(context.Background(), rawURL, handler, DefaultSSEOptions())
+}
+
+// SubscribeWithOptions opens an SSE stream at rawURL through the session's
+// HTTP client, so requests carry the session's cookies and headers and use
+// its proxy.  The request timeout does not apply to the stream.  A
+// client.Override in ctx selects the matching variant client, as in Do.
+//
+// It returns once the first connection is established, or with an error if
+// that fails.  ctx bounds the first connection attempt only; use Close to
+// end the subscription.
+func (s *Session) SubscribeWithOptions(ctx context.Context, rawURL string, handler EventHandler, opts SSEOptions) (*Subscription, error) {
+	ov := client.OverrideFrom(ctx)
+	c, err := s.clientFor(ov)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: %w", s.ID, err)
+	}
+	stream := *c
+	stream.Timeout = 0
+
+	sub := &Subscription{
+		s:       s,
+		url:     rawURL,
+		handler: handler,
+		opts:    opts,
+		ov:      ov,
+		c:       &stream,
+		done:    make(chan struct{}),
+		retry:   opts.RetryDelay,
+	}
+	sub.ctx, sub.cancel = context.WithCancel(context.Background())
+
+	// The first attempt is bounded by both ctx and Close.
+	stop := context.AfterFunc(ctx, sub.cancel)
+	resp, err := sub.connect()
+	if !stop() && err == nil {
+		resp.Body.Close()
+		err = fmt.Errorf("session %d: subscribe %s: %w", s.ID, rawURL, ctx.Err())
+	}
+	if err != nil {
+		sub.cancel()
+		return nil, err
+	}
+
+	s.streamsMu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[*Subscription]struct{})
+	}
+	s.subs[sub] = struct{}{}
+	s.streamsMu.Unlock()
+
+	go sub.run(resp)
+	return sub, nil
+}
+
+// connect performs one request and checks that it returned an event stream.
+// A 204 response yields ErrSubscriptionClosed.
+func (sub *Subscription) connect() (*http.Response, error) {
+	s := sub.s
+	req, err := http.NewRequestWithContext(client.WithOverride(sub.ctx, sub.ov), http.MethodGet, sub.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: build request: %w", s.ID, err)
+	}
+	for k, vs := range sub.opts.Header {
+		req.Header[http.CanonicalHeaderKey(k)] = vs
+	}
+	s.applyHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	sub.mu.Lock()
+	if sub.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", sub.lastEventID)
+	}
+	sub.mu.Unlock()
+
+	resp, err := sub.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: subscribe %s: %w", s.ID, sub.url, err)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		resp.Body.Close()
+		return nil, ErrSubscriptionClosed
+	}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mt != "text/event-stream" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("session %d: subscribe %s: unexpected response %s (%s)", s.ID, sub.url, resp.Status, mt)
+	}
+
+	sub.mu.Lock()
+	sub.connected = true
+	sub.mu.Unlock()
+	if o := sub.opts.Observer; o != nil {
+		o.SSEConnected()
+	}
+	s.UpdateLastActivity()
+	return resp, nil
+}
+
+// run reads resp and its successors until the subscription ends, then
+// removes it from the session, whether it ended by Close, a 204 response or
+// a failed reconnect.
+func (sub *Subscription) run(resp *http.Response) {
+	defer close(sub.done)
+	defer sub.unregister()
+	for {
+		sub.read(resp)
+		resp.Body.Close()
+		sub.mu.Lock()
+		sub.connected = false
+		sub.mu.Unlock()
+
+		var err error
+		if resp, err = sub.reconnect(); err != nil {
+			sub.err = err
+			return
+		}
+		sub.reconnects.Add(1)
+	}
+}
+
+// reconnect waits for the retry delay and connects again, backing off while
+// attempts fail.
+func (sub *Subscription) reconnect() (*http.Response, error) {
+	sub.mu.Lock()
+	delay := sub.retry
+	sub.mu.Unlock()
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-sub.ctx.Done():
+			return nil, ErrSubscriptionClosed
+		case <-time.After(delay):
+		}
+		resp, err := sub.connect()
+		switch {
+		case err == nil:
+			return resp, nil
+		case sub.ctx.Err() != nil:
+			return nil, ErrSubscriptionClosed
+		case errors.Is(err, ErrSubscriptionClosed):
+			return nil, err
+		case sub.opts.MaxReconnects > 0 && attempt >= sub.opts.MaxReconnects:
+			return nil, fmt.Errorf("reconnect failed after %d attempts: %w", attempt, err)
+		}
+		delay *= 2
+		if delay <= 0 {
+			delay = time.Second
+		}
+		if m := sub.opts.MaxRetryDelay; m > 0 && delay > m {
+			delay = m
+		}
+	}
+}
+
+// read parses the event stream in resp until it ends.
+func (sub *Subscription) read(resp *http.Response) {
+	sc := bufio.NewScanner(resp.Body)
+	max := sub.opts.MaxEventSize
+	if max <= 0 {
+		max = bufio.MaxScanTokenSize
+	}
+	sc.Buffer(make([]byte, 0, min(max, 64<<10)), max)
+	sc.Split(scanSSELines)
+
+	var (
+		typ   string
+		data  strings.Builder
+		retry time.Duration
+		first = true
+	)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if first {
+			line = bytes.TrimPrefix(line, []byte("\xEF\xBB\xBF"))
+			first = false
+		}
+		if len(line) == 0 {
+			if data.Len() > 0 {
+				sub.dispatch(typ, strings.TrimSuffix(data.String(), "\n"), retry)
+			}
+			typ, retry = "", 0
+			data.Reset()
+			continue
+		}
+		if line[0] == ':' {
+			continue // comment
+		}
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "event":
+			typ = string(value)
+		case "data":
+			data.Write(value)
+			data.WriteByte('\n')
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				sub.mu.Lock()
+				sub.lastEventID = string(value)
+				sub.mu.Unlock()
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 32); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				sub.mu.Lock()
+				sub.retry = retry
+				sub.mu.Unlock()
+			}
+		}
+	}
+}
+
+// dispatch delivers one event and updates the gap statistics.
+func (sub *Subscription) dispatch(typ, data string, retry time.Duration) {
+	if typ == "" {
+		typ = "message"
+	}
+	now := time.Now()
+	sub.mu.Lock()
+	id := sub.lastEventID
+	var gap time.Duration
+	if !sub.lastEvent.IsZero() {
+		gap = now.Sub(sub.lastEvent)
+	}
+	sub.lastEvent = now
+	var missed uint64
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+		if sub.hasNumID && n > sub.lastNumID+1 {
+			missed = n - sub.lastNumID - 1
+		}
+		sub.lastNumID, sub.hasNumID = n, true
+	}
+	sub.mu.Unlock()
+
+	sub.events.Add(1)
+	sub.missed.Add(missed)
+	if o := sub.opts.Observer; o != nil {
+		o.SSEEvent(gap, missed)
+	}
+	sub.s.UpdateLastActivity()
+	if sub.handler != nil {
+		sub.handler(Event{ID: id, Type: typ, Data: data, Retry: retry})
+	}
+}
+
+// scanSSELines is a bufio.SplitFunc for SSE lines, which may end in "\r\n",
+// "\n" or a lone "\r".
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// A "\r" may be followed by "\n" in the next read.
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF {
+		// An incomplete final line is discarded, as the spec requires.
+		return len(data), nil, nil
+	}
+	return 0, nil, nil
+}
+
+// Stats returns the subscription's counters.
+func (sub *Subscription) Stats() SubscriptionStats {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return SubscriptionStats{
+		Events:       sub.events.Load(),
+		Reconnects:   sub.reconnects.Load(),
+		MissedEvents: sub.missed.Load(),
+		LastEventID:  sub.lastEventID,
+		Connected:    sub.connected,
+	}
+}
+
+// Done is closed when the subscription has ended.
+func (sub *Subscription) Done() <-chan struct{} { return sub.done }
+
+// Err returns why the subscription ended: ErrSubscriptionClosed after Close
+// or a 204 from the server, or the error of the last reconnect attempt.  It
+// returns nil while the subscription is running.
+func (sub *Subscription) Err() error {
+	select {
+	case <-sub.done:
+		return sub.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription and waits for the reader goroutine to exit.
+// It is safe to call more than once, but not from the handler.
+func (sub *Subscription) Close() {
+	sub.cancel()
+	<-sub.done
+}
+
+// unregister removes sub from its session's open subscriptions.
+func (sub *Subscription) unregister() {
+	sub.cancel()
+	sub.s.streamsMu.Lock()
+	delete(sub.s.subs, sub)
+	sub.s.streamsMu.Unlock()
+}
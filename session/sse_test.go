@@ -0,0 +1,136 @@
+package session_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/metrics"
+	"github.com/firasghr/GoSessionEngine/session"
+)
+
+func TestSubscribe_ParsesAndResumes(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		lastEventIDs []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastEventIDs)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch n {
+		case 1:
+			// CRLF and lone CR line endings, a comment, a multi-line event
+			// and a retry field, then the server drops the stream.
+			fmt.Fprint(w, ": welcome\r\nretry: 10\r\nid: 1\r\ndata: hello\r\n\r\n")
+			fmt.Fprint(w, "event: update\rid: 2\rdata: a\rdata: b\r\r")
+		case 2:
+			// Resume, skipping event 3.
+			fmt.Fprint(w, "id: 4\ndata:resumed\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	s, _ := session.NewSession(1, "", testConfig())
+	defer s.Close()
+
+	m := metrics.NewMetrics()
+	opts := session.DefaultSSEOptions()
+	opts.Observer = m
+	var events []session.Event
+	sub, err := s.SubscribeWithOptions(t.Context(), srv.URL, func(e session.Event) {
+		events = append(events, e)
+	}, opts)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription did not end after 204")
+	}
+	if !errors.Is(sub.Err(), session.ErrSubscriptionClosed) {
+		t.Errorf("Err: got %v, want ErrSubscriptionClosed", sub.Err())
+	}
+	if _, n := s.Streams(); n != 0 {
+		t.Errorf("open subscriptions after the 204: got %d, want 0", n)
+	}
+
+	want := []session.Event{
+		{ID: "1", Type: "message", Data: "hello", Retry: 10 * time.Millisecond},
+		{ID: "2", Type: "update", Data: "a\nb"},
+		{ID: "4", Type: "message", Data: "resumed"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, events[i], want[i])
+		}
+	}
+
+	mu.Lock()
+	if len(lastEventIDs) != 3 || lastEventIDs[0] != "" || lastEventIDs[1] != "2" || lastEventIDs[2] != "4" {
+		t.Errorf("Last-Event-ID headers: got %q, want [\"\" 2 4]", lastEventIDs)
+	}
+	mu.Unlock()
+
+	st := sub.Stats()
+	if st.Events != 3 || st.MissedEvents != 1 || st.Reconnects != 1 || st.LastEventID != "4" {
+		t.Errorf("stats: %+v", st)
+	}
+	snap := m.SSESnapshot()
+	if snap.Connects != 2 || snap.Events != 3 || snap.MissedEvents != 1 {
+		t.Errorf("metrics: %+v", snap)
+	}
+}
+
+func TestSubscribe_RejectsNonEventStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{}")
+	}))
+	defer srv.Close()
+
+	s, _ := session.NewSession(1, "", testConfig())
+	defer s.Close()
+	if _, err := s.Subscribe(srv.URL, nil); err == nil {
+		t.Error("expected an error for a non-event-stream response")
+	}
+}
+
+func TestSubscribe_OutlivesRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.RequestTimeout = config.Duration(50 * time.Millisecond)
+	s, _ := session.NewSession(1, "", cfg)
+	sub, err := s.Subscribe(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if st := sub.Stats(); !st.Connected || st.Reconnects != 0 {
+		t.Errorf("stream should stay open past the request timeout: %+v", st)
+	}
+	s.Close()
+	if !errors.Is(sub.Err(), session.ErrSubscriptionClosed) {
+		t.Errorf("Err after session Close: got %v", sub.Err())
+	}
+}
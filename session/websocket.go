@@ -0,0 +1,483 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/firasghr/GoSessionEngine/client"
+)
+
+// ErrWebSocketClosed is returned by Send and Receive after the WebSocket was
+// closed by Close or by a normal closure from the server.
+var ErrWebSocketClosed = errors.New("session: websocket closed")
+
+// MessageType distinguishes text from binary WebSocket messages.
+type MessageType int
+
+const (
+	// TextMessage is a UTF-8 text message.
+	TextMessage MessageType = MessageType(websocket.MessageText)
+
+	// BinaryMessage is a binary message.
+	BinaryMessage MessageType = MessageType(websocket.MessageBinary)
+)
+
+// WebSocketMessage is one received message.
+type WebSocketMessage struct {
+	Type MessageType
+	Data []byte
+}
+
+// WebSocketObserver receives WebSocket activity for aggregation.
+// *metrics.Metrics implements it.  Methods are called concurrently.
+type WebSocketObserver interface {
+	// WebSocketOpened is called after every successful handshake,
+	// including reconnects.
+	WebSocketOpened()
+
+	// WebSocketMessage is called for every message sent or received, with
+	// its payload size.
+	WebSocketMessage(sent bool, bytes int)
+
+	// WebSocketClosed is called when a connection ends, with how long it
+	// was open.
+	WebSocketClosed(lifetime time.Duration)
+}
+
+// WebSocketOptions tunes DialWebSocketWithOptions.  Start from
+// DefaultWebSocketOptions and override individual fields.
+type WebSocketOptions struct {
+	// Header is sent with the handshake in addition to the session headers,
+	// taking precedence over them.
+	Header http.Header
+
+	// Subprotocols are offered in Sec-WebSocket-Protocol.
+	Subprotocols []string
+
+	// PingInterval is the period of keep-alive pings.  Zero disables them.
+	PingInterval time.Duration
+
+	// PongTimeout bounds the wait for the pong answering a ping.  A missed
+	// pong drops the connection, which triggers a reconnect when enabled.
+	PongTimeout time.Duration
+
+	// Reconnect re-dials after the connection drops for any reason other
+	// than Close or a normal closure (status 1000) from the server.
+	Reconnect bool
+
+	// ReconnectBackoff is the delay before the first reconnect attempt.  It
+	// doubles after every failed attempt, up to MaxReconnectBackoff.
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+
+	// MaxReconnects caps consecutive failed reconnect attempts before the
+	// WebSocket gives up.  Zero means no limit.
+	MaxReconnects int
+
+	// ReadLimit caps the size of a received message in bytes.  Zero keeps
+	// the library default of 32 KiB.
+	ReadLimit int64
+
+	// Observer, if non-nil, receives connection and message events.
+	Observer WebSocketObserver
+
+	// OnReconnect, if non-nil, is called after each successful reconnect,
+	// e.g. to re-send subscriptions.  It runs on the reader goroutine, so
+	// messages are not received until it returns.
+	OnReconnect func(ws *WebSocket)
+}
+
+// DefaultWebSocketOptions returns the options used by DialWebSocket: a ping
+// every 30 s with a 10 s pong timeout, and unlimited reconnects with backoff
+// from 500 ms to 30 s.
+func DefaultWebSocketOptions() WebSocketOptions {
+	return WebSocketOptions{
+		PingInterval:        30 * time.Second,
+		PongTimeout:         10 * time.Second,
+		Reconnect:           true,
+		ReconnectBackoff:    500 * time.Millisecond,
+		MaxReconnectBackoff: 30 * time.Second,
+	}
+}
+
+// WebSocketStats is a snapshot of a WebSocket's counters.
+type WebSocketStats struct {
+	MessagesSent     uint64
+	MessagesReceived uint64
+	BytesSent        uint64
+	BytesReceived    uint64
+
+	// Reconnects counts successful reconnects after the initial dial.
+	Reconnects uint64
+
+	// Connected reports whether a connection is currently open, and
+	// ConnectedFor how long it has been.
+	Connected    bool
+	ConnectedFor time.Duration
+}
+
+// WebSocket is a session-bound WebSocket connection that survives drops by
+// reconnecting.  A background goroutine reads messages, answers pings and
+// handles reconnects; callers consume messages with Receive.  Send, Receive,
+// Stats and Close are safe for concurrent use.
+type WebSocket struct {
+	s    *Session
+	url  string
+	opts WebSocketOptions
+	ov   *client.Override
+
+	ctx       context.Context // cancelled by Close
+	cancel    context.CancelFunc
+	closing   atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+	msgs      chan WebSocketMessage
+	done      chan struct{} // closed when the reader goroutine exits
+	err       error         // terminal error, valid after done is closed
+
+	mu       sync.Mutex
+	conn     *websocket.Conn // nil while reconnecting
+	openedAt time.Time
+	ready    chan struct{} // closed when conn is set
+
+	sent, received, bytesSent, bytesReceived, reconnects atomic.Uint64
+}
+
+// DialWebSocket opens a WebSocket to rawURL (ws:// or wss://) with
+// DefaultWebSocketOptions.  See DialWebSocketWithOptions.
+func (s *Session) DialWebSocket(rawURL string) (*WebSocket, error) {
+	return s.DialWebSocketWithOptions(context.Background(), rawURL, DefaultWebSocketOptions())
+}
+
+// DialWebSocketWithOptions opens a WebSocket to rawURL through the session's
+// HTTP client, so the handshake carries the session's cookies and headers,
+// uses its proxy and transport settings, and stores any cookies the server
+// sets.  A client.Override in ctx selects the matching variant client, as in
+// Do.  ctx bounds the initial handshake only.
+//
+// The WebSocket is closed by Close or when the session is closed.
+func (s *Session) DialWebSocketWithOptions(ctx context.Context, rawURL string, opts WebSocketOptions) (*WebSocket, error) {
+	ws := &WebSocket{
+		s:     s,
+		url:   rawURL,
+		opts:  opts,
+		ov:    client.OverrideFrom(ctx),
+		msgs:  make(chan WebSocketMessage, 64),
+		done:  make(chan struct{}),
+		ready: make(chan struct{}),
+	}
+	conn, err := ws.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
+	ws.setConn(conn)
+
+	s.streamsMu.Lock()
+	if s.sockets == nil {
+		s.sockets = make(map[*WebSocket]struct{})
+	}
+	s.sockets[ws] = struct{}{}
+	s.streamsMu.Unlock()
+
+	go ws.run(conn)
+	return ws, nil
+}
+
+// dial performs one handshake.
+func (ws *WebSocket) dial(ctx context.Context) (*websocket.Conn, error) {
+	s := ws.s
+	c, err := s.clientFor(ws.ov)
+	if err != nil {
+		return nil, fmt.Errorf("session %d: %w", s.ID, err)
+	}
+
+	header := make(http.Header)
+	s.mu.RLock()
+	for k, v := range s.Headers {
+		header.Set(k, v)
+	}
+	s.mu.RUnlock()
+	for k, vs := range ws.opts.Header {
+		header[http.CanonicalHeaderKey(k)] = vs
+	}
+
+	conn, resp, err := websocket.Dial(ctx, ws.url, &websocket.DialOptions{
+		HTTPClient:   c,
+		HTTPHeader:   header,
+		Subprotocols: ws.opts.Subprotocols,
+	})
+	if resp != nil && resp.Body != nil && err != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session %d: dial websocket %s: %w", s.ID, ws.url, err)
+	}
+	if ws.opts.ReadLimit > 0 {
+		conn.SetReadLimit(ws.opts.ReadLimit)
+	}
+	if o := ws.opts.Observer; o != nil {
+		o.WebSocketOpened()
+	}
+	s.UpdateLastActivity()
+	return conn, nil
+}
+
+func (ws *WebSocket) setConn(conn *websocket.Conn) {
+	ws.mu.Lock()
+	ws.conn = conn
+	ws.openedAt = time.Now()
+	close(ws.ready)
+	ws.mu.Unlock()
+}
+
+// dropConn records the end of the current connection.
+func (ws *WebSocket) dropConn() {
+	ws.mu.Lock()
+	lifetime := time.Since(ws.openedAt)
+	ws.conn = nil
+	ws.ready = make(chan struct{})
+	ws.mu.Unlock()
+	if o := ws.opts.Observer; o != nil {
+		o.WebSocketClosed(lifetime)
+	}
+}
+
+// run reads from conn and its successors until the WebSocket ends, then
+// removes it from the session, whether it ended by Close, a closure by the
+// server or a failed reconnect.
+func (ws *WebSocket) run(conn *websocket.Conn) {
+	defer close(ws.done)
+	defer close(ws.msgs)
+	defer ws.unregister()
+	for {
+		err := ws.readLoop(conn)
+		ws.dropConn()
+		switch {
+		case ws.closing.Load(), ws.ctx.Err() != nil,
+			websocket.CloseStatus(err) == websocket.StatusNormalClosure:
+			ws.err = ErrWebSocketClosed
+			return
+		case !ws.opts.Reconnect:
+			ws.err = fmt.Errorf("session %d: websocket %s: %w", ws.s.ID, ws.url, err)
+			return
+		}
+		if conn, err = ws.redial(); err != nil {
+			ws.err = err
+			return
+		}
+		ws.reconnects.Add(1)
+		ws.setConn(conn)
+		if ws.opts.OnReconnect != nil {
+			ws.opts.OnReconnect(ws)
+		}
+	}
+}
+
+// readLoop delivers messages from conn until it fails, pinging it meanwhile.
+func (ws *WebSocket) readLoop(conn *websocket.Conn) error {
+	connCtx, stop := context.WithCancel(ws.ctx)
+	defer stop()
+	if ws.opts.PingInterval > 0 {
+		go ws.keepAlive(connCtx, conn)
+	}
+	for {
+		typ, data, err := conn.Read(connCtx)
+		if err != nil {
+			conn.CloseNow()
+			return err
+		}
+		ws.received.Add(1)
+		ws.bytesReceived.Add(uint64(len(data)))
+		if o := ws.opts.Observer; o != nil {
+			o.WebSocketMessage(false, len(data))
+		}
+		ws.s.UpdateLastActivity()
+		select {
+		case ws.msgs <- WebSocketMessage{Type: MessageType(typ), Data: data}:
+		case <-connCtx.Done():
+			return connCtx.Err()
+		}
+	}
+}
+
+// keepAlive pings conn every PingInterval and drops it when a pong does not
+// arrive within PongTimeout.
+func (ws *WebSocket) keepAlive(ctx context.Context, conn *websocket.Conn) {
+	t := time.NewTicker(ws.opts.PingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		pingCtx := ctx
+		var cancel context.CancelFunc = func() {}
+		if ws.opts.PongTimeout > 0 {
+			pingCtx, cancel = context.WithTimeout(ctx, ws.opts.PongTimeout)
+		}
+		err := conn.Ping(pingCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				conn.CloseNow()
+			}
+			return
+		}
+	}
+}
+
+// redial reconnects with exponential backoff.
+func (ws *WebSocket) redial() (*websocket.Conn, error) {
+	backoff := ws.opts.ReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ws.ctx.Done():
+			return nil, ErrWebSocketClosed
+		case <-time.After(backoff):
+		}
+		conn, err := ws.dial(ws.ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if ws.ctx.Err() != nil {
+			return nil, ErrWebSocketClosed
+		}
+		if ws.opts.MaxReconnects > 0 && attempt >= ws.opts.MaxReconnects {
+			return nil, fmt.Errorf("reconnect failed after %d attempts: %w", attempt, err)
+		}
+		backoff *= 2
+		if backoff <= 0 {
+			backoff = time.Second
+		}
+		if m := ws.opts.MaxReconnectBackoff; m > 0 && backoff > m {
+			backoff = m
+		}
+	}
+}
+
+// current returns the open connection, waiting for a reconnect if needed.
+func (ws *WebSocket) current(ctx context.Context) (*websocket.Conn, error) {
+	for {
+		ws.mu.Lock()
+		conn, ready := ws.conn, ws.ready
+		ws.mu.Unlock()
+		if conn != nil {
+			return conn, nil
+		}
+		select {
+		case <-ready:
+		case <-ws.done:
+			return nil, ws.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Send writes one message.  While a reconnect is in progress it waits for
+// the new connection, bounded by ctx.
+func (ws *WebSocket) Send(ctx context.Context, typ MessageType, data []byte) error {
+	conn, err := ws.current(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.Write(ctx, websocket.MessageType(typ), data); err != nil {
+		return fmt.Errorf("session %d: websocket send: %w", ws.s.ID, err)
+	}
+	ws.sent.Add(1)
+	ws.bytesSent.Add(uint64(len(data)))
+	if o := ws.opts.Observer; o != nil {
+		o.WebSocketMessage(true, len(data))
+	}
+	ws.s.UpdateLastActivity()
+	return nil
+}
+
+// SendText sends msg as a text message.
+func (ws *WebSocket) SendText(ctx context.Context, msg string) error {
+	return ws.Send(ctx, TextMessage, []byte(msg))
+}
+
+// Receive returns the next message.  Messages keep arriving across
+// reconnects.  After the WebSocket ends, Receive returns ErrWebSocketClosed
+// or the error that ended it.
+//
+// Messages are buffered; when the buffer is full the connection stops being
+// read, so callers must keep calling Receive.
+func (ws *WebSocket) Receive(ctx context.Context) (WebSocketMessage, error) {
+	select {
+	case m, ok := <-ws.msgs:
+		if !ok {
+			return WebSocketMessage{}, ws.err
+		}
+		return m, nil
+	case <-ctx.Done():
+		return WebSocketMessage{}, ctx.Err()
+	}
+}
+
+// Subprotocol returns the subprotocol negotiated by the current connection,
+// or "" when none was, or while reconnecting.
+func (ws *WebSocket) Subprotocol() string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn == nil {
+		return ""
+	}
+	return ws.conn.Subprotocol()
+}
+
+// Stats returns the WebSocket's counters.
+func (ws *WebSocket) Stats() WebSocketStats {
+	st := WebSocketStats{
+		MessagesSent:     ws.sent.Load(),
+		MessagesReceived: ws.received.Load(),
+		BytesSent:        ws.bytesSent.Load(),
+		BytesReceived:    ws.bytesReceived.Load(),
+		Reconnects:       ws.reconnects.Load(),
+	}
+	ws.mu.Lock()
+	if ws.conn != nil {
+		st.Connected = true
+		st.ConnectedFor = time.Since(ws.openedAt)
+	}
+	ws.mu.Unlock()
+	return st
+}
+
+// Close sends a normal closure and stops reconnecting.  It waits for the
+// reader goroutine to exit and is safe to call more than once.
+func (ws *WebSocket) Close() error {
+	ws.closeOnce.Do(func() {
+		ws.closing.Store(true)
+		ws.mu.Lock()
+		conn := ws.conn
+		ws.mu.Unlock()
+		if conn != nil {
+			if err := conn.Close(websocket.StatusNormalClosure, ""); err != nil && !errors.Is(err, net.ErrClosed) {
+				ws.closeErr = fmt.Errorf("session %d: close websocket: %w", ws.s.ID, err)
+			}
+		}
+		ws.cancel()
+		<-ws.done
+	})
+	return ws.closeErr
+}
+
+// unregister removes ws from its session's open WebSockets.
+func (ws *WebSocket) unregister() {
+	ws.cancel()
+	ws.s.streamsMu.Lock()
+	delete(ws.s.sockets, ws)
+	ws.s.streamsMu.Unlock()
+}
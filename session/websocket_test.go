@@ -0,0 +1,218 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/firasghr/GoSessionEngine/metrics"
+	"github.com/firasghr/GoSessionEngine/session"
+)
+
+// startEchoWS serves a WebSocket echo endpoint at /ws that prefixes each
+// message with the handshake's sid cookie and User-Agent.  Sending "drop"
+// makes the server abort the connection.  /login sets the cookie.
+func startEchoWS(t *testing.T) (srv *httptest.Server, handshakes *atomic.Int32) {
+	t.Helper()
+	handshakes = new(atomic.Int32)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s1", Path: "/"})
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		sid := ""
+		if c, err := r.Cookie("sid"); err == nil {
+			sid = c.Value
+		}
+		prefix := sid + "|" + r.UserAgent() + "|"
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		handshakes.Add(1)
+		defer conn.CloseNow()
+		for {
+			typ, data, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			if string(data) == "drop" {
+				return
+			}
+			if err := conn.Write(r.Context(), typ, append([]byte(prefix), data...)); err != nil {
+				return
+			}
+		}
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, handshakes
+}
+
+func receive(t *testing.T, ws *session.WebSocket) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, err := ws.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	return string(msg.Data)
+}
+
+func TestDialWebSocket_UsesSessionCookiesAndHeaders(t *testing.T) {
+	srv, _ := startEchoWS(t)
+	s, err := session.NewSession(1, "", testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Headers["User-Agent"] = "engine-test"
+	resp, err := s.ExecuteRequest(http.MethodGet, srv.URL+"/login", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	m := metrics.NewMetrics()
+	opts := session.DefaultWebSocketOptions()
+	opts.Observer = m
+	ws, err := s.DialWebSocketWithOptions(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", opts)
+	if err != nil {
+		t.Fatalf("DialWebSocket: %v", err)
+	}
+	if err := ws.SendText(context.Background(), "hello"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if got := receive(t, ws); got != "s1|engine-test|hello" {
+		t.Errorf("got %q, want s1|engine-test|hello", got)
+	}
+
+	st := ws.Stats()
+	if st.MessagesSent != 1 || st.MessagesReceived != 1 || st.BytesSent != 5 || !st.Connected {
+		t.Errorf("stats: %+v", st)
+	}
+	if err := ws.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := ws.Receive(context.Background()); !errors.Is(err, session.ErrWebSocketClosed) {
+		t.Errorf("Receive after Close: got %v, want ErrWebSocketClosed", err)
+	}
+	snap := m.WebSocketSnapshot()
+	if snap.Opened != 1 || snap.Open != 0 || snap.MessagesReceived != 1 || snap.BytesReceived != uint64(len("s1|engine-test|hello")) {
+		t.Errorf("metrics: %+v", snap)
+	}
+}
+
+func TestDialWebSocket_Reconnects(t *testing.T) {
+	srv, handshakes := startEchoWS(t)
+	s, _ := session.NewSession(1, "", testConfig())
+	defer s.Close()
+
+	opts := session.DefaultWebSocketOptions()
+	opts.ReconnectBackoff = 10 * time.Millisecond
+	reconnected := make(chan struct{}, 1)
+	opts.OnReconnect = func(*session.WebSocket) { reconnected <- struct{}{} }
+	ws, err := s.DialWebSocketWithOptions(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ws.SendText(ctx, "drop"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reconnected:
+	case <-ctx.Done():
+		t.Fatal("no reconnect after the server dropped the connection")
+	}
+	if err := ws.SendText(ctx, "again"); err != nil {
+		t.Fatalf("SendText after reconnect: %v", err)
+	}
+	if got := receive(t, ws); !strings.HasSuffix(got, "|again") {
+		t.Errorf("got %q, want an echo of again", got)
+	}
+	if n := handshakes.Load(); n != 2 {
+		t.Errorf("handshakes: got %d, want 2", n)
+	}
+	if st := ws.Stats(); st.Reconnects != 1 {
+		t.Errorf("Reconnects: got %d, want 1", st.Reconnects)
+	}
+}
+
+func TestDialWebSocket_PingKeepAlive(t *testing.T) {
+	srv, handshakes := startEchoWS(t)
+	s, _ := session.NewSession(1, "", testConfig())
+	defer s.Close()
+
+	opts := session.DefaultWebSocketOptions()
+	opts.PingInterval = 20 * time.Millisecond
+	opts.PongTimeout = time.Second
+	ws, err := s.DialWebSocketWithOptions(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if st := ws.Stats(); !st.Connected || st.Reconnects != 0 || handshakes.Load() != 1 {
+		t.Errorf("answered pings should keep the connection: %+v", st)
+	}
+}
+
+func TestSessionClose_ClosesWebSockets(t *testing.T) {
+	srv, _ := startEchoWS(t)
+	s, _ := session.NewSession(1, "", testConfig())
+	ws, err := s.DialWebSocket("ws" + strings.TrimPrefix(srv.URL, "http") + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if err := ws.SendText(context.Background(), "x"); !errors.Is(err, session.ErrWebSocketClosed) {
+		t.Errorf("Send after session Close: got %v, want ErrWebSocketClosed", err)
+	}
+}
+
+func TestDialWebSocket_HandshakeFailure(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	s, _ := session.NewSession(1, "", testConfig())
+	defer s.Close()
+	if _, err := s.DialWebSocket("ws" + strings.TrimPrefix(srv.URL, "http")); err == nil {
+		t.Error("expected an error for a non-WebSocket endpoint")
+	}
+}
+
+func TestWebSocket_UnregistersWhenReaderExits(t *testing.T) {
+	srv, _ := startEchoWS(t)
+	s, _ := session.NewSession(1, "", testConfig())
+	defer s.Close()
+
+	opts := session.DefaultWebSocketOptions()
+	opts.Reconnect = false
+	ws, err := s.DialWebSocketWithOptions(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.Streams(); n != 1 {
+		t.Fatalf("open WebSockets: got %d, want 1", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ws.SendText(ctx, "drop"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Receive(ctx); err == nil {
+		t.Fatal("Receive succeeded after the server dropped the connection")
+	}
+	if n, _ := s.Streams(); n != 0 {
+		t.Errorf("open WebSockets after the reader exited: got %d, want 0", n)
+	}
+}
@@ -0,0 +1,327 @@
+package target
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/firasghr/GoSessionEngine/client"
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/session"
+)
+
+// GRPCResult is the outcome of one gRPC call.
+type GRPCResult struct {
+	// Code is the call's status code; codes.OK on success.
+	Code codes.Code
+
+	// Messages is the number of response messages received: one for a
+	// successful unary call, any number for a server stream.
+	Messages int
+}
+
+// grpcCall is the resolved gRPC side of a target.
+type grpcCall struct {
+	addr    string // host:port
+	secure  bool
+	service string // package.Service
+	method  string // Method
+	md      metadata.MD
+	tmpl    *template.Template
+
+	mu   sync.Mutex
+	desc protoreflect.MethodDescriptor // from the descriptor set, or reflected on first use
+}
+
+// GRPCTemplateData is the data a gRPC request template is rendered with.
+type GRPCTemplateData = TemplateData
+
+// newGRPCCall resolves the gRPC settings of ct.  The descriptor set and the
+// request template are loaded here; reflection happens on the first call.
+func newGRPCCall(ct config.Target) (*grpcCall, error) {
+	g := ct.GRPC
+	u, err := url.Parse(ct.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+	call := &grpcCall{secure: u.Scheme == "https", md: metadata.New(g.Metadata)}
+	call.addr = u.Host
+	if u.Port() == "" {
+		port := "80"
+		if call.secure {
+			port = "443"
+		}
+		call.addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	var ok bool
+	call.service, call.method, ok = strings.Cut(strings.TrimPrefix(g.Method, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("grpc method %q: want package.Service/Method", g.Method)
+	}
+
+	text := g.Request
+	if g.RequestFile != "" {
+		data, err := os.ReadFile(g.RequestFile) // #nosec G304 – operator-supplied config path
+		if err != nil {
+			return nil, fmt.Errorf("read grpc request file: %w", err)
+		}
+		text = string(data)
+	}
+	if call.tmpl, err = client.ParseTemplate(ct.Name, text); err != nil {
+		return nil, fmt.Errorf("grpc request: %w", err)
+	}
+
+	if g.DescriptorSet != "" {
+		files, err := loadDescriptorSet(g.DescriptorSet)
+		if err != nil {
+			return nil, err
+		}
+		if call.desc, err = findMethod(files, call.service, call.method); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+// IsGRPC reports whether t is a gRPC target, to be run with Invoke rather
+// than NewRequest.
+func (t *Target) IsGRPC() bool { return t.grpc != nil }
+
+// Invoke performs one gRPC call to t on the session's gRPC connection; see
+// InvokeWith.  A feeder that has run out fails the call with
+// codes.ResourceExhausted and an error wrapping feeder.ErrExhausted.
+func (t *Target) Invoke(ctx context.Context, s *session.Session) (GRPCResult, error) {
+	d, err := t.Data(s.ID)
+	if err != nil {
+		return GRPCResult{Code: codes.ResourceExhausted}, err
+	}
+	return t.InvokeWith(ctx, s, d)
+}
+
+// InvokeWith performs one gRPC call to t on the session's gRPC connection.
+// The request is rendered from the target's template with d; a server
+// stream is read to the end.  A non-OK status is returned both as the error
+// and as GRPCResult.Code.
+func (t *Target) InvokeWith(ctx context.Context, s *session.Session, d TemplateData) (GRPCResult, error) {
+	g := t.grpc
+	if g == nil {
+		return GRPCResult{Code: codes.InvalidArgument}, fmt.Errorf("target %q: not a gRPC target", t.Name)
+	}
+	ctx = client.WithOverride(ctx, t.override)
+	conn, err := s.GRPCConn(ctx, g.addr, g.secure)
+	if err != nil {
+		return GRPCResult{Code: codes.Unavailable}, fmt.Errorf("target %q: %w", t.Name, err)
+	}
+	desc, err := g.methodDesc(ctx, conn)
+	if err != nil {
+		return GRPCResult{Code: status.Code(err)}, fmt.Errorf("target %q: %w", t.Name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := g.tmpl.Execute(&buf, d); err != nil {
+		return GRPCResult{Code: codes.InvalidArgument}, fmt.Errorf("target %q: render request: %w", t.Name, err)
+	}
+	req := dynamicpb.NewMessage(desc.Input())
+	if b := bytes.TrimSpace(buf.Bytes()); len(b) > 0 {
+		if err := protojson.Unmarshal(b, req); err != nil {
+			return GRPCResult{Code: codes.InvalidArgument}, fmt.Errorf("target %q: decode request JSON: %w", t.Name, err)
+		}
+	}
+
+	if len(g.md) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, g.md)
+	}
+	fullMethod := "/" + g.service + "/" + g.method
+	var res GRPCResult
+	if desc.IsStreamingServer() {
+		res.Messages, err = serverStream(ctx, conn, fullMethod, req, desc.Output())
+	} else if err = conn.Invoke(ctx, fullMethod, req, dynamicpb.NewMessage(desc.Output())); err == nil {
+		res.Messages = 1
+	}
+	s.UpdateLastActivity()
+	res.Code = status.Code(err)
+	if err != nil {
+		return res, fmt.Errorf("target %q: %s: %w", t.Name, fullMethod, err)
+	}
+	return res, nil
+}
+
+// serverStream sends req and reads responses until the stream ends.
+func serverStream(ctx context.Context, conn *grpc.ClientConn, method string, req proto.Message, out protoreflect.MessageDescriptor) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, method)
+	if err != nil {
+		return 0, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return 0, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for {
+		if err := stream.RecvMsg(dynamicpb.NewMessage(out)); err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		n++
+	}
+}
+
+// methodDesc returns the method descriptor, asking the server through
+// reflection the first time when no descriptor set was configured.
+func (g *grpcCall) methodDesc(ctx context.Context, conn *grpc.ClientConn) (protoreflect.MethodDescriptor, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.desc != nil {
+		return g.desc, nil
+	}
+	files, err := reflectFiles(ctx, conn, g.service)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection for %s: %w", g.service, err)
+	}
+	if g.desc, err = findMethod(files, g.service, g.method); err != nil {
+		return nil, err
+	}
+	return g.desc, nil
+}
+
+// loadDescriptorSet reads a FileDescriptorSet file.
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path) // #nosec G304 – operator-supplied config path
+	if err != nil {
+		return nil, fmt.Errorf("read descriptor set: %w", err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse descriptor set %s: %w", path, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("descriptor set %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// findMethod looks up service/method in files and rejects client streaming,
+// which a load-test call cannot template.
+func findMethod(files *protoregistry.Files, service, method string) (protoreflect.MethodDescriptor, error) {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("grpc service %s: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("grpc service %s: not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("grpc service %s has no method %s", service, method)
+	}
+	if md.IsStreamingClient() {
+		return nil, fmt.Errorf("grpc method %s/%s: client and bidirectional streaming are not supported", service, method)
+	}
+	return md, nil
+}
+
+// reflectFiles fetches the file defining symbol, and every file it depends
+// on, through the gRPC server reflection service.
+func reflectFiles(ctx context.Context, conn *grpc.ClientConn, symbol string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fds := make(map[string]*descriptorpb.FileDescriptorProto)
+	ask := func(req *rpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return err
+			}
+			fds[fd.GetName()] = fd
+		}
+		return nil
+	}
+
+	if err := ask(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	}); err != nil {
+		return nil, err
+	}
+	// Servers usually send the transitive dependencies along; fetch any
+	// that are missing, preferring the descriptors linked into this binary.
+	for {
+		var missing []string
+		for _, fd := range fds {
+			for _, dep := range fd.GetDependency() {
+				if _, ok := fds[dep]; !ok {
+					missing = append(missing, dep)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+		for _, dep := range missing {
+			if _, ok := fds[dep]; ok {
+				continue
+			}
+			if f, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				fds[dep] = protodesc.ToFileDescriptorProto(f)
+				continue
+			}
+			if err := ask(&rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+			}); err != nil {
+				return nil, fmt.Errorf("fetch %s: %w", dep, err)
+			}
+			if _, ok := fds[dep]; !ok {
+				return nil, fmt.Errorf("server did not return %s", dep)
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range fds {
+		set.File = append(set.File, fd)
+	}
+	return protodesc.NewFiles(set)
+}
@@ -0,0 +1,191 @@
+package target_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/firasghr/GoSessionEngine/cluster/pb"
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/session"
+	"github.com/firasghr/GoSessionEngine/target"
+)
+
+// fakeController records the requests it serves.
+type fakeController struct {
+	pb.UnimplementedMasterControllerServer
+
+	mu    sync.Mutex
+	pcIDs []string
+	auth  []string
+}
+
+func (f *fakeController) GetGlobalCookies(ctx context.Context, req *pb.GetGlobalCookiesRequest) (*pb.GetGlobalCookiesResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	f.mu.Lock()
+	f.pcIDs = append(f.pcIDs, req.GetPcId())
+	f.auth = append(f.auth, md.Get("authorization")...)
+	f.mu.Unlock()
+	if req.GetPcId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "pc_id required")
+	}
+	return &pb.GetGlobalCookiesResponse{Version: 7}, nil
+}
+
+func (f *fakeController) WatchCookies(_ *pb.WatchCookiesRequest, stream grpc.ServerStreamingServer[pb.GetGlobalCookiesResponse]) error {
+	for v := int64(1); v <= 3; v++ {
+		if err := stream.Send(&pb.GetGlobalCookiesResponse{Version: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startGRPC serves a fakeController with server reflection on a loopback
+// port and returns it with its http:// URL.
+func startGRPC(t *testing.T) (*fakeController, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	fc := &fakeController{}
+	pb.RegisterMasterControllerServer(srv, fc)
+	reflection.Register(srv)
+	go srv.Serve(ln) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+	return fc, "http://" + ln.Addr().String()
+}
+
+func newGRPCSession(t *testing.T) *session.Session {
+	t.Helper()
+	s, err := session.NewSession(1, "", config.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestInvoke_UnaryWithReflection(t *testing.T) {
+	fc, url := startGRPC(t)
+	set, err := target.NewSet([]config.Target{{
+		Name: "cookies",
+		URL:  url,
+		GRPC: &config.GRPCTarget{
+			Method:   "pb.MasterController/GetGlobalCookies",
+			Request:  `{"pc_id": "pc-{{.SessionID}}-{{.Seq}}"}`,
+			Metadata: map[string]string{"authorization": "Bearer t"},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := set.Pick()
+	if !tg.IsGRPC() {
+		t.Fatal("target should be a gRPC target")
+	}
+	s := newGRPCSession(t)
+	for i := 0; i < 2; i++ {
+		res, err := tg.Invoke(context.Background(), s)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if res.Code != codes.OK || res.Messages != 1 {
+			t.Errorf("call %d: got %+v, want OK with one message", i, res)
+		}
+	}
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if len(fc.pcIDs) != 2 || fc.pcIDs[0] != "pc-1-1" || fc.pcIDs[1] != "pc-1-2" {
+		t.Errorf("rendered pc_ids: got %v", fc.pcIDs)
+	}
+	if len(fc.auth) != 2 || fc.auth[0] != "Bearer t" {
+		t.Errorf("metadata: got %v", fc.auth)
+	}
+}
+
+func TestInvoke_ServerStreamingWithDescriptorSet(t *testing.T) {
+	_, url := startGRPC(t)
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(pb.File_controller_proto),
+	}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "controller.pb")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := target.NewSet([]config.Target{{
+		Name: "watch",
+		URL:  url,
+		GRPC: &config.GRPCTarget{
+			Method:        "pb.MasterController/WatchCookies",
+			DescriptorSet: path,
+			Request:       `{"pc_id": "w"}`,
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := targets.Pick().Invoke(context.Background(), newGRPCSession(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != codes.OK || res.Messages != 3 {
+		t.Errorf("got %+v, want OK with three messages", res)
+	}
+}
+
+func TestInvoke_StatusCode(t *testing.T) {
+	_, url := startGRPC(t)
+	set, err := target.NewSet([]config.Target{{
+		Name: "empty",
+		URL:  url,
+		GRPC: &config.GRPCTarget{Method: "pb.MasterController/GetGlobalCookies"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := set.Pick().Invoke(context.Background(), newGRPCSession(t))
+	if err == nil {
+		t.Fatal("expected an error for an invalid request")
+	}
+	if res.Code != codes.InvalidArgument {
+		t.Errorf("Code: got %v, want InvalidArgument", res.Code)
+	}
+}
+
+func TestNewSet_GRPCUnknownMethod(t *testing.T) {
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(pb.File_controller_proto),
+	}}
+	data, _ := proto.Marshal(set)
+	path := filepath.Join(t.TempDir(), "controller.pb")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := target.NewSet([]config.Target{{
+		Name: "bad",
+		URL:  "http://127.0.0.1:1",
+		GRPC: &config.GRPCTarget{Method: "pb.MasterController/Nope", DescriptorSet: path},
+	}})
+	if err == nil {
+		t.Error("expected an error for a method missing from the descriptor set")
+	}
+}
@@ -0,0 +1,479 @@
+// Package target turns the declarative targets from config into ready-to-send
+// HTTP requests for the default job.
+//
+// A Set is built once per configuration snapshot (at startup and after every
+// reload).  Building resolves everything that should not happen on the hot
+// path: body files are read into memory, methods are normalised, expected
+// status codes are indexed and cumulative weights are precomputed.  Pick and
+// NewRequest are then cheap and allocation-light, so thousands of sessions
+// can draw from the same Set concurrently.  Once a target's feeder runs
+// out, Pick recomputes the weights without it.
+//
+// # Templates
+//
+// A URL, header value or body containing "{{" is a Go text/template,
+// rendered for every request with a TemplateData.  Targets that name a
+// feeder receive its next row as .Row.
+//
+// # Thread safety
+//
+// A Set's targets do not change after NewSet returns, and it is safe for
+// concurrent use.  Swap in a new Set (e.g. via atomic.Pointer) when the
+// configuration changes.
+package target
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"github.com/firasghr/GoSessionEngine/check"
+	"github.com/firasghr/GoSessionEngine/client"
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/feeder"
+)
+
+// Target is a resolved config.Target.
+type Target struct {
+	// Name identifies the target in logs and per-target metrics.
+	Name string
+
+	// Method is the upper-cased HTTP method.
+	Method string
+
+	// URL is the absolute request URL.  For unix:// targets it is the
+	// http://localhost URL sent over the socket.
+	URL string
+
+	// Headers are set on every request to this target.
+	Headers map[string]string
+
+	// Body is the request body (inline or loaded from the body file).
+	Body []byte
+
+	expect   map[int]struct{}
+	override *client.Override // transport settings that differ from the session's
+	grpc     *grpcCall        // set for gRPC targets; see Invoke
+	redirect *client.RedirectPolicy
+	feeder   *feeder.Feeder
+	checks   check.Set
+	tmpl     *requestTemplate // nil when nothing is templated
+	weight   int
+	seq      atomic.Uint64
+}
+
+// TemplateData is the data request templates are rendered with.
+type TemplateData struct {
+	// SessionID is the ID of the calling session.
+	SessionID int
+
+	// Seq counts requests to the target, starting at 1.
+	Seq uint64
+
+	// Target is the target name.
+	Target string
+
+	// Row is the feeder row drawn for the request, or nil when the target
+	// has no feeder.
+	Row feeder.Row
+}
+
+// Data draws the template data for the next request of session sessionID:
+// the next sequence number and, if t has a feeder, the next row.  The error
+// wraps feeder.ErrExhausted once the feeder has run out; the target should
+// then not be requested.
+func (t *Target) Data(sessionID int) (TemplateData, error) {
+	d := TemplateData{SessionID: sessionID, Seq: t.seq.Add(1), Target: t.Name}
+	if t.feeder != nil {
+		row, err := t.feeder.Next(sessionID)
+		if err != nil {
+			return d, fmt.Errorf("target %q: %w", t.Name, err)
+		}
+		d.Row = row
+	}
+	return d, nil
+}
+
+// Exhausted reports whether t's feeder has run out of data for every
+// session, so t can no longer be requested.  Targets without a feeder, or
+// whose feeder recycles or never runs out, are never exhausted.
+func (t *Target) Exhausted() bool { return t.feeder != nil && t.feeder.Exhausted() }
+
+// NewRequest builds a fresh request for t on behalf of session 0; see
+// NewRequestWith.
+func (t *Target) NewRequest() (*http.Request, error) {
+	d, err := t.Data(0)
+	if err != nil {
+		return nil, err
+	}
+	return t.NewRequestWith(d)
+}
+
+// NewRequestWith builds a fresh request for t, rendering its templates with
+// d.  The body is backed by a bytes.Reader, so http.NewRequest sets GetBody
+// and the request can be replayed on redirects and retries.  Per-target
+// transport settings travel in the request context as a client.Override,
+// which session.Session honours, the redirect policy as a
+// client.RedirectPolicy, and the target name as the circuit breaker key.
+func (t *Target) NewRequestWith(d TemplateData) (*http.Request, error) {
+	u, headers, data := t.URL, t.Headers, t.Body
+	if t.tmpl != nil {
+		var err error
+		if u, headers, data, err = t.tmpl.render(t, d); err != nil {
+			return nil, fmt.Errorf("target %q: render request: %w", t.Name, err)
+		}
+	}
+	var body io.Reader
+	if len(data) > 0 {
+		body = bytes.NewReader(data)
+	}
+	ctx := client.WithOverride(context.Background(), t.override)
+	ctx = client.WithBreakerKey(ctx, t.Name)
+	if t.redirect != nil {
+		ctx = client.WithRedirectPolicy(ctx, *t.redirect)
+	}
+	req, err := http.NewRequestWithContext(ctx, t.Method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("target %q: build request: %w", t.Name, err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// requestTemplate holds the templated parts of a target.  Nil fields are
+// sent as configured.
+type requestTemplate struct {
+	url     *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+}
+
+// newRequestTemplate parses the parts of t containing "{{", returning nil
+// when there are none.
+func newRequestTemplate(t *Target) (*requestTemplate, error) {
+	rt := &requestTemplate{}
+	parse := func(name, text string) (*template.Template, error) {
+		if !strings.Contains(text, "{{") {
+			return nil, nil
+		}
+		return client.ParseTemplate(name, text)
+	}
+	var err error
+	if rt.url, err = parse("url", t.URL); err != nil {
+		return nil, err
+	}
+	if rt.body, err = parse("body", string(t.Body)); err != nil {
+		return nil, err
+	}
+	for k, v := range t.Headers {
+		tmpl, err := parse("header "+k, v)
+		if err != nil {
+			return nil, err
+		}
+		if tmpl != nil {
+			if rt.headers == nil {
+				rt.headers = make(map[string]*template.Template)
+			}
+			rt.headers[k] = tmpl
+		}
+	}
+	if rt.url == nil && rt.body == nil && rt.headers == nil {
+		return nil, nil
+	}
+	return rt, nil
+}
+
+// render returns t's URL, headers and body with the templated parts
+// rendered with d.
+func (rt *requestTemplate) render(t *Target, d TemplateData) (string, map[string]string, []byte, error) {
+	var buf bytes.Buffer
+	u, headers, body := t.URL, t.Headers, t.Body
+	if rt.url != nil {
+		if err := rt.url.Execute(&buf, d); err != nil {
+			return "", nil, nil, err
+		}
+		u = buf.String()
+	}
+	if rt.headers != nil {
+		headers = make(map[string]string, len(t.Headers))
+		for k, v := range t.Headers {
+			if tmpl := rt.headers[k]; tmpl != nil {
+				buf.Reset()
+				if err := tmpl.Execute(&buf, d); err != nil {
+					return "", nil, nil, err
+				}
+				v = buf.String()
+			}
+			headers[k] = v
+		}
+	}
+	if rt.body != nil {
+		buf.Reset()
+		if err := rt.body.Execute(&buf, d); err != nil {
+			return "", nil, nil, err
+		}
+		body = bytes.Clone(buf.Bytes())
+	}
+	return u, headers, body, nil
+}
+
+// Expected reports whether status counts as success for t: one of the
+// configured expect_status codes, or any 2xx/3xx status when none are set.
+func (t *Target) Expected(status int) bool {
+	if len(t.expect) == 0 {
+		return status >= 200 && status < 400
+	}
+	_, ok := t.expect[status]
+	return ok
+}
+
+// Checks returns the response checks of t, or nil when it has none.  A
+// response counts as a success only if it is Expected and passes them all.
+func (t *Target) Checks() check.Set { return t.checks }
+
+// Set is a weighted collection of targets.
+type Set struct {
+	targets []*Target
+	live    atomic.Pointer[picker] // the targets Pick still chooses from
+}
+
+// picker chooses among targets by weight.
+type picker struct {
+	targets []*Target
+	cum     []int // cumulative weights, parallel to targets
+	total   int
+}
+
+// newPicker returns a picker over the targets that are not exhausted.
+func newPicker(targets []*Target) *picker {
+	p := &picker{}
+	for _, t := range targets {
+		if t.Exhausted() {
+			continue
+		}
+		p.targets = append(p.targets, t)
+		p.total += t.weight
+		p.cum = append(p.cum, p.total)
+	}
+	return p
+}
+
+// NewSet resolves cfgTargets into a Set.  It returns an error if a body
+// file, gRPC descriptor set or gRPC request template cannot be loaded.
+func NewSet(cfgTargets []config.Target) (*Set, error) {
+	return NewSetWithFeeders(cfgTargets, nil)
+}
+
+// NewSetWithFeeders is NewSet for targets that draw rows from feeders,
+// keyed by name.  The feeders are shared, not copied, so a Set rebuilt on
+// reload continues where the previous one left off.  It returns an error
+// if a target names a feeder missing from feeders or a template does not
+// parse.
+func NewSetWithFeeders(cfgTargets []config.Target, feeders map[string]*feeder.Feeder) (*Set, error) {
+	s := &Set{}
+	for _, ct := range cfgTargets {
+		t := &Target{
+			Name:    ct.Name,
+			Method:  strings.ToUpper(ct.Method),
+			URL:     ct.URL,
+			Headers: ct.Headers,
+			Body:    []byte(ct.Body),
+		}
+		if t.Method == "" {
+			t.Method = http.MethodGet
+		}
+		if ct.BodyFile != "" {
+			data, err := os.ReadFile(ct.BodyFile) // #nosec G304 – operator-supplied config path
+			if err != nil {
+				return nil, fmt.Errorf("target %q: read body file: %w", ct.Name, err)
+			}
+			t.Body = data
+		}
+		if dial, httpURL, ok, err := client.SplitUnixURL(ct.URL); ok {
+			if err != nil {
+				return nil, fmt.Errorf("target %q: %w", ct.Name, err)
+			}
+			t.URL, ct.Dial = httpURL, dial
+		}
+		ov, err := transportOverride(ct)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", ct.Name, err)
+		}
+		t.override = ov
+		if r := ct.Redirects; r != nil {
+			mode, err := client.ParseRedirectMode(r.Mode)
+			if err != nil {
+				return nil, fmt.Errorf("target %q: %w", ct.Name, err)
+			}
+			t.redirect = &client.RedirectPolicy{Mode: mode, MaxHops: r.MaxHops}
+		}
+		if ct.Feeder != "" {
+			if t.feeder = feeders[ct.Feeder]; t.feeder == nil {
+				return nil, fmt.Errorf("target %q: unknown feeder %q", ct.Name, ct.Feeder)
+			}
+		}
+		if ct.GRPC != nil {
+			if t.grpc, err = newGRPCCall(ct); err != nil {
+				return nil, fmt.Errorf("target %q: %w", ct.Name, err)
+			}
+		} else if t.tmpl, err = newRequestTemplate(t); err != nil {
+			return nil, fmt.Errorf("target %q: %w", ct.Name, err)
+		}
+		if t.checks, err = check.NewSet(ct.Checks); err != nil {
+			return nil, fmt.Errorf("target %q: %w", ct.Name, err)
+		}
+		if len(ct.ExpectStatus) > 0 {
+			t.expect = make(map[int]struct{}, len(ct.ExpectStatus))
+			for _, code := range ct.ExpectStatus {
+				t.expect[code] = struct{}{}
+			}
+		}
+
+		t.weight = ct.Weight
+		if t.weight <= 0 {
+			t.weight = 1
+		}
+		s.targets = append(s.targets, t)
+	}
+	s.live.Store(newPicker(s.targets))
+	return s, nil
+}
+
+// Len returns the number of targets in the set.
+func (s *Set) Len() int { return len(s.targets) }
+
+// Targets returns the targets in configuration order.  The slice must not be
+// modified.
+func (s *Set) Targets() []*Target { return s.targets }
+
+// OverrideKeys returns the keys of the targets' transport overrides; see
+// session.Session.PruneVariants.
+func (s *Set) OverrideKeys() map[string]struct{} {
+	keys := make(map[string]struct{})
+	for _, t := range s.targets {
+		if t.override != nil {
+			keys[t.override.Key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// Pick returns a target chosen at random with probability proportional to its
+// weight, or nil if the set is empty or Drained.  Exhausted targets are
+// dropped from the choice as Pick finds them.  Safe for concurrent use.
+func (s *Set) Pick() *Target {
+	for {
+		p := s.live.Load()
+		var t *Target
+		switch len(p.targets) {
+		case 0:
+			return nil
+		case 1:
+			t = p.targets[0]
+		default:
+			n := rand.IntN(p.total)
+			t = p.targets[sort.SearchInts(p.cum, n+1)]
+		}
+		if !t.Exhausted() {
+			return t
+		}
+		s.live.CompareAndSwap(p, newPicker(p.targets))
+	}
+}
+
+// Drained reports whether s has targets but every one of them is
+// exhausted, so nothing is left to send.
+func (s *Set) Drained() bool {
+	return len(s.targets) > 0 && s.Pick() == nil
+}
+
+// transportOverride returns the client.Override for the transport settings
+// ct sets, or nil when it uses the session defaults.  Certificate material is
+// loaded once here.  The key embeds the settings, so a reload that changes
+// them yields fresh session clients while an unchanged target keeps its
+// pooled connections.
+func transportOverride(ct config.Target) (*client.Override, error) {
+	var apply []func(opts *client.TransportOptions, slot int)
+
+	if ct.Protocol != "" {
+		proto, err := client.ParseProtocol(ct.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		apply = append(apply, func(opts *client.TransportOptions, _ int) {
+			opts.Protocol = proto
+		})
+	}
+	if ct.TLS != nil {
+		fn, err := tlsApply(*ct.TLS)
+		if err != nil {
+			return nil, err
+		}
+		apply = append(apply, fn)
+	}
+	if ct.Dial != "" {
+		if _, _, err := client.ParseDialAddr(ct.Dial); err != nil {
+			return nil, err
+		}
+		apply = append(apply, func(opts *client.TransportOptions, _ int) {
+			opts.Dial = ct.Dial
+		})
+	}
+
+	if len(apply) == 0 {
+		return nil, nil
+	}
+	return &client.Override{
+		Key: fmt.Sprintf("target %s protocol=%s tls=%+v dial=%s", ct.Name, ct.Protocol, ct.TLS, ct.Dial),
+		Apply: func(opts *client.TransportOptions, slot int) {
+			for _, fn := range apply {
+				fn(opts, slot)
+			}
+		},
+	}, nil
+}
+
+// tlsApply loads the certificate material of a target's TLS settings and
+// returns the function applying it to session transport options.
+func tlsApply(tc config.TLSConfig) (func(opts *client.TransportOptions, slot int), error) {
+	var (
+		pool  *x509.CertPool
+		certs []tls.Certificate
+		err   error
+	)
+	if len(tc.CAFiles) > 0 {
+		if pool, err = client.LoadCertPool(tc.CAFiles...); err != nil {
+			return nil, err
+		}
+	}
+	for _, cc := range tc.ClientCerts {
+		cert, err := client.LoadClientCertificate(cc.CertFile, cc.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return func(opts *client.TransportOptions, slot int) {
+		if pool != nil {
+			opts.TLS.RootCAs = pool
+		}
+		if len(certs) > 0 {
+			opts.TLS.Certificates = []tls.Certificate{certs[slot%len(certs)]}
+		}
+		if tc.InsecureSkipVerify {
+			opts.TLS.InsecureSkipVerify = true
+		}
+	}, nil
+}
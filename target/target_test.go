@@ -0,0 +1,280 @@
+package target_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/firasghr/GoSessionEngine/client"
+	"github.com/firasghr/GoSessionEngine/config"
+	"github.com/firasghr/GoSessionEngine/feeder"
+	"github.com/firasghr/GoSessionEngine/target"
+)
+
+func TestNewSet_Empty(t *testing.T) {
+	set, err := target.NewSet(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 0 || set.Pick() != nil {
+		t.Error("empty set should have no targets and Pick should return nil")
+	}
+}
+
+func TestNewRequest_InlineBodyAndHeaders(t *testing.T) {
+	set, err := target.NewSet([]config.Target{{
+		Name:    "login",
+		Method:  "post",
+		URL:     "http://example.com/login",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"user":"a"}`,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := set.Pick()
+	req, err := tg.NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "POST" {
+		t.Errorf("Method: got %q, want POST", req.Method)
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type header not applied")
+	}
+	if req.GetBody == nil {
+		t.Fatal("GetBody should be set so the body is replayable")
+	}
+	for i := 0; i < 2; i++ {
+		rc, _ := req.GetBody()
+		data, _ := io.ReadAll(rc)
+		if string(data) != `{"user":"a"}` {
+			t.Errorf("body read %d: got %q", i, data)
+		}
+	}
+}
+
+func TestNewSet_BodyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.xml")
+	if err := os.WriteFile(path, []byte("<ping/>"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	set, err := target.NewSet([]config.Target{{Name: "x", URL: "http://example.com", BodyFile: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(set.Pick().Body); got != "<ping/>" {
+		t.Errorf("Body: got %q", got)
+	}
+
+	if _, err := target.NewSet([]config.Target{{Name: "x", URL: "http://example.com", BodyFile: path + ".missing"}}); err == nil {
+		t.Error("expected error for missing body file")
+	}
+}
+
+func TestExpected(t *testing.T) {
+	set, _ := target.NewSet([]config.Target{
+		{Name: "default", URL: "http://example.com"},
+		{Name: "strict", URL: "http://example.com", ExpectStatus: []int{201, 404}},
+	})
+	def, strict := set.Targets()[0], set.Targets()[1]
+
+	if !def.Expected(204) || !def.Expected(302) || def.Expected(404) {
+		t.Error("default expectations should accept 2xx/3xx only")
+	}
+	if !strict.Expected(404) || !strict.Expected(201) || strict.Expected(200) {
+		t.Error("explicit expect_status should be honoured exactly")
+	}
+}
+
+func TestPick_Weighted(t *testing.T) {
+	set, _ := target.NewSet([]config.Target{
+		{Name: "heavy", URL: "http://example.com", Weight: 9},
+		{Name: "light", URL: "http://example.com", Weight: 1},
+	})
+	counts := map[string]int{}
+	const n = 10000
+	for i := 0; i < n; i++ {
+		counts[set.Pick().Name]++
+	}
+	if counts["heavy"] < 8500 || counts["heavy"] > 9500 {
+		t.Errorf("heavy picked %d/%d times, want ~9000", counts["heavy"], n)
+	}
+	if counts["light"] == 0 {
+		t.Error("light target never picked")
+	}
+}
+
+func TestPick_SkipsExhaustedTargets(t *testing.T) {
+	f, err := feeder.New("users", []feeder.Row{{"id": "1"}}, feeder.Sequential, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := target.NewSetWithFeeders([]config.Target{
+		{Name: "login", URL: "http://example.com/{{.Row.id}}", Feeder: "users", Weight: 9},
+		{Name: "home", URL: "http://example.com"},
+	}, map[string]*feeder.Feeder{"users": f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := set.Targets()[0]
+	if _, err := login.Data(0); err != nil {
+		t.Fatal(err)
+	}
+	if !login.Exhausted() {
+		t.Fatal("login should be exhausted after its only row")
+	}
+	for i := 0; i < 100; i++ {
+		if got := set.Pick().Name; got != "home" {
+			t.Fatalf("pick %d: got %q, want only home", i, got)
+		}
+	}
+	if set.Drained() {
+		t.Error("a set with a live target should not be drained")
+	}
+
+	only, _ := target.NewSetWithFeeders([]config.Target{
+		{Name: "login", URL: "http://example.com/{{.Row.id}}", Feeder: "users"},
+	}, map[string]*feeder.Feeder{"users": f})
+	if only.Pick() != nil || !only.Drained() {
+		t.Error("a set whose every target is exhausted should be drained")
+	}
+	if empty, _ := target.NewSet(nil); empty.Drained() {
+		t.Error("an empty set should not be drained")
+	}
+}
+
+func TestNewRequest_TLSOverride(t *testing.T) {
+	set, err := target.NewSet([]config.Target{
+		{Name: "plain", URL: "https://example.com/"},
+		{Name: "internal", URL: "https://internal.example/", TLS: &config.TLSConfig{InsecureSkipVerify: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, internal := set.Targets()[0], set.Targets()[1]
+
+	req, _ := plain.NewRequest()
+	if client.OverrideFrom(req.Context()) != nil {
+		t.Error("target without tls settings should not carry an override")
+	}
+
+	req, _ = internal.NewRequest()
+	ov := client.OverrideFrom(req.Context())
+	if ov == nil {
+		t.Fatal("target with tls settings should carry an override")
+	}
+	var opts client.TransportOptions
+	ov.Apply(&opts, 0)
+	if !opts.TLS.InsecureSkipVerify {
+		t.Error("override should apply insecure_skip_verify")
+	}
+}
+
+func TestNewSet_MissingCAFile(t *testing.T) {
+	_, err := target.NewSet([]config.Target{{
+		Name: "a", URL: "https://example.com/",
+		TLS: &config.TLSConfig{CAFiles: []string{filepath.Join(t.TempDir(), "missing.pem")}},
+	}})
+	if err == nil {
+		t.Error("expected an error for a missing CA bundle")
+	}
+}
+
+func TestNewRequest_RedirectPolicy(t *testing.T) {
+	set, err := target.NewSet([]config.Target{{
+		Name:      "login",
+		URL:       "http://example.com/login",
+		Redirects: &config.RedirectConfig{Mode: "same-host", MaxHops: 3},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := set.Pick().NewRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok := client.RedirectPolicyFrom(req.Context())
+	if !ok || p.Mode != client.RedirectSameHost || p.MaxHops != 3 {
+		t.Errorf("policy: got %+v, %v", p, ok)
+	}
+}
+
+func TestNewRequest_FeederTemplates(t *testing.T) {
+	f, err := feeder.New("users", []feeder.Row{
+		{"user": "alice", "id": "1"},
+		{"user": "bob \"b\"", "id": "2"},
+	}, feeder.Sequential, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := target.NewSetWithFeeders([]config.Target{{
+		Name:    "profile",
+		Method:  "PUT",
+		URL:     "http://example.com/users/{{.Row.id}}?seq={{.Seq}}",
+		Headers: map[string]string{"X-User": "{{.Row.user}}", "Accept": "application/json"},
+		Body:    `{"name":{{json .Row.user}},"session":{{.SessionID}}}`,
+		Feeder:  "users",
+	}}, map[string]*feeder.Feeder{"users": f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := set.Pick()
+
+	want := []struct{ url, user, body string }{
+		{"http://example.com/users/1?seq=1", "alice", `{"name":"alice","session":4}`},
+		{"http://example.com/users/2?seq=2", `bob "b"`, `{"name":"bob \"b\"","session":4}`},
+	}
+	for i, w := range want {
+		d, err := tg.Data(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := tg.NewRequestWith(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.URL.String() != w.url {
+			t.Errorf("request %d URL: got %q, want %q", i, req.URL, w.url)
+		}
+		if req.Header.Get("X-User") != w.user || req.Header.Get("Accept") != "application/json" {
+			t.Errorf("request %d headers: got %v", i, req.Header)
+		}
+		for j := 0; j < 2; j++ {
+			rc, _ := req.GetBody()
+			data, _ := io.ReadAll(rc)
+			if string(data) != w.body {
+				t.Errorf("request %d body read %d: got %q, want %q", i, j, data, w.body)
+			}
+		}
+	}
+	if _, err := tg.Data(4); !errors.Is(err, feeder.ErrExhausted) {
+		t.Errorf("third draw: got %v, want ErrExhausted", err)
+	}
+	if tg.Headers["X-User"] != "{{.Row.user}}" {
+		t.Error("rendering must not modify the target's headers")
+	}
+}
+
+func TestNewSetWithFeeders_Errors(t *testing.T) {
+	if _, err := target.NewSetWithFeeders([]config.Target{{
+		Name: "a", URL: "http://example.com/", Feeder: "missing",
+	}}, nil); err == nil {
+		t.Error("expected an error for an unknown feeder")
+	}
+	if _, err := target.NewSet([]config.Target{{
+		Name: "a", URL: "http://example.com/{{.Row",
+	}}); err == nil {
+		t.Error("expected an error for a malformed template")
+	}
+	set, err := target.NewSet([]config.Target{{Name: "a", URL: "http://example.com/{{.Row.id}}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Pick().NewRequest(); err == nil {
+		t.Error("expected an error rendering .Row without a feeder")
+	}
+}